@@ -0,0 +1,88 @@
+// Command bench-compare compares the latest run in a benchmark history with an earlier one, and
+// flags the operations which got slower by more than a threshold.
+//
+// It exits 0 when nothing regressed, 1 when something did and 2 on errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/deis/workflow/_tests/pkg/bench"
+)
+
+var (
+	history   = flag.String("history", "bench.json", "the benchmark history file")
+	baseline  = flag.String("baseline", "", "compare against the latest earlier run with this label, instead of the previous run")
+	threshold = flag.Float64("threshold", 0.2, "how much slower, relative to the baseline, counts as a regression")
+	asJSON    = flag.Bool("json", false, "print the comparison as JSON")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+  bench-compare [options]
+
+Options:
+`)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	h, err := bench.Load(*history)
+	check(err)
+	current := h.Latest()
+	if current == nil {
+		check(fmt.Errorf("%s has no benchmark runs", *history))
+	}
+	base, err := h.Baseline(*baseline)
+	check(err)
+	comparisons := bench.Compare(base, current, *threshold)
+
+	regressed := false
+	for _, c := range comparisons {
+		regressed = regressed || c.Regressed
+	}
+	if *asJSON {
+		data, err := json.MarshalIndent(comparisons, "", "  ")
+		check(err)
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("Comparing %s with %s\n\n", describe(current), describe(base))
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "OPERATION\tBASELINE\tCURRENT\tCHANGE\t")
+		for _, c := range comparisons {
+			note := ""
+			if c.Regressed {
+				note = "REGRESSION"
+			}
+			fmt.Fprintf(w, "%s\t%.1fs\t%.1fs\t%+.0f%%\t%s\n", c.Name, c.Baseline, c.Current, c.Change*100, note)
+		}
+		w.Flush()
+	}
+	if regressed {
+		os.Exit(1)
+	}
+}
+
+func describe(run *bench.Run) string {
+	started := run.Started.Format("2006-01-02 15:04")
+	if run.Label == "" {
+		return "the run of " + started
+	}
+	return fmt.Sprintf("%s (%s)", run.Label, started)
+}
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
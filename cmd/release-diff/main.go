@@ -0,0 +1,94 @@
+// Command release-diff reports how two releases of a Deis app differ.
+//
+// Run "release-diff snapshot" after each change to record the app's current release with its
+// config, limits and tags, then "release-diff v2 v3" to compare them. Releases without a snapshot
+// are fetched from the controller, but only their metadata can be compared.
+//
+// Like diff(1), it exits 0 when the releases are the same, 1 when they differ and 2 on errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/deis/workflow/_tests/pkg/releases"
+)
+
+var (
+	app       = flag.String("a", "", "the app whose releases to compare")
+	snapshots = flag.String("snapshots", ".", "the directory holding release snapshots")
+	asJSON    = flag.Bool("json", false, "print the differences as JSON")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+  release-diff -a <app> [options] snapshot
+  release-diff -a <app> [options] <version> <version>
+
+Options:
+`)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *app == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch {
+	case flag.NArg() == 1 && flag.Arg(0) == "snapshot":
+		release, err := releases.Snapshot(releases.CLI, *app)
+		check(err)
+		path := snapshotPath(release.Version)
+		check(releases.Save(release, path))
+		fmt.Printf("Saved v%d of %s to %s\n", release.Version, *app, path)
+	case flag.NArg() == 2:
+		a, err := load(flag.Arg(0))
+		check(err)
+		b, err := load(flag.Arg(1))
+		check(err)
+		changes := releases.Diff(a, b)
+		if *asJSON {
+			data, err := json.MarshalIndent(changes, "", "  ")
+			check(err)
+			fmt.Println(string(data))
+		} else {
+			for _, change := range changes {
+				fmt.Println(change)
+			}
+		}
+		if len(changes) > 0 {
+			os.Exit(1)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func snapshotPath(version int) string {
+	return filepath.Join(*snapshots, fmt.Sprintf("%s-v%d.json", *app, version))
+}
+
+// load returns the snapshot of version if there is one, or else fetches its metadata.
+func load(arg string) (*releases.Release, error) {
+	version, err := releases.ParseVersion(arg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(snapshotPath(version)); err == nil {
+		return releases.Load(snapshotPath(version))
+	}
+	return releases.Fetch(releases.CLI, *app, version)
+}
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
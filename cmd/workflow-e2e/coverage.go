@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/deis/workflow/_tests/pkg/coverage"
+	"github.com/deis/workflow/_tests/pkg/report"
+	"github.com/deis/workflow/_tests/pkg/transcript"
+)
+
+// reportCoverage prints which of the CLI's commands and flags the specs in a directory of reports
+// ran successfully, reading the commands and flags from the CLI's help.
+func reportCoverage(args []string) int {
+	flags := flag.NewFlagSet("coverage", flag.ExitOnError)
+	deis := flags.String("deis", "deis", "the CLI whose commands to cover")
+	jsonPath := flags.String("json", "", "also write the coverage matrix to this JSON file")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n  workflow-e2e coverage [options] <report-dir>\n\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	cli, err := coverage.Discover(func(args ...string) (string, error) {
+		output, err := exec.Command(*deis, args...).CombinedOutput()
+		return string(output), err
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	commands, err := loadCommands(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	m := cli.Cover(commands)
+	if err := m.Write(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *jsonPath != "" {
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(*jsonPath, data, 0644)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	return 0
+}
+
+// loadCommands returns every command recorded in the reports in dir, including BeforeSuite's and
+// AfterSuite's.
+func loadCommands(dir string) ([]transcript.Command, error) {
+	// parallel runs write a report per node
+	paths, err := filepath.Glob(filepath.Join(dir, "report*.json"))
+	if err == nil && len(paths) == 0 {
+		err = fmt.Errorf("there are no reports in %s", dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var commands []transcript.Command
+	for _, path := range paths {
+		r, err := report.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range r.Specs {
+			commands = append(commands, spec.Commands...)
+		}
+	}
+	return commands, nil
+}
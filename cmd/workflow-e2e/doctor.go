@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/cluster"
+	"github.com/deis/workflow/_tests/pkg/compat"
+	"github.com/deis/workflow/_tests/pkg/doctor"
+)
+
+// runDoctor checks that the local tools the suite needs are installed and that the cluster is
+// ready to be tested, printing a checklist. It returns 1 if any check failed.
+func runDoctor(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := flags.String("config", "", "a run config file to take the router's address from")
+	adminUser := flags.String("admin-user", "", "the admin user the suite logs in as (default $TEST_ADMIN_USER, or admin)")
+	adminPassword := flags.String("admin-password", "", "the admin user's password (default $TEST_ADMIN_PASSWORD, or admin)")
+	safe := flags.Bool("safe", false, "check for a run in safe mode, which never registers the admin")
+	compatFile := flags.String("compat", "", "the compatibility matrix (default compat.yaml in tests/ or the current directory)")
+	builderVersion := flags.String("builder-version", os.Getenv("BUILDER_VERSION"), "the version of the builder, to check against the compatibility matrix")
+	timeout := flags.Duration("timeout", 10*time.Second, "how long to wait for each network check")
+	flags.Parse(args)
+
+	host, port := os.Getenv(cluster.RouterHostEnv), os.Getenv(cluster.RouterPortEnv)
+	if *configPath != "" {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		host, port = cfg.Router.Host, cfg.Router.Port
+		*safe = *safe || cfg.Safe
+		// the run passes its config's environment on to the suite
+		for name, value := range cfg.Env {
+			os.Setenv(name, value)
+		}
+		if *builderVersion == "" {
+			*builderVersion = cfg.BuilderVersion
+		}
+	}
+	// the same credentials as the suite
+	if *adminUser == "" {
+		*adminUser = envOr("TEST_ADMIN_USER", "admin")
+	}
+	if *adminPassword == "" {
+		*adminPassword = envOr("TEST_ADMIN_PASSWORD", "admin")
+	}
+	load := compat.Load
+	if *compatFile == "" {
+		load = compat.LoadDefault
+		*compatFile = "compat.yaml"
+		if _, err := os.Stat(filepath.Join("tests", *compatFile)); err == nil {
+			*compatFile = filepath.Join("tests", *compatFile)
+		}
+	}
+	matrix, err := load(*compatFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	controller, _ := cluster.Controller(host, port)
+	results := doctor.Run(doctor.Checks(doctor.Options{
+		Controller:     controller,
+		AdminUser:      *adminUser,
+		AdminPassword:  *adminPassword,
+		Safe:           *safe,
+		Matrix:         matrix,
+		BuilderVersion: *builderVersion,
+		Timeout:        *timeout,
+	}))
+	if !doctor.Print(os.Stdout, results) {
+		fmt.Println("\nFix the failed checks above before running the tests.")
+		return 1
+	}
+	fmt.Println("\nReady to run the tests.")
+	return 0
+}
+
+// envOr returns the value of the environment variable name, or def if it is empty, as the suite
+// does.
+func envOr(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/deis/workflow/_tests/pkg/cassette"
+)
+
+// compareCassettes compares two recordings of the suite, such as one against the last release and
+// one against a release candidate, and reports where the API's behaviour drifted. It exits 1 if
+// it did.
+func compareCassettes(args []string) int {
+	flags := flag.NewFlagSet("drift", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n  workflow-e2e drift <old cassette dir> <new cassette dir>\n")
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	old, err := cassette.LoadDir(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	new, err := cassette.LoadDir(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var names []string
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range new {
+		if old[name] == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var drift []string
+	for _, name := range names {
+		switch {
+		case new[name] == nil:
+			drift = append(drift, fmt.Sprintf("%s: not recorded in %s", name, flags.Arg(1)))
+		case old[name] == nil:
+			drift = append(drift, fmt.Sprintf("%s: not recorded in %s", name, flags.Arg(0)))
+		default:
+			drift = append(drift, cassette.Drift(old[name], new[name])...)
+		}
+	}
+	for _, line := range drift {
+		fmt.Println(line)
+	}
+	if len(drift) > 0 {
+		fmt.Printf("\n%d differences in %d cassettes\n", len(drift), len(names))
+		return 1
+	}
+	fmt.Printf("no drift in %d cassettes\n", len(names))
+	return 0
+}
@@ -0,0 +1,64 @@
+// Command workflow-e2e runs the Deis Workflow end-to-end tests and helps look after the clusters
+// they run against.
+//
+//	workflow-e2e run      runs the compiled suite, against a cluster or local stand-ins
+//	workflow-e2e list     lists the specs a run would include
+//	workflow-e2e doctor   checks the local tools and the cluster are ready for a run
+//	workflow-e2e reap     destroys apps left behind by interrupted runs
+//	workflow-e2e report   summarizes the reports a run wrote
+//	workflow-e2e coverage shows which of the CLI's commands and flags a run used
+//	workflow-e2e proxy    serves a fault-injecting proxy in front of the controller
+//	workflow-e2e drift    compares two recordings of the controller's traffic
+//	workflow-e2e schemas  infers the schemas of the controller's responses from a recording
+//
+// Runs are described by flags, a YAML config file given with -config, or both; flags win.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+var commands = []struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}{
+	{"run", "run the suite", runSuite},
+	{"list", "list the specs a run would include", listSpecs},
+	{"doctor", "check the local tools and the cluster are ready for a run", runDoctor},
+	{"reap", "destroy apps left behind by interrupted runs", reapApps},
+	{"report", "summarize the reports a run wrote", summarizeReports},
+	{"coverage", "show which of the CLI's commands and flags a run used", reportCoverage},
+	{"proxy", "serve a fault-injecting proxy in front of the controller", runProxy},
+	{"drift", "compare two recordings of the controller's traffic", compareCassettes},
+	{"schemas", "infer the schemas of the controller's responses from a recording", writeSchemas},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage:\n  workflow-e2e <command> [options]\n\nCommands:\n")
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", command.name, command.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun \"workflow-e2e <command> -h\" for a command's options.\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, command := range commands {
+		if command.name == os.Args[1] {
+			os.Exit(command.run(os.Args[2:]))
+		}
+	}
+	switch os.Args[1] {
+	case "-h", "-help", "--help", "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/deis/workflow/_tests/pkg/cluster"
+	"github.com/deis/workflow/_tests/pkg/faultproxy"
+)
+
+// runProxy serves a fault-injecting proxy in front of the controller until interrupted, so that
+// the CLI's handling of a scenario can be explored by hand with "deis login <proxy>".
+func runProxy(args []string) int {
+	flags := flag.NewFlagSet("proxy", flag.ExitOnError)
+	scenarioPath := flags.String("scenario", "", "a YAML file of the faults to inject (default none)")
+	target := flags.String("target", "", "the controller's URL (default worked out from DEIS_ROUTER_SERVICE_HOST and _PORT)")
+	listen := flags.String("listen", "127.0.0.1:8000", "the address to serve the proxy on")
+	flags.Parse(args)
+
+	if *target == "" {
+		var err error
+		if *target, err = cluster.Controller(os.Getenv(cluster.RouterHostEnv), os.Getenv(cluster.RouterPortEnv)); err != nil {
+			fmt.Fprintf(os.Stderr, "%v; give the controller's URL with -target\n", err)
+			return 2
+		}
+	}
+	u, err := url.Parse(*target)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	var scenario faultproxy.Scenario
+	if *scenarioPath != "" {
+		if scenario, err = faultproxy.LoadScenario(*scenarioPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	proxy, err := faultproxy.New(u, scenario)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	proxy.Logf = log.Printf
+	log.Printf("proxying http://%s to %s with %d rules", *listen, *target, len(scenario))
+	if err := http.ListenAndServe(*listen, proxy); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
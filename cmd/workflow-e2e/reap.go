@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/deis/workflow/_tests/pkg/parse"
+	"github.com/deis/workflow/_tests/pkg/releases"
+)
+
+// reapApps destroys the apps, visible to the user the deis CLI is logged in as, whose names match
+// those the suite gives its apps. It returns 1 if any could not be destroyed.
+func reapApps(args []string) int {
+	flags := flag.NewFlagSet("reap", flag.ExitOnError)
+	pattern := flags.String("pattern", "", "destroy the apps whose names match this regular expression")
+	prefix := flags.String("resource-prefix", "test-", "destroy the apps named with this prefix and a number, unless -pattern is given")
+	dryRun := flags.Bool("dry-run", false, "only print the apps which would be destroyed")
+	flags.Parse(args)
+	if *pattern == "" {
+		*pattern = "^" + regexp.QuoteMeta(*prefix) + `\d+$`
+	}
+	re, err := regexp.Compile(*pattern)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	output, err := releases.CLI("apps:list")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not list apps: %v\n%s", err, output)
+		return 2
+	}
+	code := 0
+	for _, app := range parse.AppsList(output) {
+		if !re.MatchString(app) {
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("Would destroy %s\n", app)
+			continue
+		}
+		output, err := releases.CLI("apps:destroy", "--app="+app, "--confirm="+app)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not destroy %s: %v\n%s", app, err, output)
+			code = 1
+			continue
+		}
+		fmt.Printf("Destroyed %s\n", app)
+	}
+	return code
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deis/workflow/_tests/pkg/report"
+)
+
+// summarizeReports prints a summary of the JSON reports in a directory, with the failures in them.
+// It returns 1 if any spec failed.
+func summarizeReports(args []string) int {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	transcripts := flags.Bool("transcripts", false, "print the commands each failed spec ran")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n  workflow-e2e report [options] <report-dir>\n\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	// parallel runs write a report per node
+	paths, err := filepath.Glob(filepath.Join(flags.Arg(0), "report*.json"))
+	if err == nil && len(paths) == 0 {
+		err = fmt.Errorf("there are no reports in %s", flags.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	code := 0
+	for _, path := range paths {
+		r, err := report.Load(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fmt.Printf("%s (%s): %d passed (%d flaky), %d failed, %d skipped, %d pending in %.1fs\n",
+			r.Suite, filepath.Base(path), r.Passed, r.Flaky, r.Failed, r.Skipped, r.Pending, r.Duration)
+		if cli, api := r.Versions["cli"], r.Versions["api"]; cli != "" || api != "" {
+			if builder := r.Versions["builder"]; builder != "" {
+				fmt.Printf("CLI %s and builder %s against API %s\n", cli, builder, api)
+			} else {
+				fmt.Printf("CLI %s against API %s\n", cli, api)
+			}
+		}
+		for _, spec := range r.Specs {
+			if !spec.Failed() {
+				continue
+			}
+			code = 1
+			fmt.Printf("\n  %s %s\n  %s\n    %s\n", strings.ToUpper(spec.State), spec.Name, spec.Failure.Location,
+				strings.Replace(strings.TrimSpace(spec.Failure.Message), "\n", "\n    ", -1))
+			if *transcripts {
+				fmt.Printf("\n    %s\n", strings.Replace(strings.TrimSpace(spec.Transcript()), "\n", "\n    ", -1))
+			}
+		}
+		fmt.Println()
+	}
+	return code
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/deis/workflow/_tests/pkg/cluster"
+	"github.com/deis/workflow/_tests/pkg/fakecontroller"
+	"github.com/deis/workflow/_tests/pkg/k8s"
+	"github.com/deis/workflow/_tests/pkg/k8s/fake"
+	"github.com/deis/workflow/_tests/pkg/report"
+	"github.com/deis/workflow/_tests/pkg/runner"
+	"github.com/deis/workflow/_tests/pkg/transcript"
+)
+
+// placeholderHost stands in for the router's host when listing specs, which needs no cluster.
+const placeholderHost = "192.0.2.1"
+
+// stringsFlag is a flag which may be given several times.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func loadConfig(path string) (*runner.Config, error) {
+	cfg, err := runner.LoadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the config file: %v", err)
+	}
+	return cfg, nil
+}
+
+// configFlags defines the flags describing a run on flags, and returns a function building the
+// run's config from them and the config file, once they are parsed.
+func configFlags(flags *flag.FlagSet) func() (*runner.Config, error) {
+	configPath := flags.String("config", "", "a YAML file describing the run")
+	target := flags.String("target", "", "what to run against: \"cluster\" (the default) or \"local\" stand-ins")
+	var focus, skip, labels, excludeLabels stringsFlag
+	flags.Var(&focus, "focus", "run the specs matching this regular expression (repeatable)")
+	flags.Var(&skip, "skip", "skip the specs matching this regular expression (repeatable)")
+	flags.Var(&labels, "label", "run the specs with this label (repeatable)")
+	dir := flags.String("dir", "", "the directory to run the suite in (default tests/ if the suite is found there)")
+	flags.Var(&excludeLabels, "exclude-label", "skip the specs with this label (repeatable)")
+	flakeAttempts := flags.Int("flake-attempts", 0, "run each failing spec up to this many times")
+	quarantine := flags.Bool("quarantine", false, "run only the quarantined specs")
+	safe := flags.Bool("safe", false, "run in safe mode, for clusters that real people use")
+	cassetteMode := flags.String("cassette-mode", "", "\"record\" the CLI's traffic with the controller into cassettes, or \"replay\" it from them")
+	cassetteDir := flags.String("cassette-dir", "", "the directory the cassettes are kept in")
+	schemaCheck := flags.String("schema-check", "", "\"warn\" or \"fail\" when controller responses differ from their schemas")
+	schemaDir := flags.String("schema-dir", "", "the directory of the schemas to check responses against (default tests/schemas)")
+	loadUsers := flags.Int("load-users", 0, "run only the load spec, with this many virtual users")
+	loadRate := flags.Float64("load-rate", 0, "start this many load flows a second (default as many as the users can)")
+	loadDuration := flags.String("load-duration", "", "keep starting load flows for this long (default 1m)")
+	loadDriver := flags.String("load-driver", "", "run load flows through the \"cli\" (the default) or the \"api\"")
+	cliVersion := flags.String("cli-version", "", "the version \"deis --version\" should print")
+	builderVersion := flags.String("builder-version", "", "the version of the builder under test, to check against the compatibility matrix")
+	prefix := flags.String("resource-prefix", "", "start the names of created users, apps and keys with this (default \"test-\")")
+	reportDir := flags.String("report-dir", "", "write JSON and JUnit reports to this directory")
+	artifactsDir := flags.String("artifacts-dir", "", "save artifacts of failing specs to this directory")
+	suite := flags.String("suite", "", "the compiled suite (default "+runner.SuiteBinary+" on $PATH, or in tests/)")
+
+	return func() (*runner.Config, error) {
+		cfg := new(runner.Config)
+		if *configPath != "" {
+			var err error
+			if cfg, err = loadConfig(*configPath); err != nil {
+				return nil, err
+			}
+		}
+		if cfg.Router.Host == "" {
+			cfg.Router.Host = os.Getenv(cluster.RouterHostEnv)
+			cfg.Router.Port = os.Getenv(cluster.RouterPortEnv)
+		}
+		if *target != "" {
+			cfg.Target = *target
+		}
+		cfg.Focus = append(cfg.Focus, focus...)
+		cfg.Skip = append(cfg.Skip, skip...)
+		cfg.Labels = append(cfg.Labels, labels...)
+		cfg.ExcludeLabels = append(cfg.ExcludeLabels, excludeLabels...)
+		if *flakeAttempts > 0 {
+			cfg.FlakeAttempts = *flakeAttempts
+		}
+		cfg.Quarantine = cfg.Quarantine || *quarantine
+		cfg.Safe = cfg.Safe || *safe
+		if *loadUsers > 0 {
+			cfg.LoadUsers = *loadUsers
+		}
+		if *loadRate > 0 {
+			cfg.LoadRate = *loadRate
+		}
+		for _, s := range []struct{ flag, field *string }{
+			{reportDir, &cfg.ReportDir},
+			{artifactsDir, &cfg.ArtifactsDir},
+			{suite, &cfg.Suite},
+			{dir, &cfg.Dir},
+			{prefix, &cfg.ResourcePrefix},
+			{cassetteMode, &cfg.CassetteMode},
+			{cassetteDir, &cfg.CassetteDir},
+			{schemaCheck, &cfg.SchemaCheck},
+			{schemaDir, &cfg.SchemaDir},
+			{loadDuration, &cfg.LoadDuration},
+			{loadDriver, &cfg.LoadDriver},
+			{cliVersion, &cfg.CLIVersion},
+			{builderVersion, &cfg.BuilderVersion},
+		} {
+			if *s.flag != "" {
+				*s.field = *s.flag
+			}
+		}
+		return cfg, nil
+	}
+}
+
+// runSuite runs the suite as configured, passing any arguments after the flags on to it, and
+// returns its exit code.
+func runSuite(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	build := configFlags(flags)
+	flags.Parse(args)
+	cfg, err := build()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if cfg.Target == runner.TargetLocal {
+		stop, err := startLocal(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer stop()
+	}
+	code, err := execSuite(cfg, flags.Args(), os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return code
+}
+
+// listSpecs prints the full text of every spec a run would include, without running any.
+func listSpecs(args []string) int {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	build := configFlags(flags)
+	flags.Parse(args)
+	cfg, err := build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if cfg.Router.Host == "" {
+		cfg.Router.Host = placeholderHost
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// a dry run reports the specs it would have run as passed
+	dir, err := ioutil.TempDir("", "workflow-e2e-list")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer os.RemoveAll(dir)
+	cfg.ReportDir = dir
+	code, err := execSuite(cfg, append([]string{"-ginkgo.dryRun"}, flags.Args()...), ioutil.Discard)
+	if err == nil && code != 0 {
+		err = fmt.Errorf("the suite exited with %d", code)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if cfg.Quarantine {
+		dir = filepath.Join(dir, "quarantine")
+	}
+	r, err := report.Load(filepath.Join(dir, "report.json"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	for _, spec := range r.Specs {
+		if !spec.Setup && spec.State == report.Passed {
+			fmt.Println(spec.Name)
+		}
+	}
+	return 0
+}
+
+// execSuite runs the suite with the flags and environment cfg describes, followed by extra, and
+// returns its exit code.
+func execSuite(cfg *runner.Config, extra []string, stdout io.Writer) (int, error) {
+	suite, dir, err := cfg.Locate()
+	if err != nil {
+		return 0, err
+	}
+	cmd := exec.Command(suite, append(cfg.Args(), extra...)...)
+	cmd.Dir = dir
+	cmd.Env = cfg.Environ(os.Environ())
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	code := transcript.ExitCode(err)
+	if code == -1 {
+		return 0, fmt.Errorf("could not run %s: %v", suite, err)
+	}
+	return code, nil
+}
+
+// startLocal serves a fake controller and a fake Kubernetes API for it to schedule apps on, points
+// cfg at them, and returns a function stopping them.
+func startLocal(cfg *runner.Config) (func(), error) {
+	kubeListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("could not start the fake Kubernetes API: %v", err)
+	}
+	go http.Serve(kubeListener, fake.NewServer())
+	dir, err := ioutil.TempDir("", "workflow-e2e")
+	if err != nil {
+		kubeListener.Close()
+		return nil, err
+	}
+	kubeURL := "http://" + kubeListener.Addr().String()
+	cfg.Kubeconfig = filepath.Join(dir, "kubeconfig")
+	if err := k8s.WriteKubeconfig(cfg.Kubeconfig, kubeURL); err != nil {
+		kubeListener.Close()
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		kubeListener.Close()
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("could not start the fake controller: %v", err)
+	}
+	controller := fakecontroller.NewServer()
+	controller.Kube = k8s.NewClient(kubeURL)
+	go http.Serve(listener, controller)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	cfg.Router.Host, cfg.Router.Port = "localhost", port
+	fmt.Fprintf(os.Stderr, "Serving a fake controller at http://localhost:%s, scheduling on %s\n", port, kubeURL)
+	return func() {
+		listener.Close()
+		kubeListener.Close()
+		os.RemoveAll(dir)
+	}, nil
+}
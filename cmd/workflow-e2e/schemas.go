@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/deis/workflow/_tests/pkg/cassette"
+	"github.com/deis/workflow/_tests/pkg/schema"
+)
+
+// writeSchemas infers the schemas of the controller's responses from a directory of cassettes,
+// recorded with -cassette-mode record, and writes them to the directory the suite checks
+// responses against.
+func writeSchemas(args []string) int {
+	flags := flag.NewFlagSet("schemas", flag.ExitOnError)
+	cassetteDir := flags.String("cassettes", "", "the directory of cassettes to infer the schemas from")
+	out := flags.String("out", filepath.Join("tests", "schemas"), "the directory to write the schemas to")
+	merge := flags.Bool("merge", false, "merge into the schemas already in -out, rather than replacing them")
+	flags.Parse(args)
+	if *cassetteDir == "" {
+		fmt.Fprintln(os.Stderr, "give the directory of a recording with -cassettes")
+		return 2
+	}
+
+	cassettes, err := cassette.LoadDir(*cassetteDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(cassettes) == 0 {
+		fmt.Fprintf(os.Stderr, "no cassettes in %s\n", *cassetteDir)
+		return 2
+	}
+	old, err := schema.Load(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	set := make(schema.Set)
+	if *merge {
+		set = old
+	}
+	names := make([]string, 0, len(cassettes))
+	for name := range cassettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, in := range cassettes[name].Interactions {
+			set.Add(in.Request.Method, in.Request.Path, in.Response.Status, []byte(in.Response.Body))
+		}
+	}
+
+	if !*merge {
+		// endpoints the recording never saw go, as the regenerated schemas don't know them
+		for endpoint := range old {
+			if set[endpoint] == nil {
+				fmt.Printf("removed %s\n", endpoint)
+				if err := os.Remove(filepath.Join(*out, schema.FileName(endpoint))); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					return 1
+				}
+			}
+		}
+	}
+	for _, endpoint := range set.Endpoints() {
+		if old[endpoint] == nil {
+			fmt.Printf("added %s\n", endpoint)
+		}
+	}
+	if err := set.Save(*out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Wrote the schemas of %d endpoints from %d cassettes to %s\n", len(set), len(cassettes), *out)
+	return 0
+}
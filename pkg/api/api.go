@@ -0,0 +1,177 @@
+// Package api is a small client for the parts of the controller's v2 REST API the suite uses. It
+// lets specs check the controller's contract directly, so that a failure can be pinned on the
+// controller or on the CLI.
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client talks to one controller as one user.
+type Client struct {
+	// URL is the controller's URL, such as http://deis.example.com.
+	URL string
+	// Token authenticates requests, if set.
+	Token string
+	// HTTP sends the requests; http.DefaultClient if nil.
+	HTTP *http.Client
+}
+
+// New returns a Client for the controller at controllerURL, authenticating with token.
+func New(controllerURL, token string) *Client {
+	return &Client{URL: strings.TrimRight(controllerURL, "/"), Token: token}
+}
+
+// Response is a controller's response, read in full.
+type Response struct {
+	Method string
+	Path   string
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Decode decodes the response's JSON body into v.
+func (r *Response) Decode(v interface{}) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("%s %s answered %d with a body which isn't the JSON expected (%v): %s", r.Method, r.Path, r.Status, err, r.Body)
+	}
+	return nil
+}
+
+// Object decodes the response's body as a JSON object.
+func (r *Response) Object() (map[string]interface{}, error) {
+	var v map[string]interface{}
+	return v, r.Decode(&v)
+}
+
+// Err returns an *Error if the controller answered with an error status.
+func (r *Response) Err() error {
+	if r.Status < 400 {
+		return nil
+	}
+	return &Error{Method: r.Method, Path: r.Path, Status: r.Status, Body: string(r.Body)}
+}
+
+// Error is an error response from the controller.
+type Error struct {
+	Method string
+	Path   string
+	Status int
+	Body   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.Status, http.StatusText(e.Status), e.Body)
+}
+
+// Do sends a request with body, if not nil, encoded as JSON. It only fails if no response was
+// received; error statuses are left to the caller.
+func (c *Client) Do(method, path string, body interface{}) (*Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.URL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: reading the response: %v", method, path, err)
+	}
+	return &Response{Method: method, Path: path, Status: resp.StatusCode, Header: resp.Header, Body: data}, nil
+}
+
+// call sends a request, fails unless it is answered with status, and decodes the answer into out,
+// if not nil.
+func (c *Client) call(method, path string, body interface{}, status int, out interface{}) error {
+	resp, err := c.Do(method, path, body)
+	if err != nil {
+		return err
+	}
+	if err := resp.Err(); err != nil {
+		return err
+	}
+	if resp.Status != status {
+		return fmt.Errorf("%s %s: expected %d, got %d: %s", method, path, status, resp.Status, resp.Body)
+	}
+	if out == nil {
+		return nil
+	}
+	return resp.Decode(out)
+}
+
+// Page is one page of a paginated list.
+type Page struct {
+	Count    int               `json:"count"`
+	Next     *string           `json:"next"`
+	Previous *string           `json:"previous"`
+	Results  []json.RawMessage `json:"results"`
+}
+
+// Page fetches the page of the list at path holding up to limit results from offset on. A limit
+// of 0 leaves the page size to the controller.
+func (c *Client) Page(path string, limit, offset int) (*Page, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	page := new(Page)
+	return page, c.call("GET", path, nil, http.StatusOK, page)
+}
+
+// list fetches every page of the list at path, decoding the results into out, a pointer to a
+// slice.
+func (c *Client) list(path string, out interface{}) error {
+	var results []json.RawMessage
+	for offset := 0; ; {
+		page, err := c.Page(path, 0, offset)
+		if err != nil {
+			return err
+		}
+		results = append(results, page.Results...)
+		offset += len(page.Results)
+		if page.Next == nil || len(page.Results) == 0 {
+			break
+		}
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
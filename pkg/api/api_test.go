@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deis/workflow/_tests/pkg/fakecontroller"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(fakecontroller.NewServer())
+	defer server.Close()
+	c := New(server.URL+"/", "")
+	if _, err := c.Register("admin", "admin", "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Login("admin", "wrong"); err == nil {
+		t.Error("expected a wrong password to fail")
+	}
+	if err := c.Login("admin", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if me, err := c.Whoami(); err != nil || me.Username != "admin" {
+		t.Fatalf("expected to be logged in as admin, got %+v, %v", me, err)
+	}
+
+	// more apps than fit on a page, so that listing them follows the next links
+	for i := 0; i < 105; i++ {
+		if _, err := c.CreateApp(fmt.Sprintf("test-%03d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apps, err := c.Apps()
+	if err != nil || len(apps) != 105 || apps[104].ID != "test-104" {
+		t.Fatalf("expected every app, got %d, %v", len(apps), err)
+	}
+
+	if _, err := c.SetConfig("test-000", map[string]interface{}{"FOO": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := c.Rollback("test-000", 0); err != nil || version != 3 {
+		t.Errorf("expected the rollback to create v3, got %d, %v", version, err)
+	}
+	releases, err := c.Releases("test-000")
+	if err != nil || len(releases) != 3 || releases[0].Version != 3 {
+		t.Errorf("expected 3 releases, newest first, got %+v, %v", releases, err)
+	}
+
+	_, err = c.App("missing")
+	if apiErr, ok := err.(*Error); !ok || apiErr.Status != http.StatusNotFound {
+		t.Errorf("expected a 404, got %v", err)
+	}
+}
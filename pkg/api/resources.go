@@ -0,0 +1,234 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// User is a user account.
+type User struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	IsSuperuser bool   `json:"is_superuser"`
+	IsActive    bool   `json:"is_active"`
+	DateJoined  string `json:"date_joined"`
+}
+
+// App is an application.
+type App struct {
+	UUID      string         `json:"uuid"`
+	ID        string         `json:"id"`
+	Owner     string         `json:"owner"`
+	URL       string         `json:"url"`
+	Structure map[string]int `json:"structure"`
+	Created   string         `json:"created"`
+	Updated   string         `json:"updated"`
+}
+
+// Config is an app's configuration.
+type Config struct {
+	UUID   string                 `json:"uuid"`
+	App    string                 `json:"app"`
+	Owner  string                 `json:"owner"`
+	Values map[string]interface{} `json:"values"`
+}
+
+// Release is a release of an app.
+type Release struct {
+	UUID    string  `json:"uuid"`
+	App     string  `json:"app"`
+	Version int     `json:"version"`
+	Owner   string  `json:"owner"`
+	Summary string  `json:"summary"`
+	Build   *string `json:"build"`
+	Config  string  `json:"config"`
+	Created string  `json:"created"`
+}
+
+// Build is a build of an app.
+type Build struct {
+	UUID     string            `json:"uuid"`
+	App      string            `json:"app"`
+	Owner    string            `json:"owner"`
+	Image    string            `json:"image"`
+	Procfile map[string]string `json:"procfile"`
+	Created  string            `json:"created"`
+}
+
+// Key is a user's SSH key.
+type Key struct {
+	UUID   string `json:"uuid"`
+	ID     string `json:"id"`
+	Owner  string `json:"owner"`
+	Public string `json:"public"`
+}
+
+// Register creates a user, without logging in as it.
+func (c *Client) Register(username, password, email string) (*User, error) {
+	u := new(User)
+	body := map[string]string{"username": username, "password": password, "email": email}
+	return u, c.call("POST", "/v2/auth/register/", body, http.StatusCreated, u)
+}
+
+// Login logs in as username, setting the client's token.
+func (c *Client) Login(username, password string) error {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := c.call("POST", "/v2/auth/login/", map[string]string{"username": username, "password": password}, http.StatusOK, &body); err != nil {
+		return err
+	}
+	c.Token = body.Token
+	return nil
+}
+
+// Cancel deletes the user the client is logged in as.
+func (c *Client) Cancel() error {
+	return c.call("DELETE", "/v2/auth/cancel/", nil, http.StatusNoContent, nil)
+}
+
+// Whoami returns the user the client is logged in as.
+func (c *Client) Whoami() (*User, error) {
+	u := new(User)
+	return u, c.call("GET", "/v2/auth/whoami/", nil, http.StatusOK, u)
+}
+
+// Users lists every user. Only administrators may.
+func (c *Client) Users() ([]User, error) {
+	var users []User
+	if err := c.list("/v2/users/", &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Apps lists the apps the user can see.
+func (c *Client) Apps() ([]App, error) {
+	var apps []App
+	if err := c.list("/v2/apps/", &apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+// CreateApp creates an app called id.
+func (c *Client) CreateApp(id string) (*App, error) {
+	a := new(App)
+	return a, c.call("POST", "/v2/apps/", map[string]string{"id": id}, http.StatusCreated, a)
+}
+
+// App returns the app called id.
+func (c *Client) App(id string) (*App, error) {
+	a := new(App)
+	return a, c.call("GET", appPath(id, ""), nil, http.StatusOK, a)
+}
+
+// DeleteApp destroys the app called id.
+func (c *Client) DeleteApp(id string) error {
+	return c.call("DELETE", appPath(id, ""), nil, http.StatusNoContent, nil)
+}
+
+// Config returns an app's configuration.
+func (c *Client) Config(app string) (*Config, error) {
+	config := new(Config)
+	return config, c.call("GET", appPath(app, "config/"), nil, http.StatusOK, config)
+}
+
+// SetConfig sets an app's config values, unsetting those which are nil, and creates a release.
+func (c *Client) SetConfig(app string, values map[string]interface{}) (*Config, error) {
+	config := new(Config)
+	return config, c.call("POST", appPath(app, "config/"), map[string]interface{}{"values": values}, http.StatusCreated, config)
+}
+
+// Releases lists an app's releases, newest first.
+func (c *Client) Releases(app string) ([]Release, error) {
+	var releases []Release
+	if err := c.list(appPath(app, "releases/"), &releases); err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+// Release returns the given version of an app.
+func (c *Client) Release(app string, version int) (*Release, error) {
+	r := new(Release)
+	return r, c.call("GET", appPath(app, fmt.Sprintf("releases/v%d/", version)), nil, http.StatusOK, r)
+}
+
+// Rollback rolls an app back to version, or to the release before the latest if version is 0,
+// and returns the version of the release created.
+func (c *Client) Rollback(app string, version int) (int, error) {
+	var body interface{}
+	if version > 0 {
+		body = map[string]int{"version": version}
+	}
+	var created struct {
+		Version int `json:"version"`
+	}
+	if err := c.call("POST", appPath(app, "releases/rollback/"), body, http.StatusCreated, &created); err != nil {
+		return 0, err
+	}
+	return created.Version, nil
+}
+
+// Builds lists an app's builds, newest first.
+func (c *Client) Builds(app string) ([]Build, error) {
+	var builds []Build
+	if err := c.list(appPath(app, "builds/"), &builds); err != nil {
+		return nil, err
+	}
+	return builds, nil
+}
+
+// CreateBuild deploys image to an app.
+func (c *Client) CreateBuild(app, image string) (*Build, error) {
+	b := new(Build)
+	return b, c.call("POST", appPath(app, "builds/"), map[string]string{"image": image}, http.StatusCreated, b)
+}
+
+// Keys lists the user's keys.
+func (c *Client) Keys() ([]Key, error) {
+	var keys []Key
+	if err := c.list("/v2/keys/", &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CreateKey adds a public key called id.
+func (c *Client) CreateKey(id, public string) (*Key, error) {
+	k := new(Key)
+	return k, c.call("POST", "/v2/keys/", map[string]string{"id": id, "public": public}, http.StatusCreated, k)
+}
+
+// DeleteKey removes the key called id.
+func (c *Client) DeleteKey(id string) error {
+	return c.call("DELETE", "/v2/keys/"+url.QueryEscape(id)+"/", nil, http.StatusNoContent, nil)
+}
+
+// Perms lists the users an app is shared with.
+func (c *Client) Perms(app string) ([]string, error) {
+	var body struct {
+		Users []string `json:"users"`
+	}
+	if err := c.call("GET", appPath(app, "perms/"), nil, http.StatusOK, &body); err != nil {
+		return nil, err
+	}
+	return body.Users, nil
+}
+
+// GrantPerm shares an app with username.
+func (c *Client) GrantPerm(app, username string) error {
+	return c.call("POST", appPath(app, "perms/"), map[string]string{"username": username}, http.StatusCreated, nil)
+}
+
+// RevokePerm stops sharing an app with username.
+func (c *Client) RevokePerm(app, username string) error {
+	return c.call("DELETE", appPath(app, "perms/"+url.QueryEscape(username)+"/"), nil, http.StatusNoContent, nil)
+}
+
+func appPath(app, resource string) string {
+	return "/v2/apps/" + url.QueryEscape(app) + "/" + resource
+}
@@ -0,0 +1,126 @@
+// Package artifacts gathers what the controller, and optionally the cluster, know about a spec's
+// apps so that failures can be debugged after the apps are gone.
+package artifacts
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/deis/workflow/_tests/pkg/k8s"
+)
+
+// Runner runs the deis CLI with args and returns its combined output.
+type Runner func(args ...string) (string, error)
+
+// commands are the deis commands whose output is saved for each app, by file name.
+var commands = []struct {
+	file string
+	args []string
+}{
+	{"info.txt", []string{"info"}},
+	{"releases.txt", []string{"releases:list"}},
+	{"ps.txt", []string{"ps:list"}},
+	{"config.txt", []string{"config:list"}},
+	{"logs.txt", []string{"logs"}},
+}
+
+var unsafeRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Collector writes artifacts to a directory per spec under Dir.
+type Collector struct {
+	Dir string
+	Run Runner
+	// Kube, if set, is used to save each app's pods, events and container logs.
+	Kube *k8s.Client
+	// Redact, if set, is applied to everything saved.
+	Redact func(string) string
+}
+
+// Collect saves artifacts for apps into a directory named after spec, and returns its path.
+//
+// Collection carries on past errors, since partial artifacts are better than none; they are
+// returned together at the end.
+func (c *Collector) Collect(spec string, apps []string) (string, error) {
+	dir := filepath.Join(c.Dir, Slug(spec))
+	var errs []string
+	for _, app := range apps {
+		appDir := filepath.Join(dir, app)
+		if err := os.MkdirAll(appDir, 0755); err != nil {
+			return dir, err
+		}
+		for _, command := range commands {
+			args := append(append([]string(nil), command.args...), "-a", app)
+			output, err := c.Run(args...)
+			text := fmt.Sprintf("$ deis %s\n%s", strings.Join(args, " "), output)
+			if err != nil {
+				text += fmt.Sprintf("\n(%v)\n", err)
+			}
+			if err := c.save(filepath.Join(appDir, command.file), text); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if c.Kube != nil {
+			if err := c.collectCluster(appDir, app); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", app, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return dir, fmt.Errorf("collecting artifacts: %s", strings.Join(errs, "; "))
+	}
+	return dir, nil
+}
+
+// collectCluster saves the pods and events of app's namespace, and the logs of its containers.
+func (c *Collector) collectCluster(appDir, app string) error {
+	for _, resource := range []string{"pods", "events"} {
+		data, err := c.Kube.Get(fmt.Sprintf("/api/v1/namespaces/%s/%s", app, resource))
+		if err != nil {
+			return err
+		}
+		if err := c.save(filepath.Join(appDir, resource+".json"), string(data)); err != nil {
+			return err
+		}
+	}
+	pods, err := c.Kube.Pods(app)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			log, err := c.Kube.PodLog(app, pod.Metadata.Name, container.Name)
+			if err != nil {
+				log = err.Error()
+			}
+			name := fmt.Sprintf("%s.%s.log", pod.Metadata.Name, container.Name)
+			if err := c.save(filepath.Join(appDir, name), log); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// save writes text to path, redacted.
+func (c *Collector) save(path, text string) error {
+	if c.Redact != nil {
+		text = c.Redact(text)
+	}
+	return ioutil.WriteFile(path, []byte(text), 0644)
+}
+
+// Slug turns a spec's full text into a directory name.
+func Slug(spec string) string {
+	slug := strings.Trim(unsafeRegex.ReplaceAllString(strings.ToLower(spec), "-"), "-")
+	if len(slug) > 100 {
+		slug = strings.TrimRight(slug[:100], "-")
+	}
+	if slug == "" {
+		slug = "suite"
+	}
+	return slug
+}
@@ -0,0 +1,111 @@
+package artifacts
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deis/workflow/_tests/pkg/k8s"
+	"github.com/deis/workflow/_tests/pkg/k8s/fake"
+)
+
+func TestCollect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cluster := fake.NewServer()
+	pod := k8s.Pod{Metadata: k8s.ObjectMeta{Name: "test-1-v2-web-abcde", Namespace: "test-1"}}
+	pod.Spec.Containers = []k8s.Container{{Name: "test-1-web"}}
+	if err := cluster.Put("test-1", "pods", pod.Metadata.Name, pod); err != nil {
+		t.Fatal(err)
+	}
+	cluster.SetLog("test-1", pod.Metadata.Name, "test-1-web", "listening on :5000\n")
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+
+	var ran []string
+	collector := &Collector{
+		Dir: dir,
+		Run: func(args ...string) (string, error) {
+			ran = append(ran, strings.Join(args, " "))
+			if args[0] == "logs" {
+				return "Not found.\n", fmt.Errorf("exit status 1")
+			}
+			return "=== test-1\n", nil
+		},
+		Kube: k8s.NewClient(server.URL),
+	}
+
+	specDir, err := collector.Collect("Apps with a deployed app can scale", []string{"test-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(dir, "apps-with-a-deployed-app-can-scale"); specDir != expected {
+		t.Errorf("expected artifacts in %s, got %s", expected, specDir)
+	}
+	if len(ran) != len(commands) || ran[0] != "info -a test-1" {
+		t.Errorf("unexpected commands %v", ran)
+	}
+
+	for file, expected := range map[string]string{
+		"info.txt":                           "$ deis info -a test-1\n=== test-1\n",
+		"logs.txt":                           "$ deis logs -a test-1\nNot found.\n\n(exit status 1)\n",
+		"test-1-v2-web-abcde.test-1-web.log": "listening on :5000\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(specDir, "test-1", file))
+		if err != nil {
+			t.Error(err)
+		} else if string(data) != expected {
+			t.Errorf("expected %s to hold %q, got %q", file, expected, data)
+		}
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(specDir, "test-1", "pods.json")); err != nil {
+		t.Error(err)
+	} else if !strings.Contains(string(data), pod.Metadata.Name) {
+		t.Errorf("expected pods.json to describe the pod, got %s", data)
+	}
+}
+
+func TestCollectWithoutNamespace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	server := httptest.NewServer(fake.NewServer())
+	defer server.Close()
+
+	collector := &Collector{
+		Dir:  dir,
+		Run:  func(args ...string) (string, error) { return "", nil },
+		Kube: k8s.NewClient(server.URL),
+	}
+	specDir, err := collector.Collect("Apps", []string{"gone"})
+	if err == nil || !strings.Contains(err.Error(), "gone") {
+		t.Errorf("expected an error naming the app, got %v", err)
+	}
+	// the CLI output is still saved
+	if _, err := os.Stat(filepath.Join(specDir, "gone", "info.txt")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	for spec, expected := range map[string]string{
+		"Apps with a deployed app can't create an existing app": "apps-with-a-deployed-app-can-t-create-an-existing-app",
+		`Help prints help on "--help"`:                          "help-prints-help-on-help",
+		"":                                                      "suite",
+		strings.Repeat("a ", 80):                                strings.TrimRight(strings.Repeat("a-", 50), "-"),
+	} {
+		if actual := Slug(spec); actual != expected {
+			t.Errorf("Slug(%q) = %q, expected %q", spec, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package bench keeps a history of how long deploying, scaling, rolling back and destroying apps
+// takes, and compares runs to catch regressions.
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// Run is the result of one benchmark run.
+type Run struct {
+	Started time.Time `json:"started"`
+	// Label identifies what was benchmarked, such as the platform version.
+	Label        string                 `json:"label,omitempty"`
+	Measurements map[string]Measurement `json:"measurements"`
+}
+
+// Measurement holds the samples of one measured operation, in seconds.
+type Measurement struct {
+	Samples []float64 `json:"samples"`
+}
+
+// Mean returns the mean of the samples, or 0 if there are none.
+func (m Measurement) Mean() float64 {
+	if len(m.Samples) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, sample := range m.Samples {
+		sum += sample
+	}
+	return sum / float64(len(m.Samples))
+}
+
+// History is every benchmark run so far, oldest first.
+type History struct {
+	Runs []Run `json:"runs"`
+}
+
+// Latest returns the most recent run, or nil if there are none.
+func (h *History) Latest() *Run {
+	if len(h.Runs) == 0 {
+		return nil
+	}
+	return &h.Runs[len(h.Runs)-1]
+}
+
+// Baseline returns the run to compare the latest one against: the most recent earlier run with the
+// given label, or simply the one before the latest when label is empty.
+func (h *History) Baseline(label string) (*Run, error) {
+	for i := len(h.Runs) - 2; i >= 0; i-- {
+		if label == "" || h.Runs[i].Label == label {
+			return &h.Runs[i], nil
+		}
+	}
+	if label == "" {
+		return nil, fmt.Errorf("there is no earlier run to compare against")
+	}
+	return nil, fmt.Errorf("there is no earlier run labelled %q", label)
+}
+
+// Load reads the history at path. A missing file is an empty history.
+func Load(path string) (*History, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &History{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	h := new(History)
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return h, nil
+}
+
+// Save writes h to path as JSON.
+func Save(h *History, path string) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Append adds run to the history at path.
+func Append(path string, run Run) error {
+	h, err := Load(path)
+	if err != nil {
+		return err
+	}
+	h.Runs = append(h.Runs, run)
+	return Save(h, path)
+}
+
+// Comparison is how the mean duration of an operation changed between two runs.
+type Comparison struct {
+	Name     string  `json:"name"`
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	// Change is the relative change from the baseline, so 0.25 is 25% slower.
+	Change    float64 `json:"change"`
+	Regressed bool    `json:"regressed"`
+}
+
+// Compare compares the operations measured in both runs, sorted by name. An operation regressed
+// if it got slower by more than threshold, relative to the baseline.
+func Compare(baseline, current *Run, threshold float64) []Comparison {
+	var comparisons []Comparison
+	for name, measurement := range current.Measurements {
+		base, ok := baseline.Measurements[name]
+		if !ok || len(base.Samples) == 0 || len(measurement.Samples) == 0 {
+			continue
+		}
+		c := Comparison{Name: name, Baseline: base.Mean(), Current: measurement.Mean()}
+		if c.Baseline > 0 {
+			c.Change = (c.Current - c.Baseline) / c.Baseline
+		}
+		c.Regressed = c.Change > threshold
+		comparisons = append(comparisons, c)
+	}
+	sort.Sort(byName(comparisons))
+	return comparisons
+}
+
+type byName []Comparison
+
+func (b byName) Len() int           { return len(b) }
+func (b byName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+func (b byName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
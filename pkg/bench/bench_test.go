@@ -0,0 +1,92 @@
+package bench
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/types"
+)
+
+func TestCompare(t *testing.T) {
+	baseline := &Run{Measurements: map[string]Measurement{
+		"push":    {Samples: []float64{60, 80}},
+		"scale":   {Samples: []float64{10}},
+		"destroy": {Samples: []float64{5}},
+	}}
+	current := &Run{Measurements: map[string]Measurement{
+		"push":     {Samples: []float64{100}},
+		"scale":    {Samples: []float64{8, 10}},
+		"rollback": {Samples: []float64{3}},
+	}}
+	comparisons := Compare(baseline, current, 0.2)
+	if len(comparisons) != 2 {
+		t.Fatalf("expected only the operations measured in both runs, got %+v", comparisons)
+	}
+	push, scale := comparisons[0], comparisons[1]
+	if push.Name != "push" || push.Baseline != 70 || push.Current != 100 || !push.Regressed {
+		t.Errorf("expected push to have regressed, got %+v", push)
+	}
+	if scale.Name != "scale" || scale.Change != -0.1 || scale.Regressed {
+		t.Errorf("expected scale to have improved, got %+v", scale)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	h := &History{Runs: []Run{{Label: "v2.0.0"}, {Label: "v2.1.0"}, {Label: "v2.1.0"}}}
+	if run, err := h.Baseline(""); err != nil || run != &h.Runs[1] {
+		t.Errorf("expected the previous run, got %+v, %v", run, err)
+	}
+	if run, err := h.Baseline("v2.0.0"); err != nil || run != &h.Runs[0] {
+		t.Errorf("expected the run labelled v2.0.0, got %+v, %v", run, err)
+	}
+	if _, err := h.Baseline("v1.0.0"); err == nil {
+		t.Error("expected an error for an unknown label")
+	}
+	if _, err := (&History{Runs: h.Runs[:1]}).Baseline(""); err == nil {
+		t.Error("expected an error without an earlier run")
+	}
+}
+
+func TestReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bench.json")
+
+	for i := 0; i < 2; i++ {
+		reporter := NewReporter(path, "v2.0.0")
+		reporter.SpecSuiteWillBegin(config.GinkgoConfigType{}, &types.SuiteSummary{})
+		reporter.SpecDidComplete(&types.SpecSummary{
+			IsMeasurement: true,
+			State:         types.SpecStatePassed,
+			Measurements: map[string]*types.SpecMeasurement{
+				"push": {Name: "push", Results: []float64{60, 70}},
+			},
+		})
+		reporter.SpecDidComplete(&types.SpecSummary{
+			IsMeasurement: true,
+			State:         types.SpecStateFailed,
+			Measurements: map[string]*types.SpecMeasurement{
+				"push": {Name: "push", Results: []float64{1}},
+			},
+		})
+		reporter.SpecSuiteDidEnd(&types.SuiteSummary{})
+	}
+
+	h, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Runs) != 2 {
+		t.Fatalf("expected each suite run to be appended, got %+v", h.Runs)
+	}
+	latest := h.Latest()
+	if latest.Label != "v2.0.0" || latest.Measurements["push"].Mean() != 65 {
+		t.Errorf("expected only the passing spec's samples, got %+v", latest)
+	}
+}
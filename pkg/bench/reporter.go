@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/types"
+)
+
+// Reporter is a Ginkgo reporter which gathers the measurements of every passing Measure spec, and
+// appends them to a history file as one run when the suite ends.
+type Reporter struct {
+	path string
+	run  Run
+}
+
+// NewReporter returns a Reporter which appends to the history at path, labelling the run with
+// label.
+func NewReporter(path, label string) *Reporter {
+	return &Reporter{path: path, run: Run{Label: label}}
+}
+
+// SpecSuiteWillBegin implements ginkgo's Reporter interface.
+func (r *Reporter) SpecSuiteWillBegin(config.GinkgoConfigType, *types.SuiteSummary) {
+	r.run.Started = time.Now()
+	r.run.Measurements = make(map[string]Measurement)
+}
+
+// BeforeSuiteDidRun implements ginkgo's Reporter interface.
+func (r *Reporter) BeforeSuiteDidRun(*types.SetupSummary) {}
+
+// SpecWillRun implements ginkgo's Reporter interface.
+func (r *Reporter) SpecWillRun(*types.SpecSummary) {}
+
+// SpecDidComplete implements ginkgo's Reporter interface. Measurements of failed specs are left
+// out, since they may stop part way through a sample.
+func (r *Reporter) SpecDidComplete(summary *types.SpecSummary) {
+	if !summary.IsMeasurement || !summary.Passed() {
+		return
+	}
+	for name, measurement := range summary.Measurements {
+		m := r.run.Measurements[name]
+		m.Samples = append(m.Samples, measurement.Results...)
+		r.run.Measurements[name] = m
+	}
+}
+
+// AfterSuiteDidRun implements ginkgo's Reporter interface.
+func (r *Reporter) AfterSuiteDidRun(*types.SetupSummary) {}
+
+// SpecSuiteDidEnd implements ginkgo's Reporter interface.
+func (r *Reporter) SpecSuiteDidEnd(*types.SuiteSummary) {
+	if len(r.run.Measurements) == 0 {
+		return
+	}
+	if err := Append(r.path, r.run); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write benchmark history: %v\n", err)
+	}
+}
@@ -0,0 +1,210 @@
+// Package cassette records the HTTP exchanges between the CLI and the controller during a run into
+// cassette files, one per spec, and replays them from a local stand-in so that the same specs can
+// run offline and deterministically.
+package cassette
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Version is the cassette format this package writes.
+const Version = 1
+
+// Modes of a run.
+const (
+	// Record passes the CLI's requests on to the controller, recording them.
+	Record = "record"
+	// Replay answers the CLI's requests from recorded cassettes.
+	Replay = "replay"
+)
+
+// Cassette is the traffic recorded during one spec, or during BeforeSuite or AfterSuite.
+type Cassette struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	// Names are the random names handed out during the recording, such as app names, in order.
+	// Replays hand out the same names, so that their requests match the recorded ones.
+	Names        []string      `json:"names,omitempty"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request and the controller's response to it.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Path includes the query string.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+var unsafeRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FileName returns the name of the file the named cassette is kept in. It is readable, and unique
+// even when names are long or differ only in punctuation.
+func FileName(name string) string {
+	slug := strings.Trim(unsafeRegexp.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 80 {
+		slug = strings.TrimRight(slug[:80], "-")
+	}
+	sum := sha1.Sum([]byte(name))
+	return slug + "-" + hex.EncodeToString(sum[:4]) + ".json"
+}
+
+// Load reads the cassette for name from dir.
+func Load(dir, name string) (*Cassette, error) {
+	return load(filepath.Join(dir, FileName(name)))
+}
+
+func load(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Cassette)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	if c.Version == 0 || c.Version > Version {
+		return nil, fmt.Errorf("%s has format version %d, but this suite reads versions 1 to %d", path, c.Version, Version)
+	}
+	return c, nil
+}
+
+// Save writes c to dir.
+func Save(dir string, c *Cassette) error {
+	c.Version = Version
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, FileName(c.Name)), append(data, '\n'), 0644)
+}
+
+// LoadDir reads every cassette in dir, keyed by name.
+func LoadDir(dir string) (map[string]*Cassette, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	cassettes := make(map[string]*Cassette)
+	for _, path := range paths {
+		c, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		cassettes[c.Name] = c
+	}
+	return cassettes, nil
+}
+
+// Drift compares two recordings of the same cassette and describes how the API's behaviour
+// changed: requests made or answered differently, and responses whose JSON fields changed. Values
+// are not compared, since names, tokens and times differ between any two recordings.
+func Drift(old, new *Cassette) []string {
+	var drift []string
+	for i := 0; i < len(old.Interactions) || i < len(new.Interactions); i++ {
+		switch {
+		case i >= len(new.Interactions):
+			drift = append(drift, fmt.Sprintf("%s: %s is no longer made", new.Name, describe(old.Interactions[i].Request)))
+			continue
+		case i >= len(old.Interactions):
+			drift = append(drift, fmt.Sprintf("%s: %s is new", new.Name, describe(new.Interactions[i].Request)))
+			continue
+		}
+		o, n := old.Interactions[i], new.Interactions[i]
+		if o.Request.Method != n.Request.Method || shape(old.Names, o.Request.Path) != shape(new.Names, n.Request.Path) {
+			drift = append(drift, fmt.Sprintf("%s: request %d was %s, now %s", new.Name, i+1, describe(o.Request), describe(n.Request)))
+			continue
+		}
+		if o.Response.Status != n.Response.Status {
+			drift = append(drift, fmt.Sprintf("%s: %s answered %d, now %d", new.Name, describe(n.Request), o.Response.Status, n.Response.Status))
+		}
+		oldFields, newFields := fields(o.Response.Body), fields(n.Response.Body)
+		if added, removed := difference(newFields, oldFields), difference(oldFields, newFields); len(added)+len(removed) > 0 {
+			drift = append(drift, fmt.Sprintf("%s: %s response fields added %v, removed %v", new.Name, describe(n.Request), added, removed))
+		}
+	}
+	return drift
+}
+
+func describe(r Request) string {
+	return r.Method + " " + r.Path
+}
+
+// shape replaces the recording's random names in path, so that paths of different recordings
+// can be compared.
+func shape(names []string, path string) string {
+	for i, name := range names {
+		path = strings.Replace(path, name, fmt.Sprintf("{name%d}", i), -1)
+	}
+	return path
+}
+
+// fields returns the paths of the fields in a JSON body, such as "results[].owner".
+func fields(body string) []string {
+	var v interface{}
+	if json.Unmarshal([]byte(body), &v) != nil {
+		return nil
+	}
+	set := make(map[string]bool)
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				path := key
+				if prefix != "" {
+					path = prefix + "." + key
+				}
+				set[path] = true
+				walk(path, value)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(prefix+"[]", item)
+			}
+		}
+	}
+	walk("", v)
+	var result []string
+	for path := range set {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// difference returns the strings in a but not in b.
+func difference(a, b []string) []string {
+	in := make(map[string]bool)
+	for _, s := range b {
+		in[s] = true
+	}
+	var result []string
+	for _, s := range a {
+		if !in[s] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
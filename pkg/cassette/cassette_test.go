@@ -0,0 +1,139 @@
+package cassette
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, server *httptest.Server, path string) (int, string) {
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("DEIS_API_VERSION", "2.0")
+		if r.URL.Path == "/v2/auth/login/" {
+			w.Write([]byte(`{"token": "s3cr3t-token"}`))
+			return
+		}
+		w.Write([]byte(`{"count": ` + strconv.Itoa(calls) + `}`))
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	recorder, err := NewRecorder(target, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Redact = func(s string) string { return strings.Replace(s, "s3cr3t-token", "***", -1) }
+	server := httptest.NewServer(recorder)
+	recorder.Insert("Apps can list apps")
+	if name := recorder.Name("test-123"); name != "test-123" {
+		t.Errorf("expected the recorder to hand out fresh names, got %s", name)
+	}
+	get(t, server, "/v2/auth/login/")
+	get(t, server, "/v2/apps/")
+	get(t, server, "/v2/apps/")
+	if err := recorder.Eject(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	c, err := Load(dir, "Apps can list apps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Interactions) != 3 || strings.Contains(c.Interactions[0].Response.Body, "s3cr3t") {
+		t.Errorf("expected 3 redacted interactions, got %+v", c.Interactions)
+	}
+
+	player := NewPlayer(dir)
+	server = httptest.NewServer(player)
+	defer server.Close()
+	if err := player.Insert("Apps can list apps"); err != nil {
+		t.Fatal(err)
+	}
+	if name := player.Name("test-456"); name != "test-123" {
+		t.Errorf("expected the recorded name, got %s", name)
+	}
+	var bodies []string
+	for i := 0; i < 3; i++ {
+		_, body := get(t, server, "/v2/apps/")
+		bodies = append(bodies, body)
+	}
+	if bodies[0] != `{"count": 2}` || bodies[1] != `{"count": 3}` || bodies[2] != bodies[1] {
+		t.Errorf("expected the recorded answers in order, then the last again, got %v", bodies)
+	}
+	if err := player.Eject(); err != nil {
+		t.Errorf("expected every request to be answered, got %v", err)
+	}
+
+	player.Insert("Apps can list apps")
+	if status, _ := get(t, server, "/v2/keys/"); status != MissStatus {
+		t.Errorf("expected an unrecorded request to be answered with %d, got %d", MissStatus, status)
+	}
+	if err := player.Eject(); err == nil || !strings.Contains(err.Error(), "GET /v2/keys/") {
+		t.Errorf("expected the miss to be reported, got %v", err)
+	}
+	if err := player.Insert("Apps never recorded"); err == nil {
+		t.Error("expected a missing cassette to be an error")
+	}
+}
+
+func TestFileName(t *testing.T) {
+	a, b := FileName("Apps with a deployed app can scale"), FileName("Apps with a deployed app can scale!")
+	if !strings.HasPrefix(a, "apps-with-a-deployed-app-can-scale-") || a == b {
+		t.Errorf("expected readable, distinct names, got %s and %s", a, b)
+	}
+	if long := FileName(strings.Repeat("very long ", 30)); len(long) > 100 {
+		t.Errorf("expected long names to be shortened, got %s", long)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	old := &Cassette{Name: "Apps", Names: []string{"test-1"}, Interactions: []Interaction{
+		{Request{Method: "GET", Path: "/v2/apps/test-1/"}, Response{Status: 200, Body: `{"id": "test-1", "owner": "a"}`}},
+		{Request{Method: "GET", Path: "/v2/apps/"}, Response{Status: 200, Body: `{"results": [{"id": "test-1"}]}`}},
+	}}
+	same := &Cassette{Name: "Apps", Names: []string{"test-2"}, Interactions: []Interaction{
+		{Request{Method: "GET", Path: "/v2/apps/test-2/"}, Response{Status: 200, Body: `{"id": "test-2", "owner": "b"}`}},
+		{Request{Method: "GET", Path: "/v2/apps/"}, Response{Status: 200, Body: `{"results": [{"id": "test-2"}]}`}},
+	}}
+	if drift := Drift(old, same); len(drift) != 0 {
+		t.Errorf("expected different names and values not to count as drift, got %v", drift)
+	}
+
+	changed := &Cassette{Name: "Apps", Names: []string{"test-2"}, Interactions: []Interaction{
+		{Request{Method: "GET", Path: "/v2/apps/test-2/"}, Response{Status: 200, Body: `{"id": "test-2", "owner": "b", "structure": {}}`}},
+		{Request{Method: "GET", Path: "/v2/apps/"}, Response{Status: 500}},
+		{Request{Method: "GET", Path: "/v2/keys/"}, Response{Status: 200}},
+	}}
+	drift := Drift(old, changed)
+	if len(drift) != 4 {
+		t.Fatalf("expected a new field, a new status, a removed field and a new request, got %v", drift)
+	}
+	if !strings.Contains(drift[0], "added [structure]") || !strings.Contains(drift[1], "answered 200, now 500") ||
+		!strings.Contains(drift[2], "removed [results results[].id]") || !strings.Contains(drift[3], "GET /v2/keys/ is new") {
+		t.Errorf("unexpected drift %v", drift)
+	}
+}
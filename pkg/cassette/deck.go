@@ -0,0 +1,230 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/deis/workflow/_tests/pkg/cluster"
+)
+
+// Deck stands between the CLI and the controller, recording or replaying a cassette at a time.
+type Deck interface {
+	http.Handler
+	// Insert starts the named cassette.
+	Insert(name string) error
+	// Eject finishes the current cassette.
+	Eject() error
+	// Name returns the random name to use in place of fresh: fresh itself when recording, which
+	// is remembered, and the name recorded in its place when replaying.
+	Name(fresh string) string
+}
+
+// headers which are never recorded, since they are secret or meaningless in a replay
+var unrecordedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Date", "Content-Length"}
+
+// Recorder is a Deck which forwards requests to the controller and saves what passed in a
+// cassette per Insert.
+type Recorder struct {
+	// Redact, if set, masks secrets in requests and responses before they are saved.
+	Redact func(string) string
+
+	dir     string
+	forward *httputil.ReverseProxy
+	mu      sync.Mutex
+	current *Cassette
+}
+
+// NewRecorder returns a Recorder forwarding to the controller at target and saving cassettes to
+// dir, which is created if need be.
+func NewRecorder(target *url.URL, dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, forward: cluster.ReverseProxy(target)}, nil
+}
+
+// Insert implements Deck.
+func (r *Recorder) Insert(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = &Cassette{Name: name}
+	return nil
+}
+
+// Eject implements Deck, saving the cassette.
+func (r *Recorder) Eject() error {
+	r.mu.Lock()
+	c := r.current
+	r.current = nil
+	r.mu.Unlock()
+	if c == nil {
+		return nil
+	}
+	if r.Redact != nil {
+		// redact only now, once every secret the cassette holds, such as a login's token, is known
+		for i := range c.Interactions {
+			in := &c.Interactions[i]
+			in.Request.Path = r.Redact(in.Request.Path)
+			in.Request.Body = r.Redact(in.Request.Body)
+			in.Response.Body = r.Redact(in.Response.Body)
+			for _, values := range in.Response.Header {
+				for j := range values {
+					values[j] = r.Redact(values[j])
+				}
+			}
+		}
+	}
+	return Save(r.dir, c)
+}
+
+// Name implements Deck.
+func (r *Recorder) Name(fresh string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current != nil {
+		r.current.Names = append(r.current.Names, fresh)
+	}
+	return fresh
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	r.forward.ServeHTTP(recorder, req)
+
+	header := make(http.Header)
+	for key, values := range recorder.Header() {
+		header[key] = append([]string(nil), values...)
+		w.Header()[key] = values
+	}
+	for _, key := range unrecordedHeaders {
+		header.Del(key)
+	}
+	w.WriteHeader(recorder.Code)
+	w.Write(recorder.Body.Bytes())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current != nil {
+		r.current.Interactions = append(r.current.Interactions, Interaction{
+			Request:  Request{Method: req.Method, Path: req.URL.RequestURI(), Body: string(body)},
+			Response: Response{Status: recorder.Code, Header: header, Body: recorder.Body.String()},
+		})
+	}
+}
+
+// MissStatus is the status a Player answers requests with when its cassette has no answer.
+const MissStatus = http.StatusNotImplemented
+
+// Player is a Deck which answers requests from recorded cassettes. Each request is answered by the
+// first unplayed interaction with the same method and path; once those run out, the last of them
+// is played again, since specs poll for as long as they need to.
+type Player struct {
+	dir     string
+	mu      sync.Mutex
+	current *Cassette
+	played  []bool
+	names   int
+	misses  []string
+}
+
+// NewPlayer returns a Player reading cassettes from dir.
+func NewPlayer(dir string) *Player {
+	return &Player{dir: dir}
+}
+
+// Insert implements Deck. It fails if the named cassette was never recorded.
+func (p *Player) Insert(name string) error {
+	c, err := Load(p.dir, name)
+	if os.IsNotExist(err) {
+		err = fmt.Errorf("%q was never recorded; record it with CASSETTE_MODE=record", name)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current, p.played, p.names, p.misses = c, nil, 0, nil
+	if err != nil {
+		return err
+	}
+	p.played = make([]bool, len(c.Interactions))
+	return nil
+}
+
+// Eject implements Deck. It fails if any request could not be answered.
+func (p *Player) Eject() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	misses := p.misses
+	name := ""
+	if p.current != nil {
+		name = p.current.Name
+	}
+	p.current, p.played, p.misses = nil, nil, nil
+	if len(misses) > 0 {
+		return fmt.Errorf("the cassette for %q has no answer to %v; the spec or the CLI changed since it was recorded", name, misses)
+	}
+	return nil
+}
+
+// Name implements Deck.
+func (p *Player) Name(fresh string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current == nil || p.names >= len(p.current.Names) {
+		return fresh
+	}
+	p.names++
+	return p.current.Names[p.names-1]
+}
+
+// ServeHTTP implements http.Handler.
+func (p *Player) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	p.mu.Lock()
+	in, ok := p.next(req.Method, req.URL.RequestURI())
+	if !ok {
+		p.misses = append(p.misses, req.Method+" "+req.URL.RequestURI())
+	}
+	p.mu.Unlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("no recorded answer to %s %s", req.Method, req.URL.RequestURI()), MissStatus)
+		return
+	}
+	for key, values := range in.Response.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(in.Response.Status)
+	w.Write([]byte(in.Response.Body))
+}
+
+func (p *Player) next(method, path string) (Interaction, bool) {
+	if p.current == nil {
+		return Interaction{}, false
+	}
+	last := -1
+	for i, in := range p.current.Interactions {
+		if in.Request.Method != method || in.Request.Path != path {
+			continue
+		}
+		if !p.played[i] {
+			p.played[i] = true
+			return in, true
+		}
+		last = i
+	}
+	if last < 0 {
+		return Interaction{}, false
+	}
+	return p.current.Interactions[last], true
+}
@@ -0,0 +1,85 @@
+// Package cluster works out the addresses of the Deis Workflow cluster under test from the router
+// host and port the tests are given.
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// Environment variables holding the router's host and port.
+const (
+	RouterHostEnv = "DEIS_ROUTER_SERVICE_HOST"
+	RouterPortEnv = "DEIS_ROUTER_SERVICE_PORT"
+)
+
+// BuilderPort is the port deis-builder accepts git pushes over SSH on.
+const BuilderPort = "2222"
+
+// ErrMissingRouterHost is returned when no router host was given.
+var ErrMissingRouterHost = errors.New("missing " + RouterHostEnv)
+
+var ipv4Regex = regexp.MustCompile(`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])$`)
+
+// Controller returns the URL of the controller behind the router at host and port. An IPv4 host is
+// turned into a xip.io domain, so that app domains resolve without setting up DNS.
+func Controller(host, port string) (string, error) {
+	if host == "" {
+		return "", ErrMissingRouterHost
+	}
+	if ipv4Regex.MatchString(host) {
+		host = fmt.Sprintf("deis.%s.xip.io", host)
+	}
+	switch port {
+	case "443":
+		return "https://" + host, nil
+	case "80", "":
+		return "http://" + host, nil
+	default:
+		return fmt.Sprintf("http://%s:%s", host, port), nil
+	}
+}
+
+// Domain returns the domain apps are served from, which is the controller's domain without its
+// "deis." prefix.
+func Domain(controller string) (string, error) {
+	u, err := neturl.Parse(controller)
+	if err != nil {
+		return "", err
+	}
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if !strings.HasPrefix(host, "deis.") {
+		return "", fmt.Errorf("the controller's host %s does not start with \"deis.\", so the domain apps are served from is unknown", host)
+	}
+	return strings.TrimPrefix(host, "deis."), nil
+}
+
+// Builder returns the address deis-builder accepts SSH connections on.
+func Builder(controller string) (string, error) {
+	domain, err := Domain(controller)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort("deis-builder."+domain, BuilderPort), nil
+}
+
+// ReverseProxy returns a proxy passing requests on to the controller at target, with their Host
+// header set to the controller's, since the router picks the controller by its host name.
+func ReverseProxy(target *neturl.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	direct := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		direct(r)
+		r.Host = target.Host
+	}
+	return proxy
+}
@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"testing"
+)
+
+func TestController(t *testing.T) {
+	for _, c := range []struct{ host, port, expected string }{
+		{"192.0.2.10", "", "http://deis.192.0.2.10.xip.io"},
+		{"192.0.2.10", "31182", "http://deis.192.0.2.10.xip.io:31182"},
+		{"deis.example.com", "443", "https://deis.example.com"},
+		{"deis.example.com", "80", "http://deis.example.com"},
+	} {
+		if actual, err := Controller(c.host, c.port); err != nil || actual != c.expected {
+			t.Errorf("Controller(%q, %q) = %q, %v, expected %q", c.host, c.port, actual, err, c.expected)
+		}
+	}
+	if _, err := Controller("", "80"); err != ErrMissingRouterHost {
+		t.Errorf("expected ErrMissingRouterHost, got %v", err)
+	}
+}
+
+func TestDomainAndBuilder(t *testing.T) {
+	domain, err := Domain("http://deis.192.0.2.10.xip.io:31182")
+	if err != nil || domain != "192.0.2.10.xip.io" {
+		t.Errorf("expected the domain without deis. or the port, got %q, %v", domain, err)
+	}
+	builder, err := Builder("https://deis.example.com")
+	if err != nil || builder != "deis-builder.example.com:2222" {
+		t.Errorf("unexpected builder address %q, %v", builder, err)
+	}
+	if _, err := Domain("http://localhost:8000"); err == nil {
+		t.Error("expected an error for a controller host without deis.")
+	}
+}
+
+func TestReverseProxy(t *testing.T) {
+	var host string
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	}))
+	defer controller.Close()
+	target, _ := neturl.Parse(controller.URL)
+	proxy := httptest.NewServer(ReverseProxy(target))
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/v2/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if host != target.Host {
+		t.Errorf("expected the controller's host name %s, got %s", target.Host, host)
+	}
+}
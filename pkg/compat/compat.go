@@ -0,0 +1,209 @@
+// Package compat describes which deis CLI releases work with which controller API versions, and
+// which behaviours differ between them, so that one suite can test several CLI releases against
+// each platform release.
+package compat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// APIVersionHeader is the header the controller reports its API version in.
+const APIVersionHeader = "DEIS_API_VERSION"
+
+// Versions are the versions of the components under test.
+type Versions struct {
+	CLI string `json:"cli"`
+	API string `json:"api"`
+	// Builder is empty when the builder's version is unknown: it reports none over SSH, so it
+	// must be given.
+	Builder string `json:"builder,omitempty"`
+}
+
+// Map returns v keyed by component, for reports.
+func (v Versions) Map() map[string]string {
+	m := map[string]string{"cli": v.CLI, "api": v.API}
+	if v.Builder != "" {
+		m["builder"] = v.Builder
+	}
+	return m
+}
+
+// Platform is a controller API version range, and the CLI and builder releases which work with
+// it; Builder may be empty.
+type Platform struct {
+	API     string `yaml:"api"`
+	CLI     string `yaml:"cli"`
+	Builder string `yaml:"builder"`
+}
+
+// Feature is a behaviour which only some versions have. Specs relying on it are skipped, or
+// adapt, when the versions under test lack it.
+type Feature struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// CLI and API are the versions which have the feature; either may be empty.
+	CLI string `yaml:"cli"`
+	API string `yaml:"api"`
+}
+
+// Matrix is the declared compatibility between CLI releases and controller API versions.
+type Matrix struct {
+	Platforms []Platform `yaml:"platforms"`
+	Features  []Feature  `yaml:"features"`
+}
+
+// Load reads the matrix at path, which must exist.
+func Load(path string) (*Matrix, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := new(Matrix)
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// LoadDefault reads the matrix at path like Load, except that a missing file is an empty matrix,
+// which accepts any versions. It is for the default path, which need not exist; a path the user
+// chose must be read with Load.
+func LoadDefault(path string) (*Matrix, error) {
+	m, err := Load(path)
+	if os.IsNotExist(err) {
+		return &Matrix{}, nil
+	}
+	return m, err
+}
+
+func (m *Matrix) validate() error {
+	for _, p := range m.Platforms {
+		if p.API == "" {
+			return fmt.Errorf("a platform has no api versions")
+		}
+		for _, c := range []string{p.API, p.CLI, p.Builder} {
+			if _, err := ParseConstraint(c); err != nil {
+				return err
+			}
+		}
+	}
+	seen := make(map[string]bool)
+	for _, f := range m.Features {
+		if f.Name == "" {
+			return fmt.Errorf("a feature has no name")
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("feature %q is declared twice", f.Name)
+		}
+		seen[f.Name] = true
+		for _, c := range []string{f.API, f.CLI} {
+			if _, err := ParseConstraint(c); err != nil {
+				return fmt.Errorf("feature %q: %v", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns an error unless the matrix says v's CLI, and its builder if known, work with its
+// controller.
+func (m *Matrix) Check(v Versions) error {
+	if len(m.Platforms) == 0 {
+		return nil
+	}
+	cli, api, err := parse(v)
+	if err != nil {
+		return err
+	}
+	var builder Version
+	if v.Builder != "" {
+		if builder, err = ParseVersion(v.Builder); err != nil {
+			return fmt.Errorf("the builder's version: %v", err)
+		}
+	}
+	var supported, builders []string
+	for _, p := range m.Platforms {
+		if !allows(p.API, api) {
+			continue
+		}
+		if !allows(p.CLI, cli) {
+			supported = append(supported, p.CLI)
+			continue
+		}
+		if v.Builder == "" || allows(p.Builder, builder) {
+			return nil
+		}
+		builders = append(builders, p.Builder)
+	}
+	if len(builders) > 0 {
+		return fmt.Errorf("builder %s does not work with API version %s, which needs builder %s", v.Builder, v.API, strings.Join(builders, " or "))
+	}
+	if len(supported) == 0 {
+		return fmt.Errorf("API version %s is not in the compatibility matrix", v.API)
+	}
+	return fmt.Errorf("CLI %s does not work with API version %s, which needs CLI %s", v.CLI, v.API, strings.Join(supported, " or "))
+}
+
+// Supports reports whether both components in v have the named feature.
+func (m *Matrix) Supports(feature string, v Versions) (bool, error) {
+	for _, f := range m.Features {
+		if f.Name != feature {
+			continue
+		}
+		cli, api, err := parse(v)
+		if err != nil {
+			return false, err
+		}
+		return allows(f.CLI, cli) && allows(f.API, api), nil
+	}
+	return false, fmt.Errorf("feature %q is not in the compatibility matrix", feature)
+}
+
+// Feature returns the named feature, if it is declared.
+func (m *Matrix) Feature(name string) (Feature, bool) {
+	for _, f := range m.Features {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Feature{}, false
+}
+
+func parse(v Versions) (cli, api Version, err error) {
+	if cli, err = ParseVersion(v.CLI); err != nil {
+		return cli, api, fmt.Errorf("the CLI's version: %v", err)
+	}
+	if api, err = ParseVersion(v.API); err != nil {
+		return cli, api, fmt.Errorf("the controller's API version: %v", err)
+	}
+	return cli, api, nil
+}
+
+// allows reports whether v satisfies the constraint c, which validate has already parsed once.
+func allows(c string, v Version) bool {
+	constraint, _ := ParseConstraint(c)
+	return constraint.Allows(v)
+}
+
+// APIVersion asks the controller at url for its API version.
+func APIVersion(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url + "/v2/")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	version := resp.Header.Get(APIVersionHeader)
+	if version == "" {
+		return "", fmt.Errorf("the response (%s) has no %s header, so this is not a Deis Workflow controller", resp.Status, APIVersionHeader)
+	}
+	return version, nil
+}
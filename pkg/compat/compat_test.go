@@ -0,0 +1,142 @@
+package compat
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"2.0.0", "2.0", 0},
+		{"v2.0.1", "2.0.0", 1},
+		{"2.0.0-dev", "2.0.0", -1},
+		{"2.0.0-beta1", "2.0.0-beta2", -1},
+		{"2.1.0-alpha", "2.0.9", 1},
+		{"10.0.0", "9.0.0", 1},
+	} {
+		a, err := ParseVersion(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseVersion(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.Compare(b); got != test.want {
+			t.Errorf("comparing %s with %s: expected %d, got %d", test.a, test.b, test.want, got)
+		}
+	}
+	if _, err := ParseVersion("deis version 2"); err == nil {
+		t.Error("expected an error parsing a sentence")
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	c, err := ParseConstraint(">=2.0.0-beta1 <2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for version, want := range map[string]bool{
+		"2.0.0-alpha": false,
+		"2.0.0-beta1": true,
+		"2.0.0-dev":   true,
+		"2.0.5":       true,
+		"2.1.0-rc1":   true,
+		"2.1.0":       false,
+	} {
+		v, _ := ParseVersion(version)
+		if got := c.Allows(v); got != want {
+			t.Errorf("%s: expected %v, got %v", version, want, got)
+		}
+	}
+	if c, err := ParseConstraint("2.0"); err != nil || !c.Allows(Version{Major: 2}) {
+		t.Errorf("expected a bare version to mean equality, got %v, %v", c, err)
+	}
+	for _, bad := range []string{"=>2.0", ">=two"} {
+		if _, err := ParseConstraint(bad); err == nil {
+			t.Errorf("expected %q to be invalid", bad)
+		}
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := LoadDefault(filepath.Join(dir, "missing.yaml"))
+	if err != nil || m.Check(Versions{CLI: "anything", API: "at all"}) != nil {
+		t.Errorf("expected a missing default matrix to accept any versions, got %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected a missing matrix to be an error, got %v", err)
+	}
+
+	path := filepath.Join(dir, "compat.yaml")
+	ioutil.WriteFile(path, []byte(`
+platforms:
+- api: "2.0"
+  cli: ">=2.0.0-beta1 <2.2"
+- api: "2.1"
+  cli: ">=2.1.0"
+  builder: ">=2.1.0"
+features:
+- name: auth-regenerate
+  cli: ">=2.0.0-beta2"
+`), 0644)
+	if m, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(Versions{CLI: "2.1.3", API: "2.0"}); err != nil {
+		t.Error(err)
+	}
+	if err := m.Check(Versions{CLI: "2.0.0", API: "2.1"}); err == nil || !strings.Contains(err.Error(), ">=2.1.0") {
+		t.Errorf("expected an old CLI to be rejected naming the CLI it needs, got %v", err)
+	}
+	if err := m.Check(Versions{CLI: "2.1.0", API: "2.1", Builder: "v2.1.1"}); err != nil {
+		t.Error(err)
+	}
+	if err := m.Check(Versions{CLI: "2.1.0", API: "2.1", Builder: "v2.0.0"}); err == nil || !strings.Contains(err.Error(), "builder >=2.1.0") {
+		t.Errorf("expected an old builder to be rejected naming the builder it needs, got %v", err)
+	}
+	if err := m.Check(Versions{CLI: "2.0.0", API: "3.0"}); err == nil {
+		t.Error("expected an unknown API version to be rejected")
+	}
+
+	if ok, err := m.Supports("auth-regenerate", Versions{CLI: "2.0.0-beta1", API: "2.0"}); ok || err != nil {
+		t.Errorf("expected 2.0.0-beta1 to lack auth-regenerate, got %v, %v", ok, err)
+	}
+	if ok, err := m.Supports("auth-regenerate", Versions{CLI: "2.0.0-dev", API: "2.0"}); !ok || err != nil {
+		t.Errorf("expected 2.0.0-dev to have auth-regenerate, got %v, %v", ok, err)
+	}
+	if _, err := m.Supports("teleport", Versions{CLI: "2.0.0", API: "2.0"}); err == nil {
+		t.Error("expected an undeclared feature to be an error")
+	}
+
+	ioutil.WriteFile(path, []byte("features:\n- name: x\n  cli: \"~2.0\"\n"), 0644)
+	if _, err := Load(path); err == nil {
+		t.Error("expected a bad constraint to be rejected when loading")
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.Header().Set(APIVersionHeader, "2.0")
+		}
+	}))
+	defer server.Close()
+	if version, err := APIVersion(http.DefaultClient, server.URL); err != nil || version != "2.0" {
+		t.Errorf("expected 2.0, got %q, %v", version, err)
+	}
+}
@@ -0,0 +1,132 @@
+package compat
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a release version such as 2.0.0 or 2.0.0-dev. Missing minor and patch numbers are 0,
+// so the controller's API version "2.0" is 2.0.0.
+type Version struct {
+	Major, Minor, Patch int
+	// Pre is the pre-release part, such as "dev" or "beta1". A pre-release comes before the release
+	// it precedes, and pre-releases of the same version are ordered by comparing Pre as a string.
+	Pre string
+}
+
+var versionRegexp = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+// ParseVersion parses s, which may start with a "v".
+func ParseVersion(s string) (Version, error) {
+	match := versionRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return Version{}, fmt.Errorf("%q is not a version", s)
+	}
+	var v Version
+	for i, n := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		if match[i+1] != "" {
+			*n, _ = strconv.Atoi(match[i+1])
+		}
+	}
+	v.Pre = match[4]
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 as v comes before, is the same as, or comes after o.
+func (v Version) Compare(o Version) int {
+	for _, pair := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if pair[0] != pair[1] {
+			return sign(pair[0] - pair[1])
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+	return strings.Compare(v.Pre, o.Pre)
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// Constraint is a set of comparisons a version must all satisfy, written separated by spaces, such
+// as ">=2.0.0-beta1 <2.1". The operators are =, !=, <, <=, > and >=; a version with no operator
+// must be equal. The empty constraint is satisfied by every version.
+type Constraint []comparison
+
+type comparison struct {
+	op      string
+	version Version
+}
+
+// ParseConstraint parses s.
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, field := range strings.Fields(s) {
+		end := strings.IndexFunc(field, func(r rune) bool { return !strings.ContainsRune("<>=!", r) })
+		if end < 0 {
+			end = len(field)
+		}
+		op := field[:end]
+		switch op {
+		case "":
+			op = "="
+		case "=", "!=", "<", "<=", ">", ">=":
+		default:
+			return nil, fmt.Errorf("%q has an unknown operator %q", s, op)
+		}
+		v, err := ParseVersion(field[end:])
+		if err != nil {
+			return nil, fmt.Errorf("in %q: %v", s, err)
+		}
+		c = append(c, comparison{op, v})
+	}
+	return c, nil
+}
+
+// Allows reports whether v satisfies c.
+func (c Constraint) Allows(v Version) bool {
+	for _, cmp := range c {
+		n := v.Compare(cmp.version)
+		var ok bool
+		switch cmp.op {
+		case "=":
+			ok = n == 0
+		case "!=":
+			ok = n != 0
+		case "<":
+			ok = n < 0
+		case "<=":
+			ok = n <= 0
+		case ">":
+			ok = n > 0
+		case ">=":
+			ok = n >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
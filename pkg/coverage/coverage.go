@@ -0,0 +1,378 @@
+// Package coverage works out which of the CLI's commands and flags a run of the suite used. The
+// commands and their flags are read from the CLI's own help, and the run from the commands its
+// reports recorded.
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/deis/workflow/_tests/pkg/transcript"
+)
+
+// Command is one of the CLI's commands, as its help describes it.
+type Command struct {
+	Name string
+	// Flags are the command's options in their long form, such as --app, without --help.
+	Flags []string
+	// short maps the short forms of options, such as -a, to their long forms.
+	short map[string]string
+}
+
+// CLI is every command the CLI has.
+type CLI struct {
+	Commands map[string]*Command
+	// Shortcuts map the shortcuts the CLI takes, such as create, to the commands they run.
+	Shortcuts map[string]string
+}
+
+var (
+	topicRegex    = regexp.MustCompile(`^\s+([a-z][a-z0-9-]*)\s{2,}\S`)
+	commandRegex  = regexp.MustCompile(`(?m)^\s*([a-z][a-z0-9-]*:[a-z0-9:-]+)\s`)
+	shortcutRegex = regexp.MustCompile(`(?m)^\s*(\S+)\s+->\s+(\S+)`)
+)
+
+// ParseTopics returns the topics listed under "Subcommands" by `deis help`, such as apps and ps.
+func ParseTopics(help string) []string {
+	var topics []string
+	in := false
+	for _, line := range strings.Split(help, "\n") {
+		switch {
+		case strings.Contains(line, "Subcommands"):
+			in = true
+		case !in || strings.TrimSpace(line) == "":
+		case topicRegex.MatchString(line):
+			topics = append(topics, topicRegex.FindStringSubmatch(line)[1])
+		case !strings.HasPrefix(line, " "):
+			in = false
+		}
+	}
+	return topics
+}
+
+// ParseCommands returns the commands listed by `deis help <topic>`, such as apps:create.
+func ParseCommands(help string) []string {
+	var commands []string
+	for _, match := range commandRegex.FindAllStringSubmatch(help, -1) {
+		commands = append(commands, match[1])
+	}
+	return commands
+}
+
+// ParseCommand reads the options of command name from `deis help <name>`.
+func ParseCommand(name, help string) *Command {
+	c := &Command{Name: name, short: make(map[string]string)}
+	i := strings.Index(help, "Options:")
+	if i < 0 {
+		return c
+	}
+	for _, line := range strings.Split(help[i:], "\n")[1:] {
+		var short, long string
+		// an option's line starts with its names, such as "-a --app=<app>"; other lines describe it
+		for _, field := range strings.Fields(strings.Replace(line, ",", " ", -1)) {
+			if !strings.HasPrefix(field, "-") {
+				break
+			}
+			if strings.HasPrefix(field, "--") && len(field) > 2 {
+				long = strings.SplitN(field, "=", 2)[0]
+			} else if len(field) == 2 {
+				short = field
+			}
+		}
+		if long == "" || long == "--help" {
+			continue
+		}
+		c.Flags = append(c.Flags, long)
+		if short != "" {
+			c.short[short] = long
+		}
+	}
+	return c
+}
+
+// ParseShortcuts reads the shortcuts listed by `deis shortcuts`, such as "create -> apps:create".
+func ParseShortcuts(output string) map[string]string {
+	shortcuts := make(map[string]string)
+	for _, match := range shortcutRegex.FindAllStringSubmatch(output, -1) {
+		shortcuts[match[1]] = match[2]
+	}
+	return shortcuts
+}
+
+// Discover reads the CLI's commands and flags from its help, running it with run: `deis help`,
+// then `deis help` for each topic and command, and `deis shortcuts`.
+func Discover(run func(args ...string) (string, error)) (*CLI, error) {
+	help, err := run("help")
+	if err != nil {
+		return nil, fmt.Errorf("deis help: %v", err)
+	}
+	topics := ParseTopics(help)
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("deis help listed no topics:\n%s", help)
+	}
+	cli := &CLI{Commands: make(map[string]*Command)}
+	for _, topic := range topics {
+		help, err := run("help", topic)
+		if err != nil {
+			return nil, fmt.Errorf("deis help %s: %v", topic, err)
+		}
+		for _, name := range ParseCommands(help) {
+			help, err := run("help", name)
+			if err != nil {
+				return nil, fmt.Errorf("deis help %s: %v", name, err)
+			}
+			cli.Commands[name] = ParseCommand(name, help)
+		}
+	}
+	// older CLIs have no shortcuts command
+	output, _ := run("shortcuts")
+	cli.Shortcuts = ParseShortcuts(output)
+	return cli, nil
+}
+
+// Matrix is how much of the CLI a run used.
+type Matrix struct {
+	Commands []Coverage `json:"commands"`
+	// Unknown are the commands and flags run which the CLI's help doesn't describe, such as
+	// those run to check that they are refused.
+	Unknown []string `json:"unknown,omitempty"`
+}
+
+// Coverage is how much a run used one command.
+type Coverage struct {
+	Command string `json:"command"`
+	Runs    int    `json:"runs"`
+	// Passed counts the runs which exited 0. Only those cover the command, since a command which
+	// was only ever refused was never really tested.
+	Passed int `json:"passed"`
+	// Flags counts how many passing runs used each of the command's flags.
+	Flags map[string]int `json:"flags"`
+}
+
+// Covered reports whether a run of the command passed.
+func (c Coverage) Covered() bool {
+	return c.Passed > 0
+}
+
+// UnusedFlags returns the command's flags no passing run used, sorted.
+func (c Coverage) UnusedFlags() []string {
+	var unused []string
+	for flag, uses := range c.Flags {
+		if uses == 0 {
+			unused = append(unused, flag)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// Cover works out which commands and flags of cli the recorded commands used.
+func (c *CLI) Cover(commands []transcript.Command) *Matrix {
+	coverage := make(map[string]*Coverage)
+	for name, command := range c.Commands {
+		coverage[name] = &Coverage{Command: name, Flags: make(map[string]int)}
+		for _, flag := range command.Flags {
+			coverage[name].Flags[flag] = 0
+		}
+	}
+	unknown := make(map[string]bool)
+	for _, recorded := range commands {
+		for _, args := range Invocations(recorded.Command) {
+			if args[0] == "help" || strings.HasPrefix(args[0], "-") {
+				continue
+			}
+			name := c.resolve(args[0])
+			if name == "" {
+				unknown[args[0]] = true
+				continue
+			}
+			cov := coverage[name]
+			cov.Runs++
+			if recorded.ExitCode != 0 {
+				continue
+			}
+			cov.Passed++
+			for _, arg := range args[1:] {
+				if arg == "--" {
+					break
+				}
+				if !strings.HasPrefix(arg, "-") || arg == "-" {
+					continue
+				}
+				flag := strings.SplitN(arg, "=", 2)[0]
+				if long, ok := c.Commands[name].short[flag]; ok {
+					flag = long
+				}
+				if _, ok := cov.Flags[flag]; ok {
+					cov.Flags[flag]++
+				} else if flag != "-h" && flag != "--help" {
+					unknown[name+" "+flag] = true
+				}
+			}
+		}
+	}
+
+	var names []string
+	for name := range coverage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	m := new(Matrix)
+	for _, name := range names {
+		m.Commands = append(m.Commands, *coverage[name])
+	}
+	for name := range unknown {
+		m.Unknown = append(m.Unknown, name)
+	}
+	sort.Strings(m.Unknown)
+	return m
+}
+
+// resolve returns the command arg runs, following shortcuts and treating a bare topic as its
+// list command, or "" if it runs none.
+func (c *CLI) resolve(arg string) string {
+	if target, ok := c.Shortcuts[arg]; ok {
+		arg = target
+	}
+	if _, ok := c.Commands[arg]; ok {
+		return arg
+	}
+	if _, ok := c.Commands[arg+":list"]; ok {
+		return arg + ":list"
+	}
+	return ""
+}
+
+// Untested returns the topics none of whose commands are covered, as "ps:*", and the other
+// commands which aren't covered.
+func (m *Matrix) Untested() (topics, commands []string) {
+	covered := make(map[string]bool)
+	for _, cov := range m.Commands {
+		topic := strings.SplitN(cov.Command, ":", 2)[0]
+		covered[topic] = covered[topic] || cov.Covered()
+	}
+	for _, cov := range m.Commands {
+		topic := strings.SplitN(cov.Command, ":", 2)[0]
+		switch {
+		case !covered[topic]:
+			if len(topics) == 0 || topics[len(topics)-1] != topic+":*" {
+				topics = append(topics, topic+":*")
+			}
+		case !cov.Covered():
+			commands = append(commands, cov.Command)
+		}
+	}
+	return topics, commands
+}
+
+// Write prints m as a table of commands, with the flags each left unused, followed by totals and
+// what was left untested.
+func (m *Matrix) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMMAND\tRUNS\tPASSED\tFLAGS USED\tUNUSED FLAGS")
+	var covered, flags, used int
+	for _, cov := range m.Commands {
+		unused := cov.UnusedFlags()
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d/%d\t%s\n", cov.Command, cov.Runs, cov.Passed,
+			len(cov.Flags)-len(unused), len(cov.Flags), strings.Join(unused, " "))
+		if cov.Covered() {
+			covered++
+		}
+		flags += len(cov.Flags)
+		used += len(cov.Flags) - len(unused)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\n%d of %d commands covered, %d of %d flags used\n", covered, len(m.Commands), used, flags)
+	topics, commands := m.Untested()
+	if len(topics) > 0 {
+		fmt.Fprintf(w, "No coverage at all: %s\n", strings.Join(topics, ", "))
+	}
+	if len(commands) > 0 {
+		fmt.Fprintf(w, "Untested: %s\n", strings.Join(commands, ", "))
+	}
+	if len(m.Unknown) > 0 {
+		fmt.Fprintf(w, "Not in the CLI's help: %s\n", strings.Join(m.Unknown, ", "))
+	}
+	return nil
+}
+
+// Invocations returns the arguments of each run of the CLI in a recorded shell command line,
+// without the leading "deis". The line is split into simple commands at &&, ||, ; and |, and
+// environment assignments before the CLI are skipped.
+func Invocations(line string) [][]string {
+	var invocations [][]string
+	for _, words := range splitShell(line) {
+		for len(words) > 0 && strings.Contains(words[0], "=") && !strings.HasPrefix(words[0], "-") {
+			words = words[1:]
+		}
+		if len(words) > 1 && (words[0] == "deis" || strings.HasSuffix(words[0], "/deis")) {
+			invocations = append(invocations, words[1:])
+		}
+	}
+	return invocations
+}
+
+// splitShell splits line into simple commands and their words, following /bin/sh's quoting well
+// enough for the command lines the suite runs.
+func splitShell(line string) [][]string {
+	var (
+		commands [][]string
+		words    []string
+		word     []rune
+		inWord   bool
+		quote    rune
+	)
+	endWord := func() {
+		if inWord {
+			words = append(words, string(word))
+		}
+		word, inWord = nil, false
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+		}
+		words = nil
+	}
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word = append(word, r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]) {
+				i++
+				word = append(word, runes[i])
+			} else {
+				word = append(word, r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word, inWord = append(word, runes[i]), true
+		case r == ' ' || r == '\t' || r == '\n':
+			endWord()
+		case r == ';' || r == '|' || r == '&':
+			endCommand()
+		default:
+			word, inWord = append(word, r), true
+		}
+	}
+	endCommand()
+	return commands
+}
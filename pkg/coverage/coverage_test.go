@@ -0,0 +1,178 @@
+package coverage
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deis/workflow/_tests/pkg/transcript"
+)
+
+// help is what a small CLI prints for each help command.
+var help = map[string]string{
+	"help": `The Deis command-line client issues API calls to a Deis controller.
+
+Usage: deis <command> [<args>...]
+
+Auth commands::
+
+  register      register a new user with a controller
+  login         login to a controller
+
+Subcommands, use 'deis help [subcommand]' to learn more::
+
+  apps          manage applications used to provide services
+  ps            manage processes inside an app container
+
+Shortcut commands, use 'deis shortcuts' to see all::
+
+  create        create a new application
+`,
+	"help apps": `Valid commands for apps:
+
+apps:create        create a new application
+apps:list          list accessible applications
+
+Use 'deis help [command]' to learn more.
+`,
+	"help ps": `Valid commands for processes:
+
+ps:list        list application processes
+ps:scale       scale processes by type (web=2, worker=1)
+
+Use 'deis help [command]' to learn more.
+`,
+	"help apps:create": `Creates a new application.
+
+- if no <id> is provided, one will be generated automatically.
+
+Usage: deis apps:create [<id>] [options]
+
+Arguments:
+  <id>
+    a uniquely identifiable name for the application.
+
+Options:
+  --no-remote
+    do not create a 'deis' git remote.
+  -b --buildpack=<url>
+    a buildpack url to use for this app
+  -r --remote=<remote>
+    name of remote to create. [default: deis]
+`,
+	"help apps:list": `Lists applications visible to the current user.
+
+Usage: deis apps:list [options]
+
+Options:
+  -l --limit=<num>
+    the maximum number of results to display, defaults to config setting
+`,
+	"help ps:list": `Lists processes servicing an application.
+
+Usage: deis ps:list [options]
+
+Options:
+  -a --app=<app>
+    the uniquely identifiable name for the application.
+`,
+	"help ps:scale": `Scales an application's processes by type.
+
+Usage: deis ps:scale <type>=<num>... [options]
+
+Options:
+  -a --app=<app>
+    the uniquely identifiable name for the application.
+`,
+	"shortcuts": `Valid shortcuts are:
+
+create               -> apps:create
+scale                -> ps:scale
+`,
+}
+
+func discover(t *testing.T) *CLI {
+	cli, err := Discover(func(args ...string) (string, error) {
+		if output, ok := help[strings.Join(args, " ")]; ok {
+			return output, nil
+		}
+		return "", errors.New("exit status 1")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cli
+}
+
+func TestDiscover(t *testing.T) {
+	cli := discover(t)
+	if len(cli.Commands) != 4 {
+		t.Fatalf("expected 4 commands, got %v", cli.Commands)
+	}
+	create := cli.Commands["apps:create"]
+	if !reflect.DeepEqual(create.Flags, []string{"--no-remote", "--buildpack", "--remote"}) {
+		t.Errorf("expected apps:create's flags, got %q", create.Flags)
+	}
+	if create.short["-b"] != "--buildpack" {
+		t.Errorf("expected -b to be short for --buildpack, got %q", create.short)
+	}
+	if cli.Shortcuts["scale"] != "ps:scale" {
+		t.Errorf("expected the scale shortcut, got %v", cli.Shortcuts)
+	}
+}
+
+func TestInvocations(t *testing.T) {
+	for line, expected := range map[string][][]string{
+		"deis apps:create test-1 --no-remote":             {{"apps:create", "test-1", "--no-remote"}},
+		"DEIS_PROFILE=faults deis apps:list":              {{"apps:list"}},
+		`deis config:set 'FOO=a b' "BAR=\"c\"" -a test-1`: {{"config:set", "FOO=a b", `BAR="c"`, "-a", "test-1"}},
+		"cd /tmp/app && git push deis master":             nil,
+		"deis ps:list -a x | grep web; deis apps:list":    {{"ps:list", "-a", "x"}, {"apps:list"}},
+		"/usr/local/bin/deis whoami":                      {{"whoami"}},
+	} {
+		if got := Invocations(line); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %q to run %q, got %q", line, expected, got)
+		}
+	}
+}
+
+func TestCover(t *testing.T) {
+	m := discover(t).Cover([]transcript.Command{
+		{Command: "deis apps:create test-1 --no-remote", ExitCode: 0},
+		{Command: "deis create test-2 -b http://example.com/buildpack.git", ExitCode: 0},
+		{Command: "deis apps:create Bad_Name --remote=other", ExitCode: 1},
+		{Command: "deis apps", ExitCode: 0},
+		{Command: "deis scale web=2 -a test-1", ExitCode: 1},
+		{Command: "deis bogus-command", ExitCode: 1},
+		{Command: "deis help apps", ExitCode: 0},
+	})
+	expected := []Coverage{
+		{Command: "apps:create", Runs: 3, Passed: 2, Flags: map[string]int{"--no-remote": 1, "--buildpack": 1, "--remote": 0}},
+		{Command: "apps:list", Runs: 1, Passed: 1, Flags: map[string]int{"--limit": 0}},
+		{Command: "ps:list", Flags: map[string]int{"--app": 0}},
+		{Command: "ps:scale", Runs: 1, Flags: map[string]int{"--app": 0}},
+	}
+	if !reflect.DeepEqual(m.Commands, expected) {
+		t.Errorf("expected %+v, got %+v", expected, m.Commands)
+	}
+	if !reflect.DeepEqual(m.Unknown, []string{"bogus-command"}) {
+		t.Errorf("expected bogus-command to be unknown, got %q", m.Unknown)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"apps:create  3     2       2/3         --remote",
+		"2 of 4 commands covered, 2 of 6 flags used",
+		"No coverage at all: ps:*",
+		"Not in the CLI's help: bogus-command",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in:\n%s", line, buf.String())
+		}
+	}
+}
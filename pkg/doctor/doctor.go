@@ -0,0 +1,266 @@
+// Package doctor checks that everything the suite needs is in place before it runs, so that a
+// missing tool or an unreachable cluster is reported plainly instead of failing deep inside
+// BeforeSuite.
+package doctor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/cluster"
+	"github.com/deis/workflow/_tests/pkg/compat"
+)
+
+// APIVersionHeader is the header the controller reports its API version in.
+const APIVersionHeader = compat.APIVersionHeader
+
+// lookupHost resolves host names; tests replace it.
+var lookupHost = net.LookupHost
+
+// Check is one thing that must work for the suite to run.
+type Check struct {
+	Name string
+	// Run returns details worth showing when the check passes, or an error saying how to fix it.
+	Run func() (string, error)
+}
+
+// Warning is returned by a check which found something which may, but need not, stop the suite.
+// Print shows it without failing.
+type Warning struct {
+	Err error
+}
+
+func (w Warning) Error() string {
+	return w.Err.Error()
+}
+
+// Result is the outcome of a Check.
+type Result struct {
+	Name   string
+	Detail string
+	Err    error
+}
+
+// Run runs every check, in order.
+func Run(checks []Check) []Result {
+	results := make([]Result, len(checks))
+	for i, check := range checks {
+		detail, err := check.Run()
+		results[i] = Result{Name: check.Name, Detail: detail, Err: err}
+	}
+	return results
+}
+
+// Print writes results to w as a checklist, and reports whether they all passed.
+func Print(w io.Writer, results []Result) bool {
+	ok := true
+	for _, result := range results {
+		_, warning := result.Err.(Warning)
+		switch {
+		case warning:
+			fmt.Fprintf(w, "[WARN] %s\n       %v\n", result.Name, result.Err)
+		case result.Err != nil:
+			ok = false
+			fmt.Fprintf(w, "[FAIL] %s\n       %v\n", result.Name, result.Err)
+		case result.Detail != "":
+			fmt.Fprintf(w, "[ OK ] %s (%s)\n", result.Name, result.Detail)
+		default:
+			fmt.Fprintf(w, "[ OK ] %s\n", result.Name)
+		}
+	}
+	return ok
+}
+
+// Tool checks that the named executable is on $PATH, which the suite needs it for.
+func Tool(name, purpose string) Check {
+	return Check{
+		Name: name + " is installed",
+		Run: func() (string, error) {
+			path, err := exec.LookPath(name)
+			if err != nil {
+				return "", fmt.Errorf("%s was not found on $PATH; install it, as it is needed %s", name, purpose)
+			}
+			return path, nil
+		},
+	}
+}
+
+// CLIVersion checks that the deis CLI runs, and reports its version.
+func CLIVersion() Check {
+	return Check{
+		Name: "deis CLI runs",
+		Run: func() (string, error) {
+			output, err := exec.Command("deis", "--version").CombinedOutput()
+			version := strings.TrimSpace(string(output))
+			if err != nil {
+				if version != "" {
+					err = fmt.Errorf("%v: %s", err, version)
+				}
+				return "", fmt.Errorf("\"deis --version\" failed (%v); install the v2 CLI from http://deis.io/deis-cli/install-v2-alpha.sh", err)
+			}
+			return "version " + version, nil
+		},
+	}
+}
+
+// Controller checks that the controller answers at the given URL and reports its API version.
+func Controller(client *http.Client, controller string) Check {
+	return Check{
+		Name: "controller is reachable at " + controller,
+		Run: func() (string, error) {
+			version, err := compat.APIVersion(client, controller)
+			if err != nil {
+				return "", fmt.Errorf("%v; check DEIS_ROUTER_SERVICE_HOST and DEIS_ROUTER_SERVICE_PORT point at the router", err)
+			}
+			return "API version " + version, nil
+		},
+	}
+}
+
+// Compatible checks that the compatibility matrix says the installed CLI works with the controller,
+// and so does the builder, if its version is given.
+func Compatible(client *http.Client, controller, builder string, matrix *compat.Matrix) Check {
+	return Check{
+		Name: "CLI and controller versions are compatible",
+		Run: func() (string, error) {
+			output, err := exec.Command("deis", "--version").Output()
+			if err != nil {
+				return "", fmt.Errorf("could not get the CLI's version: %v", err)
+			}
+			v := compat.Versions{CLI: strings.TrimSpace(string(output)), Builder: builder}
+			if v.API, err = compat.APIVersion(client, controller); err != nil {
+				return "", err
+			}
+			if err := matrix.Check(v); err != nil {
+				return "", fmt.Errorf("%v; install a CLI release listed in compat.yaml", err)
+			}
+			if v.Builder != "" {
+				return fmt.Sprintf("CLI %s and builder %s with API %s", v.CLI, v.Builder, v.API), nil
+			}
+			return fmt.Sprintf("CLI %s with API %s", v.CLI, v.API), nil
+		},
+	}
+}
+
+// AdminLogin checks that the admin user can log in to the controller. Unless safe, a refused login
+// is only a Warning, since the suite registers the admin itself on a new cluster.
+func AdminLogin(client *http.Client, controller, username, password string, safe bool) Check {
+	return Check{
+		Name: fmt.Sprintf("%s can log in", username),
+		Run: func() (string, error) {
+			body, err := json.Marshal(map[string]string{"username": username, "password": password})
+			if err != nil {
+				return "", err
+			}
+			resp, err := client.Post(controller+"/v2/auth/login/", "application/json", bytes.NewReader(body))
+			if err != nil {
+				return "", err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK && safe {
+				return "", fmt.Errorf("logging in was refused (%s); in safe mode the suite never registers %q, so give its real password in TEST_ADMIN_PASSWORD", resp.Status, username)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return "", Warning{fmt.Errorf("logging in was refused (%s); the suite registers %q itself on a new cluster, but if that user already exists it must have the password in TEST_ADMIN_PASSWORD", resp.Status, username)}
+			}
+			return "", nil
+		},
+	}
+}
+
+// BuilderSSH checks that deis-builder accepts SSH connections at addr, which git pushes go to.
+func BuilderSSH(addr string, timeout time.Duration) Check {
+	return Check{
+		Name: "builder accepts SSH connections at " + addr,
+		Run: func() (string, error) {
+			conn, err := net.DialTimeout("tcp", addr, timeout)
+			if err != nil {
+				return "", fmt.Errorf("%v; git pushes go to this address, so make sure the builder's service exposes port 2222", err)
+			}
+			defer conn.Close()
+			conn.SetReadDeadline(time.Now().Add(timeout))
+			banner, err := bufio.NewReader(conn).ReadString('\n')
+			if err != nil || !strings.HasPrefix(banner, "SSH-") {
+				return "", fmt.Errorf("%s did not greet us as an SSH server; is something else listening there?", addr)
+			}
+			return strings.TrimSpace(banner), nil
+		},
+	}
+}
+
+// AppDNS checks that names under the domain apps are served from resolve.
+func AppDNS(domain string) Check {
+	host := "doctor-check." + domain
+	return Check{
+		Name: "app domains resolve",
+		Run: func() (string, error) {
+			addrs, err := lookupHost(host)
+			if err != nil {
+				return "", fmt.Errorf("%s does not resolve (%v); apps need wildcard DNS for *.%s, which xip.io gives for free if DEIS_ROUTER_SERVICE_HOST is an IP address", host, err, domain)
+			}
+			return fmt.Sprintf("%s is %s", host, strings.Join(addrs, ", ")), nil
+		},
+	}
+}
+
+// Failed returns a check which always fails with err, for checks which could not be set up.
+func Failed(name string, err error) Check {
+	return Check{Name: name, Run: func() (string, error) { return "", err }}
+}
+
+// Options configure the checks returned by Checks.
+type Options struct {
+	// Controller is the controller's URL, or empty if it could not be worked out.
+	Controller    string
+	AdminUser     string
+	AdminPassword string
+	// Safe is set for runs in safe mode, which only log in as the admin.
+	Safe bool
+	// Matrix, if set, is the compatibility matrix the CLI and controller are checked against.
+	Matrix *compat.Matrix
+	// BuilderVersion, if set, is also checked against Matrix.
+	BuilderVersion string
+	// Timeout bounds each network check.
+	Timeout time.Duration
+}
+
+// Checks returns every check the suite needs to pass, in the order they are best fixed in.
+func Checks(opts Options) []Check {
+	checks := []Check{
+		Tool("deis", "to drive the controller"),
+		CLIVersion(),
+		Tool("git", "to push apps"),
+		Tool("ssh", "for git to push over"),
+		Tool("ssh-keygen", "to create the test user's SSH key"),
+	}
+	if opts.Controller == "" {
+		return append(checks, Failed("controller is reachable",
+			fmt.Errorf("set DEIS_ROUTER_SERVICE_HOST, and DEIS_ROUTER_SERVICE_PORT if the router is not on port 80")))
+	}
+	client := &http.Client{Timeout: opts.Timeout}
+	checks = append(checks,
+		Controller(client, opts.Controller),
+		AdminLogin(client, opts.Controller, opts.AdminUser, opts.AdminPassword, opts.Safe))
+	if opts.Matrix != nil {
+		checks = append(checks, Compatible(client, opts.Controller, opts.BuilderVersion, opts.Matrix))
+	}
+	if builder, err := cluster.Builder(opts.Controller); err != nil {
+		checks = append(checks, Failed("builder accepts SSH connections", err))
+	} else {
+		checks = append(checks, BuilderSSH(builder, opts.Timeout))
+	}
+	if domain, err := cluster.Domain(opts.Controller); err != nil {
+		checks = append(checks, Failed("app domains resolve", err))
+	} else {
+		checks = append(checks, AppDNS(domain))
+	}
+	return checks
+}
@@ -0,0 +1,118 @@
+package doctor
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestController(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.Header().Set(APIVersionHeader, "2.0.0")
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	detail, err := Controller(http.DefaultClient, server.URL).Run()
+	if err != nil || detail != "API version 2.0.0" {
+		t.Errorf("expected the API version, got %q, %v", detail, err)
+	}
+
+	other := httptest.NewServer(http.NotFoundHandler())
+	defer other.Close()
+	if _, err := Controller(http.DefaultClient, other.URL).Run(); err == nil || !strings.Contains(err.Error(), APIVersionHeader) {
+		t.Errorf("expected a server without the API version header to fail, got %v", err)
+	}
+}
+
+func TestAdminLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v2/auth/login/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var buf bytes.Buffer
+		buf.ReadFrom(r.Body)
+		if !strings.Contains(buf.String(), `"password":"admin"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"token":"abc"}`))
+	}))
+	defer server.Close()
+
+	if _, err := AdminLogin(http.DefaultClient, server.URL, "admin", "admin", true).Run(); err != nil {
+		t.Errorf("expected the login to pass, got %v", err)
+	}
+	if _, err := AdminLogin(http.DefaultClient, server.URL, "admin", "wrong", true).Run(); err == nil {
+		t.Error("expected a refused login to fail in safe mode")
+	} else if _, warning := err.(Warning); warning {
+		t.Errorf("expected a refused login to be more than a warning in safe mode, got %v", err)
+	}
+	if _, err := AdminLogin(http.DefaultClient, server.URL, "admin", "wrong", false).Run(); err == nil {
+		t.Error("expected a refused login to be reported")
+	} else if _, warning := err.(Warning); !warning {
+		t.Errorf("expected a refused login to be a warning, since the suite can register the admin, got %v", err)
+	}
+}
+
+func TestBuilderSSH(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("SSH-2.0-Go\r\n"))
+			conn.Close()
+		}
+	}()
+
+	detail, err := BuilderSSH(listener.Addr().String(), time.Second).Run()
+	if err != nil || detail != "SSH-2.0-Go" {
+		t.Errorf("expected the SSH banner, got %q, %v", detail, err)
+	}
+}
+
+func TestAppDNS(t *testing.T) {
+	defer func(lookup func(string) ([]string, error)) { lookupHost = lookup }(lookupHost)
+	lookupHost = func(host string) ([]string, error) {
+		if host == "doctor-check.192.0.2.10.xip.io" {
+			return []string{"192.0.2.10"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	if _, err := AppDNS("192.0.2.10.xip.io").Run(); err != nil {
+		t.Errorf("expected the app domain to resolve, got %v", err)
+	}
+	if _, err := AppDNS("example.invalid").Run(); err == nil || !strings.Contains(err.Error(), "*.example.invalid") {
+		t.Errorf("expected an actionable error, got %v", err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var buf bytes.Buffer
+	ok := Print(&buf, Run([]Check{
+		Tool("sh", "to run commands"),
+		Tool("no-such-tool-for-doctor", "to test the doctor"),
+	}))
+	if ok {
+		t.Error("expected a failed check to fail the checklist")
+	}
+	output := buf.String()
+	if !strings.Contains(output, "[ OK ] sh is installed") || !strings.Contains(output, "[FAIL] no-such-tool-for-doctor is installed") {
+		t.Errorf("unexpected checklist:\n%s", output)
+	}
+}
@@ -0,0 +1,975 @@
+// Package fakecontroller is an in-memory stand-in for the Deis Workflow controller's v2 API, so
+// that the CLI-only parts of the suite and the runner can be exercised without a cluster.
+//
+// It covers users and tokens, apps, config, releases, builds, keys and permissions, and pages lists
+// as the controller does. It builds and runs nothing: builds of images are recorded as releases,
+// and, given a Kubernetes API server such as the one in pkg/k8s/fake, scheduled as the controller
+// would schedule them.
+package fakecontroller
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/k8s"
+	"github.com/deis/workflow/_tests/pkg/validation"
+)
+
+// Versions the fake reports in every response's headers.
+const (
+	APIVersion      = "2.0"
+	PlatformVersion = "2.0.0-fake"
+)
+
+// timeFormat is how the controller formats times.
+const timeFormat = "2006-01-02T15:04:05MST"
+
+var (
+	appPathRegex   = regexp.MustCompile(`^/v2/apps/([^/]+)/(?:(config|releases|builds|perms|scale|pods)/(?:([^/]+)/)?)?$`)
+	keyPathRegex   = regexp.MustCompile(`^/v2/keys/([^/]+)/$`)
+	adminPathRegex = regexp.MustCompile(`^/v2/admin/perms/([^/]+)/$`)
+)
+
+// pageSize is how many results a page of a list holds unless the request sets a limit, as with
+// the controller.
+const pageSize = 100
+
+// Server is a fake controller. It is safe for concurrent use.
+type Server struct {
+	// Domain is the domain apps are served from, used in their URLs.
+	Domain string
+	// Kube, if set, is the Kubernetes API server apps are scheduled on.
+	Kube *k8s.Client
+
+	mu     sync.Mutex
+	users  map[string]*user
+	tokens map[string]string
+	apps   map[string]*app
+	keys   map[string]*key
+	nextID int
+}
+
+type user struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	IsSuperuser bool   `json:"is_superuser"`
+	IsActive    bool   `json:"is_active"`
+	DateJoined  string `json:"date_joined"`
+	password    string
+	token       string
+}
+
+type app struct {
+	UUID      string         `json:"uuid"`
+	ID        string         `json:"id"`
+	Owner     string         `json:"owner"`
+	URL       string         `json:"url"`
+	Structure map[string]int `json:"structure"`
+	Created   string         `json:"created"`
+	Updated   string         `json:"updated"`
+	config    map[string]interface{}
+	memory    map[string]interface{}
+	cpu       map[string]interface{}
+	tags      map[string]interface{}
+	releases  []*release
+	builds    []*build
+	perms     []string
+}
+
+type release struct {
+	UUID    string  `json:"uuid"`
+	App     string  `json:"app"`
+	Version int     `json:"version"`
+	Owner   string  `json:"owner"`
+	Summary string  `json:"summary"`
+	Build   *string `json:"build"`
+	Config  string  `json:"config"`
+	Created string  `json:"created"`
+	Updated string  `json:"updated"`
+	values  map[string]interface{}
+	memory  map[string]interface{}
+	cpu     map[string]interface{}
+	tags    map[string]interface{}
+}
+
+type build struct {
+	UUID       string            `json:"uuid"`
+	App        string            `json:"app"`
+	Owner      string            `json:"owner"`
+	Image      string            `json:"image"`
+	Sha        string            `json:"sha"`
+	Procfile   map[string]string `json:"procfile"`
+	Dockerfile string            `json:"dockerfile"`
+	Created    string            `json:"created"`
+	Updated    string            `json:"updated"`
+}
+
+type key struct {
+	UUID    string `json:"uuid"`
+	ID      string `json:"id"`
+	Owner   string `json:"owner"`
+	Public  string `json:"public"`
+	Created string `json:"created"`
+	Updated string `json:"updated"`
+}
+
+// NewServer returns a Server with no users. As with a real controller, the first user to register
+// becomes an administrator.
+func NewServer() *Server {
+	return &Server{
+		Domain: "example.com",
+		users:  make(map[string]*user),
+		tokens: make(map[string]string),
+		apps:   make(map[string]*app),
+		keys:   make(map[string]*key),
+	}
+}
+
+// Apps returns the names of every app, sorted.
+func (s *Server) Apps() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.apps))
+	for name := range s.apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DEIS_API_VERSION", APIVersion)
+	w.Header().Set("DEIS_PLATFORM_VERSION", PlatformVersion)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v2/auth/register/":
+		s.register(w, r)
+		return
+	case "/v2/auth/login/":
+		s.login(w, r)
+		return
+	}
+
+	u := s.authenticate(r)
+	if u == nil {
+		writeError(w, http.StatusUnauthorized, "Authentication credentials were not provided.")
+		return
+	}
+	switch path := r.URL.Path; {
+	case path == "/v2/auth/whoami/":
+		writeJSON(w, http.StatusOK, u)
+	case path == "/v2/auth/cancel/":
+		s.cancel(w, r, u)
+	case path == "/v2/auth/tokens/":
+		s.regenerate(w, r, u)
+	case path == "/v2/auth/passwd/":
+		s.passwd(w, r, u)
+	case path == "/v2/users/":
+		s.listUsers(w, r, u)
+	case path == "/v2/admin/perms/":
+		s.serveAdmins(w, r, u)
+	case adminPathRegex.MatchString(path):
+		s.deleteAdmin(w, r, u, adminPathRegex.FindStringSubmatch(path)[1])
+	case path == "/v2/apps/":
+		s.serveApps(w, r, u)
+	case appPathRegex.MatchString(path):
+		match := appPathRegex.FindStringSubmatch(path)
+		s.serveApp(w, r, u, match[1], match[2], match[3])
+	case path == "/v2/keys/":
+		s.serveKeys(w, r, u)
+	case keyPathRegex.MatchString(path):
+		s.deleteKey(w, r, u, keyPathRegex.FindStringSubmatch(path)[1])
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+// authenticate returns the user whose token authorizes r, if any. s.mu must be held.
+func (s *Server) authenticate(r *http.Request) *user {
+	fields := strings.Fields(r.Header.Get("Authorization"))
+	if len(fields) != 2 || strings.ToLower(fields[0]) != "token" {
+		return nil
+	}
+	return s.users[s.tokens[fields[1]]]
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Email    string `json:"email"`
+	}
+	if !readJSON(w, r, "POST", &body) {
+		return
+	}
+	switch {
+	case body.Username == "" || body.Password == "":
+		writeFieldError(w, "username", "This field may not be blank.")
+	case s.users[body.Username] != nil:
+		writeFieldError(w, "username", "A user with that username already exists.")
+	default:
+		s.nextID++
+		u := &user{
+			ID:          s.nextID,
+			Username:    body.Username,
+			Email:       body.Email,
+			IsSuperuser: len(s.users) == 0,
+			IsActive:    true,
+			DateJoined:  now(),
+			password:    body.Password,
+		}
+		s.users[u.Username] = u
+		s.newToken(u)
+		writeJSON(w, http.StatusCreated, u)
+	}
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if !readJSON(w, r, "POST", &body) {
+		return
+	}
+	u := s.users[body.Username]
+	if u == nil || u.password != body.Password {
+		writeFieldError(w, "non_field_errors", "Unable to log in with provided credentials.")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": u.token})
+}
+
+func (s *Server) cancel(w http.ResponseWriter, r *http.Request, u *user) {
+	var body struct {
+		Username string `json:"username"`
+	}
+	if !readJSON(w, r, "DELETE", &body) {
+		return
+	}
+	target := u
+	if body.Username != "" && body.Username != u.Username {
+		if !u.IsSuperuser {
+			writeError(w, http.StatusForbidden, "You do not have permission to perform this action.")
+			return
+		}
+		if target = s.users[body.Username]; target == nil {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+	}
+	for _, a := range s.apps {
+		if a.Owner == target.Username {
+			writeError(w, http.StatusConflict, fmt.Sprintf("%s still has applications assigned. Delete or transfer ownership", target.Username))
+			return
+		}
+	}
+	delete(s.tokens, target.token)
+	delete(s.users, target.Username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) regenerate(w http.ResponseWriter, r *http.Request, u *user) {
+	var body struct {
+		Username string `json:"username"`
+		All      bool   `json:"all"`
+	}
+	if !readJSON(w, r, "POST", &body) {
+		return
+	}
+	if (body.All || body.Username != "") && !u.IsSuperuser {
+		writeError(w, http.StatusForbidden, "You do not have permission to perform this action.")
+		return
+	}
+	switch {
+	case body.All:
+		for _, other := range s.users {
+			s.newToken(other)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{}"))
+	case body.Username != "":
+		target := s.users[body.Username]
+		if target == nil {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"token": s.newToken(target)})
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"token": s.newToken(u)})
+	}
+}
+
+func (s *Server) passwd(w http.ResponseWriter, r *http.Request, u *user) {
+	var body struct {
+		Username    string `json:"username"`
+		Password    string `json:"password"`
+		NewPassword string `json:"new_password"`
+	}
+	if !readJSON(w, r, "POST", &body) {
+		return
+	}
+	target := u
+	if body.Username != "" && body.Username != u.Username {
+		if !u.IsSuperuser {
+			writeError(w, http.StatusForbidden, "You do not have permission to perform this action.")
+			return
+		}
+		if target = s.users[body.Username]; target == nil {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+	} else if body.Password != u.password {
+		writeFieldError(w, "non_field_errors", "Current password does not match")
+		return
+	}
+	if body.NewPassword == "" {
+		writeFieldError(w, "new_password", "This field may not be blank.")
+		return
+	}
+	target.password = body.NewPassword
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{}"))
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request, u *user) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method \"%s\" not allowed.", r.Method))
+		return
+	}
+	if !u.IsSuperuser {
+		writeError(w, http.StatusForbidden, "You do not have permission to perform this action.")
+		return
+	}
+	names := make([]string, 0, len(s.users))
+	for name := range s.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	results := make([]interface{}, len(names))
+	for i, name := range names {
+		results[i] = s.users[name]
+	}
+	writeList(w, r, results)
+}
+
+func (s *Server) serveAdmins(w http.ResponseWriter, r *http.Request, u *user) {
+	if !u.IsSuperuser {
+		writeError(w, http.StatusForbidden, "You do not have permission to perform this action.")
+		return
+	}
+	switch r.Method {
+	case "GET":
+		names := make([]string, 0, len(s.users))
+		for name, other := range s.users {
+			if other.IsSuperuser {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		results := make([]interface{}, len(names))
+		for i, name := range names {
+			results[i] = map[string]interface{}{"username": name, "is_superuser": true}
+		}
+		writeList(w, r, results)
+	case "POST":
+		var body struct {
+			Username string `json:"username"`
+		}
+		if !readJSON(w, r, "POST", &body) {
+			return
+		}
+		target := s.users[body.Username]
+		if target == nil {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		target.IsSuperuser = true
+		w.WriteHeader(http.StatusCreated)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method \"%s\" not allowed.", r.Method))
+	}
+}
+
+func (s *Server) deleteAdmin(w http.ResponseWriter, r *http.Request, u *user, username string) {
+	if r.Method != "DELETE" {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method \"%s\" not allowed.", r.Method))
+		return
+	}
+	if !u.IsSuperuser {
+		writeError(w, http.StatusForbidden, "You do not have permission to perform this action.")
+		return
+	}
+	target := s.users[username]
+	if target == nil || !target.IsSuperuser {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	target.IsSuperuser = false
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) serveApps(w http.ResponseWriter, r *http.Request, u *user) {
+	switch r.Method {
+	case "GET":
+		names := make([]string, 0, len(s.apps))
+		for name, a := range s.apps {
+			if a.visibleTo(u) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		results := make([]interface{}, len(names))
+		for i, name := range names {
+			results[i] = s.apps[name]
+		}
+		writeList(w, r, results)
+	case "POST":
+		var body struct {
+			ID string `json:"id"`
+		}
+		if !readJSON(w, r, "POST", &body) {
+			return
+		}
+		if body.ID == "" {
+			body.ID = "app-" + newUUID()[:8]
+		}
+		if err := validation.AppName(body.ID); err != nil {
+			writeFieldError(w, "id", err.Error())
+			return
+		}
+		if s.apps[body.ID] != nil {
+			writeFieldError(w, "id", "App with this id already exists.")
+			return
+		}
+		created := now()
+		a := &app{
+			UUID:      newUUID(),
+			ID:        body.ID,
+			Owner:     u.Username,
+			URL:       body.ID + "." + s.Domain,
+			Structure: map[string]int{},
+			Created:   created,
+			Updated:   created,
+			config:    map[string]interface{}{},
+			memory:    map[string]interface{}{},
+			cpu:       map[string]interface{}{},
+			tags:      map[string]interface{}{},
+		}
+		if err := s.createNamespace(a.ID); err != nil {
+			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("could not create the namespace: %v", err))
+			return
+		}
+		a.addRelease(u.Username, fmt.Sprintf("%s created initial release", u.Username))
+		s.apps[a.ID] = a
+		writeJSON(w, http.StatusCreated, a)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method \"%s\" not allowed.", r.Method))
+	}
+}
+
+func (s *Server) serveApp(w http.ResponseWriter, r *http.Request, u *user, id, resource, sub string) {
+	a := s.apps[id]
+	if a == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	// collaborators may do anything but destroy the app or share it further
+	owner := u.IsSuperuser || a.Owner == u.Username
+	if !a.visibleTo(u) || (!owner && (r.Method == "DELETE" && resource == "" || resource == "perms" && r.Method != "GET")) {
+		writeError(w, http.StatusForbidden, "You do not have permission to perform this action.")
+		return
+	}
+	switch {
+	case resource == "config" && sub != "":
+		writeError(w, http.StatusNotFound, "Not found.")
+	case resource == "" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, a)
+	case resource == "" && r.Method == "DELETE":
+		if err := s.deleteNamespace(id); err != nil {
+			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("could not delete the namespace: %v", err))
+			return
+		}
+		delete(s.apps, id)
+		w.WriteHeader(http.StatusNoContent)
+	case resource == "config" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, a.configJSON())
+	case resource == "config" && r.Method == "POST":
+		var body struct {
+			Values map[string]interface{} `json:"values"`
+			Memory map[string]interface{} `json:"memory"`
+			CPU    map[string]interface{} `json:"cpu"`
+			Tags   map[string]interface{} `json:"tags"`
+		}
+		if !readJSON(w, r, "POST", &body) {
+			return
+		}
+		for k, v := range body.Values {
+			if err := validation.ConfigKey(k); err != nil {
+				writeFieldError(w, "values", err.Error())
+				return
+			}
+			if err := validation.ConfigValue(fmt.Sprint(v)); v != nil && err != nil {
+				writeFieldError(w, "values", err.Error())
+				return
+			}
+		}
+		fields := []struct {
+			name    string
+			changes map[string]interface{}
+			config  map[string]interface{}
+		}{
+			{"values", body.Values, a.config},
+			{"memory", body.Memory, a.memory},
+			{"cpu", body.CPU, a.cpu},
+			{"tags", body.Tags, a.tags},
+		}
+		// check every field before changing any, so that a rejected request changes nothing
+		for _, field := range fields {
+			for k, v := range field.changes {
+				if _, ok := field.config[k]; v == nil && !ok {
+					writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("%s does not exist under %s", k, field.name))
+					return
+				}
+			}
+		}
+		var changes []string
+		for _, field := range fields {
+			added, removed := applyChanges(field.config, field.changes)
+			if field.name != "values" {
+				// the controller describes limits and tags changes by process type or tag
+				added = append(added, removed...)
+				sort.Strings(added)
+				if len(added) > 0 {
+					changes = append(changes, fmt.Sprintf("changed %s for %s", field.name, strings.Join(added, ", ")))
+				}
+				continue
+			}
+			if len(added) > 0 {
+				changes = append(changes, "added "+strings.Join(added, ", "))
+			}
+			if len(removed) > 0 {
+				changes = append(changes, "deleted "+strings.Join(removed, ", "))
+			}
+		}
+		a.addRelease(u.Username, fmt.Sprintf("%s %s", u.Username, strings.Join(changes, " and ")))
+		if !s.scheduled(w, a) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, a.configJSON())
+	case resource == "releases" && sub == "" && r.Method == "GET":
+		results := make([]interface{}, len(a.releases))
+		for i := range a.releases {
+			results[i] = a.releases[len(a.releases)-1-i]
+		}
+		writeList(w, r, results)
+	case resource == "releases" && sub == "rollback" && r.Method == "POST":
+		var body struct {
+			Version int `json:"version"`
+		}
+		if !readJSON(w, r, "POST", &body) {
+			return
+		}
+		latest := a.releases[len(a.releases)-1].Version
+		if body.Version == 0 {
+			body.Version = latest - 1
+		}
+		if body.Version < 1 || body.Version >= latest {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("version cannot be below 0 or the latest release, v%d", latest))
+			return
+		}
+		target := a.releases[body.Version-1]
+		a.config, a.memory, a.cpu, a.tags = copyValues(target.values), copyValues(target.memory), copyValues(target.cpu), copyValues(target.tags)
+		rollback := a.addRelease(u.Username, fmt.Sprintf("%s rolled back to v%d", u.Username, body.Version))
+		if !s.scheduled(w, a) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]int{"version": rollback.Version})
+	case resource == "releases" && strings.HasPrefix(sub, "v") && r.Method == "GET":
+		version, err := strconv.Atoi(sub[1:])
+		if err != nil || version < 1 || version > len(a.releases) {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		writeJSON(w, http.StatusOK, a.releases[version-1])
+	case resource == "builds" && sub == "" && r.Method == "GET":
+		results := make([]interface{}, len(a.builds))
+		for i := range a.builds {
+			results[i] = a.builds[len(a.builds)-1-i]
+		}
+		writeList(w, r, results)
+	case resource == "builds" && sub == "" && r.Method == "POST":
+		var body struct {
+			Image    string            `json:"image"`
+			Procfile map[string]string `json:"procfile"`
+		}
+		if !readJSON(w, r, "POST", &body) {
+			return
+		}
+		if body.Image == "" {
+			writeFieldError(w, "image", "This field is required.")
+			return
+		}
+		b := a.addBuild(u.Username, body.Image, body.Procfile)
+		if !s.scheduled(w, a) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, b)
+	case resource == "scale" && sub == "" && r.Method == "POST":
+		var body map[string]int
+		if !readJSON(w, r, "POST", &body) {
+			return
+		}
+		if len(a.builds) == 0 {
+			writeError(w, http.StatusBadRequest, "No build associated with this release")
+			return
+		}
+		for procType, replicas := range body {
+			if !a.hasProcType(procType) {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("Container type %s does not exist in application", procType))
+				return
+			}
+			if replicas < 0 {
+				writeError(w, http.StatusBadRequest, "Invalid scaling format")
+				return
+			}
+		}
+		for procType, replicas := range body {
+			a.Structure[procType] = replicas
+		}
+		if !s.scheduled(w, a) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case resource == "pods" && sub == "" && r.Method == "GET":
+		results, err := s.podsJSON(a)
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("could not list pods: %v", err))
+			return
+		}
+		writeList(w, r, results)
+	case resource == "perms" && sub == "" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, map[string][]string{"users": append([]string{}, a.perms...)})
+	case resource == "perms" && sub == "" && r.Method == "POST":
+		var body struct {
+			Username string `json:"username"`
+		}
+		if !readJSON(w, r, "POST", &body) {
+			return
+		}
+		if s.users[body.Username] == nil {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		if !a.sharedWith(body.Username) {
+			a.perms = append(a.perms, body.Username)
+			sort.Strings(a.perms)
+		}
+		w.WriteHeader(http.StatusCreated)
+	case resource == "perms" && sub != "" && r.Method == "DELETE":
+		if !a.sharedWith(sub) {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		for i, name := range a.perms {
+			if name == sub {
+				a.perms = append(a.perms[:i], a.perms[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method \"%s\" not allowed.", r.Method))
+	}
+}
+
+func (s *Server) serveKeys(w http.ResponseWriter, r *http.Request, u *user) {
+	switch r.Method {
+	case "GET":
+		ids := make([]string, 0, len(s.keys))
+		for id, k := range s.keys {
+			if k.Owner == u.Username {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		results := make([]interface{}, len(ids))
+		for i, id := range ids {
+			results[i] = s.keys[id]
+		}
+		writeList(w, r, results)
+	case "POST":
+		var body struct {
+			ID     string `json:"id"`
+			Public string `json:"public"`
+		}
+		if !readJSON(w, r, "POST", &body) {
+			return
+		}
+		if s.keys[body.ID] != nil {
+			writeFieldError(w, "id", "Key with this id already exists.")
+			return
+		}
+		created := now()
+		k := &key{UUID: newUUID(), ID: body.ID, Owner: u.Username, Public: body.Public, Created: created, Updated: created}
+		s.keys[k.ID] = k
+		writeJSON(w, http.StatusCreated, k)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method \"%s\" not allowed.", r.Method))
+	}
+}
+
+func (s *Server) deleteKey(w http.ResponseWriter, r *http.Request, u *user, id string) {
+	if r.Method != "DELETE" {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method \"%s\" not allowed.", r.Method))
+		return
+	}
+	k := s.keys[id]
+	if k == nil || k.Owner != u.Username {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	delete(s.keys, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newToken gives u a new token, invalidating its old one. s.mu must be held.
+func (s *Server) newToken(u *user) string {
+	delete(s.tokens, u.token)
+	u.token = newUUID()
+	s.tokens[u.token] = u.Username
+	return u.token
+}
+
+// scheduled schedules the app, writing an error response and returning false if that fails.
+// s.mu must be held.
+func (s *Server) scheduled(w http.ResponseWriter, a *app) bool {
+	if err := s.schedule(a); err != nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("could not schedule %s: %v", a.ID, err))
+		return false
+	}
+	return true
+}
+
+// hasProcType reports whether the app's latest build has the process type: one of its Procfile's,
+// or "cmd", the image's own command, without one.
+func (a *app) hasProcType(procType string) bool {
+	procfile := a.builds[len(a.builds)-1].Procfile
+	if len(procfile) == 0 {
+		return procType == "cmd"
+	}
+	_, ok := procfile[procType]
+	return ok
+}
+
+// visibleTo reports whether u may see the app.
+func (a *app) visibleTo(u *user) bool {
+	return u.IsSuperuser || a.Owner == u.Username || a.sharedWith(u.Username)
+}
+
+// sharedWith reports whether the app's owner shared it with username.
+func (a *app) sharedWith(username string) bool {
+	for _, name := range a.perms {
+		if name == username {
+			return true
+		}
+	}
+	return false
+}
+
+// addBuild records a build of image and releases it. Nothing runs, but the app's structure is
+// scaled up as the controller would on a first deploy.
+func (a *app) addBuild(owner, image string, procfile map[string]string) *build {
+	created := now()
+	b := &build{
+		UUID:     newUUID(),
+		App:      a.ID,
+		Owner:    owner,
+		Image:    image,
+		Procfile: procfile,
+		Created:  created,
+		Updated:  created,
+	}
+	if b.Procfile == nil {
+		b.Procfile = map[string]string{}
+	}
+	a.builds = append(a.builds, b)
+	if len(a.Structure) == 0 {
+		// the first deploy scales up the web process, or the image's own command without a Procfile
+		if len(procfile) == 0 {
+			a.Structure["cmd"] = 1
+		} else if _, ok := procfile["web"]; ok {
+			a.Structure["web"] = 1
+		}
+	}
+	rel := a.addRelease(owner, fmt.Sprintf("%s deployed %s", owner, image))
+	rel.Build = &b.UUID
+	return b
+}
+
+// addRelease records a new release of the app's current config and build.
+func (a *app) addRelease(owner, summary string) *release {
+	created := now()
+	rel := &release{
+		UUID:    newUUID(),
+		App:     a.ID,
+		Version: len(a.releases) + 1,
+		Owner:   owner,
+		Summary: summary,
+		Config:  newUUID(),
+		Created: created,
+		Updated: created,
+		values:  copyValues(a.config),
+		memory:  copyValues(a.memory),
+		cpu:     copyValues(a.cpu),
+		tags:    copyValues(a.tags),
+	}
+	if len(a.releases) > 0 {
+		rel.Build = a.releases[len(a.releases)-1].Build
+	}
+	a.releases = append(a.releases, rel)
+	a.Updated = created
+	return rel
+}
+
+func (a *app) configJSON() map[string]interface{} {
+	latest := a.releases[len(a.releases)-1]
+	return map[string]interface{}{
+		"uuid":        latest.Config,
+		"app":         a.ID,
+		"owner":       a.Owner,
+		"values":      a.config,
+		"memory":      a.memory,
+		"cpu":         a.cpu,
+		"tags":        a.tags,
+		"registry":    map[string]interface{}{},
+		"healthcheck": map[string]interface{}{},
+		"created":     latest.Created,
+		"updated":     latest.Updated,
+	}
+}
+
+// applyChanges sets the changed keys of config, deleting those changed to nil, and returns the
+// keys added or changed and those deleted, sorted.
+func applyChanges(config, changes map[string]interface{}) (added, removed []string) {
+	for k, v := range changes {
+		if v == nil {
+			delete(config, k)
+			removed = append(removed, k)
+		} else {
+			config[k] = fmt.Sprint(v)
+			added = append(added, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
+
+func now() string {
+	return time.Now().UTC().Format(timeFormat)
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[:8], h[8:12], h[12:16], h[16:20], h[20:])
+}
+
+// readJSON decodes r's body into v, after checking r's method. A missing body is left as the zero
+// value. It writes an error response and returns false if either is wrong.
+func readJSON(w http.ResponseWriter, r *http.Request, method string, v interface{}) bool {
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method \"%s\" not allowed.", r.Method))
+		return false
+	}
+	if r.Body == nil || r.ContentLength == 0 {
+		return true
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("JSON parse error - %v", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeList writes the page of results r asks for with its limit and offset parameters, linking
+// to the pages either side.
+func writeList(w http.ResponseWriter, r *http.Request, results []interface{}) {
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = pageSize
+	}
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	// link returns the URL of the page starting at offset
+	link := func(offset int) string {
+		q := url.Values{"limit": {strconv.Itoa(limit)}}
+		if offset > 0 {
+			q.Set("offset", strconv.Itoa(offset))
+		}
+		return (&url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: q.Encode()}).String()
+	}
+	page := map[string]interface{}{"count": len(results), "next": nil, "previous": nil}
+	if offset > 0 {
+		previous := offset - limit
+		if previous < 0 {
+			previous = 0
+		}
+		page["previous"] = link(previous)
+	}
+	if offset+limit < len(results) {
+		page["next"] = link(offset + limit)
+	}
+	if offset > len(results) {
+		offset = len(results)
+	}
+	end := offset + limit
+	if end > len(results) {
+		end = len(results)
+	}
+	page["results"] = results[offset:end]
+	writeJSON(w, http.StatusOK, page)
+}
+
+func writeError(w http.ResponseWriter, code int, detail string) {
+	writeJSON(w, code, map[string]string{"detail": detail})
+}
+
+// writeFieldError writes a validation error about field, as the controller does.
+func writeFieldError(w http.ResponseWriter, field, message string) {
+	writeJSON(w, http.StatusBadRequest, map[string][]string{field: {message}})
+}
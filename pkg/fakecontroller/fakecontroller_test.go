@@ -0,0 +1,285 @@
+package fakecontroller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/deis/workflow/_tests/pkg/k8s"
+	"github.com/deis/workflow/_tests/pkg/k8s/fake"
+)
+
+type client struct {
+	t      *testing.T
+	server *httptest.Server
+	token  string
+}
+
+// do sends body as JSON and decodes the response into out, if set, returning the status code.
+func (c *client) do(method, path string, body, out interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, err := http.NewRequest(method, c.server.URL+path, &buf)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "token "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("DEIS_API_VERSION") != APIVersion {
+		c.t.Errorf("%s %s: expected the API version header", method, path)
+	}
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func (c *client) register(username, password string) {
+	if code := c.do("POST", "/v2/auth/register/", map[string]string{"username": username, "password": password}, nil); code != http.StatusCreated {
+		c.t.Fatalf("registering %s: got %d", username, code)
+	}
+	var login struct{ Token string }
+	if code := c.do("POST", "/v2/auth/login/", map[string]string{"username": username, "password": password}, &login); code != http.StatusOK {
+		c.t.Fatalf("logging in as %s: got %d", username, code)
+	}
+	c.token = login.Token
+}
+
+func TestAuth(t *testing.T) {
+	server := httptest.NewServer(NewServer())
+	defer server.Close()
+	admin := &client{t: t, server: server}
+	admin.register("admin", "admin")
+	user := &client{t: t, server: server}
+	user.register("test-1", "asdf1234")
+
+	var me struct {
+		Username    string `json:"username"`
+		IsSuperuser bool   `json:"is_superuser"`
+	}
+	admin.do("GET", "/v2/auth/whoami/", nil, &me)
+	if me.Username != "admin" || !me.IsSuperuser {
+		t.Errorf("expected the first user to be an admin, got %+v", me)
+	}
+	if code := user.do("GET", "/v2/users/", nil, nil); code != http.StatusForbidden {
+		t.Errorf("expected only admins to list users, got %d", code)
+	}
+	if code := (&client{t: t, server: server}).do("GET", "/v2/apps/", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("expected requests without a token to be refused, got %d", code)
+	}
+
+	// regenerating every token logs everyone out
+	admin.do("POST", "/v2/auth/tokens/", map[string]bool{"all": true}, nil)
+	if code := user.do("GET", "/v2/auth/whoami/", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("expected the old token to stop working, got %d", code)
+	}
+}
+
+func TestAppsConfigAndReleases(t *testing.T) {
+	server := httptest.NewServer(NewServer())
+	defer server.Close()
+	c := &client{t: t, server: server}
+	c.register("admin", "admin")
+
+	if code := c.do("POST", "/v2/apps/", map[string]string{"id": "test-1"}, nil); code != http.StatusCreated {
+		t.Fatalf("creating an app: got %d", code)
+	}
+	if code := c.do("POST", "/v2/apps/", map[string]string{"id": "test-1"}, nil); code != http.StatusBadRequest {
+		t.Errorf("expected a duplicate app to be refused, got %d", code)
+	}
+	for _, values := range []map[string]string{{"1FOO": "bar"}, {"FOO-BAR": "bar"}, {"FOO": ""}} {
+		if code := c.do("POST", "/v2/apps/test-1/config/", map[string]interface{}{"values": values}, nil); code != http.StatusBadRequest {
+			t.Errorf("expected config %v to be refused, got %d", values, code)
+		}
+	}
+	c.do("POST", "/v2/apps/test-1/config/", map[string]interface{}{"values": map[string]string{"FOO": "bar"}}, nil)
+	c.do("POST", "/v2/apps/test-1/config/", map[string]interface{}{"values": map[string]interface{}{"FOO": nil}}, nil)
+
+	var rollback struct{ Version int }
+	if code := c.do("POST", "/v2/apps/test-1/releases/rollback/", map[string]int{"version": 2}, &rollback); code != http.StatusCreated || rollback.Version != 4 {
+		t.Fatalf("expected rolling back to create v4, got %d, %+v", code, rollback)
+	}
+	var config struct{ Values map[string]string }
+	c.do("GET", "/v2/apps/test-1/config/", nil, &config)
+	if config.Values["FOO"] != "bar" {
+		t.Errorf("expected the rollback to restore FOO, got %+v", config.Values)
+	}
+	partial := map[string]interface{}{"values": map[string]string{"FOO": "baz"}, "tags": map[string]interface{}{"MISSING": nil}}
+	if code := c.do("POST", "/v2/apps/test-1/config/", partial, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("expected unsetting a missing tag to be refused, got %d", code)
+	}
+	c.do("GET", "/v2/apps/test-1/config/", nil, &config)
+	if config.Values["FOO"] != "bar" {
+		t.Errorf("expected a refused change to leave FOO alone, got %+v", config.Values)
+	}
+	var releases struct {
+		Count   int
+		Results []struct {
+			Version int
+			Summary string
+		}
+	}
+	c.do("GET", "/v2/apps/test-1/releases/", nil, &releases)
+	if releases.Count != 4 || releases.Results[0].Summary != "admin rolled back to v2" || releases.Results[3].Summary != "admin created initial release" {
+		t.Errorf("unexpected releases %+v", releases)
+	}
+
+	if code := c.do("DELETE", "/v2/apps/test-1/", nil, nil); code != http.StatusNoContent {
+		t.Errorf("destroying the app: got %d", code)
+	}
+	if code := c.do("GET", "/v2/apps/test-1/", nil, nil); code != http.StatusNotFound {
+		t.Errorf("expected the app to be gone, got %d", code)
+	}
+}
+
+func TestPermsAndBuilds(t *testing.T) {
+	server := httptest.NewServer(NewServer())
+	defer server.Close()
+	admin := &client{t: t, server: server}
+	admin.register("admin", "admin")
+	owner := &client{t: t, server: server}
+	owner.register("test-1", "asdf1234")
+	collaborator := &client{t: t, server: server}
+	collaborator.register("test-2", "asdf1234")
+
+	owner.do("POST", "/v2/apps/", map[string]string{"id": "test-1"}, nil)
+	if code := collaborator.do("GET", "/v2/apps/test-1/", nil, nil); code != http.StatusForbidden {
+		t.Errorf("expected others not to see the app, got %d", code)
+	}
+	if code := owner.do("POST", "/v2/apps/test-1/perms/", map[string]string{"username": "test-2"}, nil); code != http.StatusCreated {
+		t.Fatalf("sharing the app: got %d", code)
+	}
+	var perms struct{ Users []string }
+	collaborator.do("GET", "/v2/apps/test-1/perms/", nil, &perms)
+	if len(perms.Users) != 1 || perms.Users[0] != "test-2" {
+		t.Errorf("unexpected perms %+v", perms)
+	}
+	if code := collaborator.do("DELETE", "/v2/apps/test-1/", nil, nil); code != http.StatusForbidden {
+		t.Errorf("expected collaborators not to destroy the app, got %d", code)
+	}
+
+	var b struct{ UUID string }
+	if code := collaborator.do("POST", "/v2/apps/test-1/builds/", map[string]string{"image": "deis/example-go"}, &b); code != http.StatusCreated {
+		t.Fatalf("deploying an image: got %d", code)
+	}
+	var rel struct {
+		Build   *string
+		Summary string
+	}
+	owner.do("GET", "/v2/apps/test-1/releases/v2/", nil, &rel)
+	if rel.Build == nil || *rel.Build != b.UUID || rel.Summary != "test-2 deployed deis/example-go" {
+		t.Errorf("expected the build to be released, got %+v", rel)
+	}
+
+	if code := owner.do("DELETE", "/v2/apps/test-1/perms/test-2/", nil, nil); code != http.StatusNoContent {
+		t.Errorf("unsharing the app: got %d", code)
+	}
+	if code := collaborator.do("GET", "/v2/apps/test-1/", nil, nil); code != http.StatusForbidden {
+		t.Errorf("expected the collaborator to lose access, got %d", code)
+	}
+
+	if code := owner.do("POST", "/v2/admin/perms/", map[string]string{"username": "test-2"}, nil); code != http.StatusForbidden {
+		t.Errorf("expected only admins to make admins, got %d", code)
+	}
+	admin.do("POST", "/v2/admin/perms/", map[string]string{"username": "test-2"}, nil)
+	if code := collaborator.do("GET", "/v2/users/", nil, nil); code != http.StatusOK {
+		t.Errorf("expected the new admin to list users, got %d", code)
+	}
+}
+
+func TestPages(t *testing.T) {
+	server := httptest.NewServer(NewServer())
+	defer server.Close()
+	c := &client{t: t, server: server}
+	c.register("admin", "admin")
+	for _, id := range []string{"a", "b", "c"} {
+		c.do("POST", "/v2/apps/", map[string]string{"id": id}, nil)
+	}
+
+	var page struct {
+		Count    int
+		Next     *string
+		Previous *string
+		Results  []struct{ ID string }
+	}
+	c.do("GET", "/v2/apps/?limit=2", nil, &page)
+	if page.Count != 3 || len(page.Results) != 2 || page.Next == nil || page.Previous != nil {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	next, _ := url.Parse(*page.Next)
+	page.Next, page.Previous = nil, nil
+	c.do("GET", next.RequestURI(), nil, &page)
+	if len(page.Results) != 1 || page.Results[0].ID != "c" || page.Next != nil || page.Previous == nil {
+		t.Errorf("unexpected last page %+v", page)
+	}
+}
+
+func TestScheduling(t *testing.T) {
+	kubeServer := httptest.NewServer(fake.NewServer())
+	defer kubeServer.Close()
+	kube := k8s.NewClient(kubeServer.URL)
+	controller := NewServer()
+	controller.Kube = kube
+	server := httptest.NewServer(controller)
+	defer server.Close()
+	c := &client{t: t, server: server}
+	c.register("admin", "admin")
+
+	if code := c.do("POST", "/v2/apps/", map[string]string{"id": "test-1"}, nil); code != http.StatusCreated {
+		t.Fatalf("creating an app: got %d", code)
+	}
+	if _, err := kube.Namespace("test-1"); err != nil {
+		t.Fatalf("expected a namespace for the app, got %v", err)
+	}
+	if code := c.do("POST", "/v2/apps/test-1/scale/", map[string]int{"cmd": 2}, nil); code != http.StatusBadRequest {
+		t.Errorf("expected scaling an app never built to fail, got %d", code)
+	}
+
+	c.do("POST", "/v2/apps/test-1/builds/", map[string]string{"image": "deis/example-go"}, nil)
+	if code := c.do("POST", "/v2/apps/test-1/scale/", map[string]int{"web": 2}, nil); code != http.StatusBadRequest {
+		t.Errorf("expected scaling a missing process type to fail, got %d", code)
+	}
+	if code := c.do("POST", "/v2/apps/test-1/scale/", map[string]int{"cmd": 4}, nil); code != http.StatusNoContent {
+		t.Fatalf("scaling: got %d", code)
+	}
+	pods, _ := kube.PodsLabelled("test-1", map[string]string{"type": "cmd"})
+	if len(pods) != 4 || pods[0].Spec.Containers[0].Image != "deis/example-go" || pods[0].Metadata.Labels["version"] != "v2" {
+		t.Fatalf("expected 4 pods of v2, got %+v", pods)
+	}
+
+	c.do("POST", "/v2/apps/test-1/config/", map[string]interface{}{"memory": map[string]string{"cmd": "64M"}, "tags": map[string]string{"environ": "prod"}}, nil)
+	pods, _ = kube.PodsLabelled("test-1", map[string]string{"type": "cmd"})
+	if len(pods) != 4 {
+		t.Fatalf("expected the new release to keep 4 pods, got %d", len(pods))
+	}
+	for _, pod := range pods {
+		if pod.Metadata.Labels["version"] != "v3" || pod.Spec.Containers[0].Resources.Limits["memory"] != "64M" || pod.Spec.NodeSelector["environ"] != "prod" {
+			t.Fatalf("expected v3 pods with the limit and tag, got %+v", pod)
+		}
+	}
+
+	var ps struct {
+		Count   int
+		Results []struct{ Type, State string }
+	}
+	c.do("GET", "/v2/apps/test-1/pods/", nil, &ps)
+	if ps.Count != 4 || ps.Results[0].Type != "cmd" || ps.Results[0].State != "up" {
+		t.Errorf("unexpected processes %+v", ps)
+	}
+
+	c.do("DELETE", "/v2/apps/test-1/", nil, nil)
+	if _, err := kube.Namespace("test-1"); !k8s.IsNotFound(err) {
+		t.Errorf("expected destroying the app to delete its namespace, got %v", err)
+	}
+}
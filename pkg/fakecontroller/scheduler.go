@@ -0,0 +1,198 @@
+package fakecontroller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deis/workflow/_tests/pkg/k8s"
+)
+
+// The fake schedules apps the way the controller does, when Server.Kube is set: each app gets a
+// namespace and a service named after it, each release a secret holding its config, and each
+// process type of the latest release a replication controller and its pods. Pods are created
+// running, since nothing runs them.
+
+// containerPort is the port app containers listen on, as the controller sets PORT.
+const containerPort = 5000
+
+// createNamespace makes the namespace and service of a new app. s.mu must be held.
+func (s *Server) createNamespace(id string) error {
+	if s.Kube == nil {
+		return nil
+	}
+	ns := k8s.Namespace{Metadata: k8s.ObjectMeta{Name: id, Labels: map[string]string{"heritage": "deis"}}}
+	ns.Status.Phase = "Active"
+	if err := s.Kube.Create("/api/v1/namespaces", ns); err != nil {
+		return err
+	}
+	svc := k8s.Service{Metadata: k8s.ObjectMeta{Name: id, Namespace: id, Labels: map[string]string{"app": id, "heritage": "deis"}}}
+	svc.Spec.Selector = map[string]string{"app": id, "type": "web"}
+	svc.Spec.Ports = []k8s.ServicePort{{Name: "http", Port: 80, TargetPort: containerPort}}
+	return s.Kube.Create(namespacePath(id, "services"), svc)
+}
+
+// deleteNamespace removes everything scheduled for an app. s.mu must be held.
+func (s *Server) deleteNamespace(id string) error {
+	if s.Kube == nil {
+		return nil
+	}
+	return s.Kube.Delete("/api/v1/namespaces/" + id)
+}
+
+// schedule brings the app's namespace in line with its latest release and structure: the
+// release's secret, a replication controller per process type, and as many pods of each as the
+// structure asks for. Apps which were never built have nothing to run. s.mu must be held.
+func (s *Server) schedule(a *app) error {
+	if s.Kube == nil || len(a.builds) == 0 {
+		return nil
+	}
+	rel := a.releases[len(a.releases)-1]
+	version := fmt.Sprintf("v%d", rel.Version)
+	b := a.builds[len(a.builds)-1]
+
+	secret := k8s.Secret{
+		Metadata: k8s.ObjectMeta{Name: fmt.Sprintf("%s-%s-env", a.ID, version), Namespace: a.ID, Labels: map[string]string{"app": a.ID, "version": version, "heritage": "deis"}},
+		Data:     make(map[string]string),
+	}
+	for key, value := range rel.values {
+		secret.Data[key] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(value)))
+	}
+	err := s.Kube.Replace(namespacePath(a.ID, "secrets")+"/"+secret.Metadata.Name, secret)
+	if k8s.IsNotFound(err) {
+		err = s.Kube.Create(namespacePath(a.ID, "secrets"), secret)
+	}
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]k8s.ReplicationController)
+	for procType, replicas := range a.Structure {
+		labels := map[string]string{"app": a.ID, "type": procType, "version": version, "heritage": "deis"}
+		rc := k8s.ReplicationController{Metadata: k8s.ObjectMeta{Name: fmt.Sprintf("%s-%s-%s", a.ID, version, procType), Namespace: a.ID, Labels: labels}}
+		rc.Spec.Replicas = replicas
+		rc.Spec.Selector = labels
+		rc.Spec.Template.Metadata = k8s.ObjectMeta{Labels: labels}
+		container := k8s.Container{Name: a.ID + "-" + procType, Image: b.Image}
+		container.Resources.Limits = make(map[string]string)
+		if limit, ok := a.memory[procType]; ok {
+			container.Resources.Limits["memory"] = fmt.Sprint(limit)
+		}
+		if limit, ok := a.cpu[procType]; ok {
+			container.Resources.Limits["cpu"] = fmt.Sprint(limit)
+		}
+		rc.Spec.Template.Spec.Containers = []k8s.Container{container}
+		if len(a.tags) > 0 {
+			rc.Spec.Template.Spec.NodeSelector = make(map[string]string)
+			for key, value := range a.tags {
+				rc.Spec.Template.Spec.NodeSelector[key] = fmt.Sprint(value)
+			}
+		}
+		wanted[rc.Metadata.Name] = rc
+	}
+
+	// replace the controllers and pods of other releases and process types
+	var rcs struct {
+		Items []k8s.ReplicationController `json:"items"`
+	}
+	if err := s.getList(namespacePath(a.ID, "replicationcontrollers"), &rcs); err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, rc := range rcs.Items {
+		existing[rc.Metadata.Name] = true
+		if _, ok := wanted[rc.Metadata.Name]; !ok {
+			if err := s.Kube.Delete(namespacePath(a.ID, "replicationcontrollers") + "/" + rc.Metadata.Name); err != nil {
+				return err
+			}
+		}
+	}
+	pods, err := s.Kube.PodsLabelled(a.ID, map[string]string{"app": a.ID})
+	if err != nil {
+		return err
+	}
+	running := make(map[string][]string)
+	for _, pod := range pods {
+		rcName := fmt.Sprintf("%s-%s-%s", a.ID, pod.Metadata.Labels["version"], pod.Metadata.Labels["type"])
+		if _, ok := wanted[rcName]; !ok {
+			if err := s.Kube.Delete(namespacePath(a.ID, "pods") + "/" + pod.Metadata.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		running[rcName] = append(running[rcName], pod.Metadata.Name)
+	}
+
+	names := make([]string, 0, len(wanted))
+	for name := range wanted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rc := wanted[name]
+		if existing[name] {
+			err = s.Kube.Replace(namespacePath(a.ID, "replicationcontrollers")+"/"+name, rc)
+		} else {
+			err = s.Kube.Create(namespacePath(a.ID, "replicationcontrollers"), rc)
+		}
+		if err != nil {
+			return err
+		}
+		sort.Strings(running[name])
+		for i, pod := range running[name] {
+			if i >= rc.Spec.Replicas {
+				if err := s.Kube.Delete(namespacePath(a.ID, "pods") + "/" + pod); err != nil {
+					return err
+				}
+			}
+		}
+		for i := len(running[name]); i < rc.Spec.Replicas; i++ {
+			pod := k8s.Pod{Metadata: k8s.ObjectMeta{Name: name + "-" + newUUID()[:5], Namespace: a.ID, Labels: rc.Spec.Template.Metadata.Labels}, Spec: rc.Spec.Template.Spec}
+			pod.Status.Phase = "Running"
+			if err := s.Kube.Create(namespacePath(a.ID, "pods"), pod); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// podsJSON lists the app's processes as the controller's pods endpoint does. s.mu must be held.
+func (s *Server) podsJSON(a *app) ([]interface{}, error) {
+	results := []interface{}{}
+	if s.Kube == nil {
+		return results, nil
+	}
+	pods, err := s.Kube.PodsLabelled(a.ID, map[string]string{"app": a.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		state := "up"
+		if pod.Status.Phase != "Running" {
+			state = strings.ToLower(pod.Status.Phase)
+		}
+		results = append(results, map[string]string{
+			"name":    pod.Metadata.Name,
+			"release": pod.Metadata.Labels["version"],
+			"type":    pod.Metadata.Labels["type"],
+			"state":   state,
+			"started": a.Updated,
+		})
+	}
+	return results, nil
+}
+
+func (s *Server) getList(path string, v interface{}) error {
+	data, err := s.Kube.Get(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func namespacePath(namespace, resource string) string {
+	return "/api/v1/namespaces/" + namespace + "/" + resource
+}
@@ -0,0 +1,246 @@
+// Package faultproxy is a reverse proxy for the controller's API which injects faults - latency,
+// error responses, reset connections, truncated bodies and failures after forwarding - into chosen
+// requests, so that specs can check how the CLI copes with a misbehaving controller.
+package faultproxy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/cluster"
+	"gopkg.in/yaml.v2"
+)
+
+// Faults a Rule can inject.
+const (
+	// Latency delays the request by the rule's Delay, then forwards it.
+	Latency = "latency"
+	// Status answers with the rule's Status instead of forwarding the request.
+	Status = "status"
+	// Reset closes the connection without answering.
+	Reset = "reset"
+	// Truncate forwards the request, but closes the connection halfway through the response's body.
+	Truncate = "truncate"
+	// Replace forwards the request, then answers with the rule's Status in place of the
+	// controller's response, like a controller failing after it acted.
+	Replace = "replace"
+)
+
+// Rule injects a fault into the requests it matches.
+type Rule struct {
+	Name string `yaml:"name"`
+	// Method is the HTTP method to match; empty matches every method.
+	Method string `yaml:"method"`
+	// Path is a regular expression matched against the request's path.
+	Path  string `yaml:"path"`
+	Fault string `yaml:"fault"`
+	// Status is the status code Status and Replace faults answer with.
+	Status int `yaml:"status"`
+	// Delay is how long Latency faults wait, such as "2s".
+	Delay string `yaml:"delay"`
+	// After is how many matching requests to let through before injecting the fault.
+	After int `yaml:"after"`
+	// Times is how many matching requests to inject the fault into; 0 means every one.
+	Times int `yaml:"times"`
+}
+
+// Scenario is a list of rules. The first rule matching a request, and due to inject its fault,
+// decides what happens to it; requests no rule is due for are forwarded untouched.
+type Scenario []Rule
+
+// LoadScenario reads the YAML scenario at path.
+func LoadScenario(path string) (Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	if _, err := compile(s); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+type rule struct {
+	Rule
+	path  *regexp.Regexp
+	delay time.Duration
+	// seen counts the requests the rule matched, and injected those it injected a fault into
+	seen, injected int
+}
+
+func compile(s Scenario) ([]*rule, error) {
+	rules := make([]*rule, len(s))
+	for i, r := range s {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		compiled := &rule{Rule: r}
+		var err error
+		if compiled.path, err = regexp.Compile(r.Path); err != nil {
+			return nil, fmt.Errorf("%s: %v", r.Name, err)
+		}
+		switch r.Fault {
+		case Latency:
+			if compiled.delay, err = time.ParseDuration(r.Delay); err != nil {
+				return nil, fmt.Errorf("%s: latency faults need a delay such as \"2s\": %v", r.Name, err)
+			}
+		case Status, Replace:
+			if r.Status < 100 || r.Status > 599 {
+				return nil, fmt.Errorf("%s: %s faults need a status code, not %d", r.Name, r.Fault, r.Status)
+			}
+		case Reset, Truncate:
+		default:
+			return nil, fmt.Errorf("%s: unknown fault %q; use %s, %s, %s, %s or %s", r.Name, r.Fault, Latency, Status, Reset, Truncate, Replace)
+		}
+		if r.After < 0 || r.Times < 0 {
+			return nil, fmt.Errorf("%s: after and times cannot be negative", r.Name)
+		}
+		rules[i] = compiled
+	}
+	return rules, nil
+}
+
+// Proxy forwards requests to the controller, injecting the faults of its scenario. It is an
+// http.Handler, to be served with httptest.NewServer or http.ListenAndServe.
+type Proxy struct {
+	// Logf, if set, is told about every fault injected.
+	Logf func(format string, args ...interface{})
+
+	forward *httputil.ReverseProxy
+	mu      sync.Mutex
+	rules   []*rule
+}
+
+// New returns a proxy to the controller at target, injecting faults as scenario says.
+func New(target *url.URL, scenario Scenario) (*Proxy, error) {
+	p := &Proxy{forward: cluster.ReverseProxy(target)}
+	if err := p.SetScenario(scenario); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetScenario replaces the proxy's scenario, resetting its counts.
+func (p *Proxy) SetScenario(scenario Scenario) error {
+	rules, err := compile(scenario)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.rules = rules
+	p.mu.Unlock()
+	return nil
+}
+
+// Injected returns how many times the named rule has injected its fault.
+func (p *Proxy) Injected(name string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range p.rules {
+		if r.Name == name {
+			return r.injected
+		}
+	}
+	return 0
+}
+
+// due returns a copy of the rule whose fault the request gets, or nil.
+func (p *Proxy) due(req *http.Request) *rule {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range p.rules {
+		if r.Method != "" && !strings.EqualFold(r.Method, req.Method) || !r.path.MatchString(req.URL.Path) {
+			continue
+		}
+		r.seen++
+		if r.seen <= r.After || r.Times > 0 && r.injected >= r.Times {
+			continue
+		}
+		r.injected++
+		injected := *r
+		return &injected
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r := p.due(req)
+	if r == nil {
+		p.forward.ServeHTTP(w, req)
+		return
+	}
+	if p.Logf != nil {
+		p.Logf("%s: injecting %s into %s %s", r.Name, r.Fault, req.Method, req.URL.Path)
+	}
+	switch r.Fault {
+	case Latency:
+		time.Sleep(r.delay)
+		p.forward.ServeHTTP(w, req)
+	case Status:
+		injectStatus(w, r)
+	case Replace:
+		p.forward.ServeHTTP(httptest.NewRecorder(), req)
+		injectStatus(w, r)
+	case Reset:
+		if conn := hijack(w); conn != nil {
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				// discard anything unsent and send a RST rather than a FIN
+				tcp.SetLinger(0)
+			}
+			conn.Close()
+		}
+	case Truncate:
+		recorder := httptest.NewRecorder()
+		p.forward.ServeHTTP(recorder, req)
+		body := recorder.Body.Bytes()
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "HTTP/1.1 %d %s\r\n", recorder.Code, http.StatusText(recorder.Code))
+		header := recorder.Header()
+		header.Del("Transfer-Encoding")
+		header.Set("Content-Length", fmt.Sprint(len(body)))
+		header.Write(&buf)
+		buf.WriteString("\r\n")
+		buf.Write(body[:len(body)/2])
+		if conn := hijack(w); conn != nil {
+			conn.Write(buf.Bytes())
+			conn.Close()
+		}
+	}
+}
+
+// injectStatus answers with r's status code, and a body saying which rule injected it.
+func injectStatus(w http.ResponseWriter, r *rule) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(r.Status)
+	fmt.Fprintf(w, "{\"detail\": %q}\n", fmt.Sprintf("fault injected by %s", r.Name))
+}
+
+// hijack takes over the connection behind w, or answers 500 and returns nil if it can't.
+func hijack(w http.ResponseWriter) net.Conn {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "the fault proxy cannot take over this connection", http.StatusInternalServerError)
+		return nil
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil
+	}
+	return conn
+}
@@ -0,0 +1,157 @@
+package faultproxy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startProxy(t *testing.T, scenario Scenario) (*Proxy, *httptest.Server, func()) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"count": 0, "results": []}`))
+	}))
+	target, _ := url.Parse(upstream.URL)
+	p, err := New(target, scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(p)
+	return p, server, func() {
+		server.Close()
+		upstream.Close()
+	}
+}
+
+func TestForward(t *testing.T) {
+	_, server, stop := startProxy(t, nil)
+	defer stop()
+	resp, err := http.Get(server.URL + "/v2/apps/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "results") {
+		t.Errorf("expected the upstream's answer, got %s %s", resp.Status, body)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	p, server, stop := startProxy(t, Scenario{
+		{Name: "second login fails", Method: "POST", Path: "^/v2/auth/login/$", Fault: Status, Status: 503, After: 1, Times: 1},
+		{Name: "apps reset", Path: "^/v2/apps/$", Fault: Reset},
+		{Name: "keys truncated", Path: "^/v2/keys/$", Fault: Truncate},
+		{Name: "slow users", Path: "^/v2/users/$", Fault: Latency, Delay: "100ms"},
+	})
+	defer stop()
+
+	var codes []int
+	for i := 0; i < 3; i++ {
+		resp, err := http.Post(server.URL+"/v2/auth/login/", "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		codes = append(codes, resp.StatusCode)
+	}
+	if codes[0] != 200 || codes[1] != 503 || codes[2] != 200 {
+		t.Errorf("expected only the second login to fail, got %v", codes)
+	}
+	if n := p.Injected("second login fails"); n != 1 {
+		t.Errorf("expected 1 injected fault, got %d", n)
+	}
+
+	if resp, err := http.Get(server.URL + "/v2/apps/"); err == nil {
+		resp.Body.Close()
+		t.Errorf("expected the connection to be reset, got %s", resp.Status)
+	}
+
+	resp, err := http.Get(server.URL + "/v2/keys/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(resp.Body); err == nil {
+		t.Error("expected reading a truncated body to fail")
+	}
+	resp.Body.Close()
+
+	start := time.Now()
+	if resp, err = http.Get(server.URL + "/v2/users/"); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || resp.StatusCode != 200 {
+		t.Errorf("expected a slow but successful answer, got %s after %v", resp.Status, elapsed)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	var forwarded int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+	p, err := New(target, Scenario{{Method: "POST", Path: "^/v2/apps/$", Fault: Replace, Status: 500}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/v2/apps/", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 500 || forwarded != 1 {
+		t.Errorf("expected the request to reach the upstream and be answered with a 500, got %s after %d requests", resp.Status, forwarded)
+	}
+	if _, err := New(target, Scenario{{Path: "^/$", Fault: Replace}}); err == nil {
+		t.Error("expected a replace fault without a status code to be rejected")
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faultproxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scenario.yaml")
+
+	ioutil.WriteFile(path, []byte(`
+- name: controller unavailable
+  method: GET
+  path: ^/v2/apps/
+  fault: status
+  status: 503
+  times: 2
+`), 0644)
+	s, err := LoadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 1 || s[0].Status != 503 || s[0].Times != 2 {
+		t.Errorf("unexpected scenario %+v", s)
+	}
+
+	for _, bad := range []string{
+		"- {path: ^/v2/, fault: explode}",
+		"- {path: ^/v2/, fault: latency}",
+		"- {path: ^/v2/, fault: status}",
+		"- {path: '(', fault: reset}",
+	} {
+		ioutil.WriteFile(path, []byte(bad), 0644)
+		if _, err := LoadScenario(path); err == nil {
+			t.Errorf("expected %s to be invalid", bad)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Package fuzz mutates the arguments of the deis CLI's commands, runs it with them, and flags the
+// runs which crash, hang or exit with a code the CLI never means to. Inputs which misbehaved are
+// kept in a corpus, to be replayed by later runs.
+package fuzz
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Placeholders in arguments, replaced by Expand.
+const (
+	App  = "{app}"
+	User = "{user}"
+	URL  = "{url}"
+	Key  = "{key}"
+)
+
+// Seeds are well-formed arguments for every command the fuzzer knows, which mutations start from.
+// Commands which open a browser are left out.
+var Seeds = [][]string{
+	{"apps:create", "fuzz-new", "--no-remote"},
+	{"apps:list"},
+	{"apps:info", "-a", App},
+	{"apps:logs", "-a", App},
+	{"apps:run", "ls", "-a", App},
+	{"apps:destroy", "--app=" + App, "--confirm=" + App},
+	{"auth:register", URL, "--username=fuzz-new", "--password=fuzz", "--email=fuzz@example.com"},
+	{"auth:login", URL, "--username=" + User, "--password=fuzz"},
+	{"auth:logout"},
+	{"auth:passwd", "--password=fuzz", "--new-password=fuzz2"},
+	{"auth:whoami"},
+	{"auth:cancel", "--username=" + User, "--password=fuzz", "--yes"},
+	{"auth:regenerate"},
+	{"builds:list", "-a", App},
+	{"builds:create", "deis/example-go", "-a", App},
+	{"config:list", "-a", App},
+	{"config:set", "FOO=bar", "-a", App},
+	{"config:unset", "FOO", "-a", App},
+	{"domains:list", "-a", App},
+	{"domains:add", "fuzz.example.com", "-a", App},
+	{"domains:remove", "fuzz.example.com", "-a", App},
+	{"git:remote", "-a", App},
+	{"healthchecks:list", "-a", App},
+	{"healthchecks:set", "liveness", "httpGet", "80", "-a", App},
+	{"keys:list"},
+	{"keys:add", Key},
+	{"keys:remove", "fuzz"},
+	{"limits:list", "-a", App},
+	{"limits:set", "cmd=64M", "-a", App},
+	{"limits:unset", "cmd", "--memory", "-a", App},
+	{"perms:list", "-a", App},
+	{"perms:create", User, "-a", App},
+	{"perms:delete", User, "-a", App},
+	{"ps:list", "-a", App},
+	{"ps:scale", "cmd=2", "-a", App},
+	{"ps:restart", "-a", App},
+	{"registry:list", "-a", App},
+	{"registry:set", "username=fuzz", "-a", App},
+	{"releases:list", "-a", App},
+	{"releases:info", "v1", "-a", App},
+	{"releases:rollback", "v1", "-a", App},
+	{"tags:list", "-a", App},
+	{"tags:set", "environ=fuzz", "-a", App},
+	{"tags:unset", "environ", "-a", App},
+	{"users:list"},
+	{"version"},
+	{"help", "apps"},
+}
+
+// interesting are the values mutations put into arguments.
+var interesting = []string{
+	"", " ", "-", "--", "-a", "--app", "--app=", "=", "==", "-h", "--help",
+	"FOO", "=bar", "FOO==bar", "web=", "web=-1", "web=abc", "web=99999999999999999999",
+	"-1", "0", "v0", "v-1", "v99999999999999999999",
+	"讲台", "😀😀😀", "שלום", "é", "​", "\x1b[31mred", "\t\n\r",
+	"%s%n%x%d", "$(echo hi)", "`echo hi`", "../../../../etc/passwd", "http://", "http://[::1",
+	strings.Repeat("a", 1000), strings.Repeat("a", 100000), strings.Repeat("讲", 10000),
+	App, User, URL, Key,
+}
+
+// Mutate returns a copy of args changed by one to three mutations chosen by r: dropping,
+// duplicating, swapping or truncating arguments, replacing or adding interesting values, adding an
+// unknown flag, or changing the command.
+func Mutate(r *rand.Rand, args []string) []string {
+	mutated := append([]string(nil), args...)
+	for n := 1 + r.Intn(3); n > 0; n-- {
+		mutated = mutateOnce(r, mutated)
+	}
+	return mutated
+}
+
+func mutateOnce(r *rand.Rand, args []string) []string {
+	if len(args) == 0 {
+		return []string{interesting[r.Intn(len(interesting))]}
+	}
+	i := r.Intn(len(args))
+	switch r.Intn(8) {
+	case 0:
+		return append(args[:i:i], args[i+1:]...)
+	case 1:
+		return insert(args, i, args[i])
+	case 2:
+		j := r.Intn(len(args))
+		args[i], args[j] = args[j], args[i]
+	case 3:
+		runes := []rune(args[i])
+		args[i] = string(runes[:r.Intn(len(runes)+1)])
+	case 4:
+		args[i] = interesting[r.Intn(len(interesting))]
+	case 5:
+		return insert(args, r.Intn(len(args)+1), interesting[r.Intn(len(interesting))])
+	case 6:
+		flags := []string{"--bogus", "-z", "--app", "-a=", "--limit=-1", "--limit=abc"}
+		return insert(args, 1+r.Intn(len(args)), flags[r.Intn(len(flags))])
+	case 7:
+		args[0] = Seeds[r.Intn(len(Seeds))][0]
+	}
+	return args
+}
+
+func insert(args []string, i int, value string) []string {
+	inserted := append(args[:i:i], value)
+	return append(inserted, args[i:]...)
+}
+
+// Expand returns args with the placeholders in them replaced by their values in vars.
+func Expand(args []string, vars map[string]string) []string {
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		for placeholder, value := range vars {
+			arg = strings.Replace(arg, placeholder, value, -1)
+		}
+		expanded[i] = arg
+	}
+	return expanded
+}
+
+// Runner runs the CLI.
+type Runner struct {
+	// Binary is the CLI, "deis" by default.
+	Binary string
+	// Env and Dir are the environment and working directory of the CLI.
+	Env []string
+	Dir string
+	// Timeout is how long the CLI may run before it is killed and counted as hung.
+	Timeout time.Duration
+}
+
+// Result is how a run of the CLI ended.
+type Result struct {
+	Args     []string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	TimedOut bool
+}
+
+// Run runs the CLI with args, with nothing on its standard input, killing it and everything it
+// started once Timeout passes.
+func (c *Runner) Run(args []string) Result {
+	binary := c.Binary
+	if binary == "" {
+		binary = "deis"
+	}
+	result := Result{Args: args, ExitCode: -1}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(binary, args...)
+	cmd.Env, cmd.Dir = c.Env, c.Dir
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	// the CLI gets a process group of its own, so that whatever it started, such as git or ssh,
+	// is killed with it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		result.Stderr = err.Error()
+		return result
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(c.Timeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		err = <-done
+		result.TimedOut = true
+	}
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	if err == nil {
+		result.ExitCode = 0
+	} else if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Exited() {
+			result.ExitCode = status.ExitStatus()
+		}
+	}
+	return result
+}
+
+// stackRegex matches the stack trace Go prints when a program panics.
+var stackRegex = regexp.MustCompile(`(?m)^(panic: |fatal error: |goroutine \d+ \[)`)
+
+// Problem returns what was wrong with how the CLI handled its input, or "" if nothing was. The CLI
+// means to exit 0, or 1 when it refuses its input or fails.
+func (r Result) Problem() string {
+	switch {
+	case r.TimedOut:
+		return "hung"
+	case stackRegex.MatchString(r.Stderr) || stackRegex.MatchString(r.Stdout):
+		return "crashed with a stack trace"
+	case r.ExitCode == -1:
+		return "was killed"
+	case r.ExitCode != 0 && r.ExitCode != 1:
+		return fmt.Sprintf("exited %d", r.ExitCode)
+	}
+	return ""
+}
+
+// Entry is an input which once made the CLI misbehave, kept in the corpus to be replayed.
+type Entry struct {
+	// Args may hold placeholders, expanded when the entry is replayed.
+	Args    []string  `json:"args"`
+	Problem string    `json:"problem"`
+	Found   time.Time `json:"found"`
+}
+
+// LoadCorpus reads the entries of the corpus in dir, in the order of their file names. A missing
+// directory is an empty corpus.
+func LoadCorpus(dir string) ([]Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var entries []Entry
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("reading %s: %v", path, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// SaveEntry adds e to the corpus in dir, and returns the path it was written to. The file is named
+// after e's arguments, so the same input is only kept once.
+func SaveEntry(dir string, e Entry) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(strings.Join(e.Args, "\x00")))
+	path := filepath.Join(dir, hex.EncodeToString(sum[:])[:12]+".json")
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
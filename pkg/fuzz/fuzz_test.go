@@ -0,0 +1,91 @@
+package fuzz
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMutate(t *testing.T) {
+	seed := []string{"config:set", "FOO=bar", "-a", App}
+	r := rand.New(rand.NewSource(1))
+	changed := 0
+	for i := 0; i < 100; i++ {
+		if !reflect.DeepEqual(Mutate(r, seed), seed) {
+			changed++
+		}
+	}
+	if !reflect.DeepEqual(seed, []string{"config:set", "FOO=bar", "-a", App}) {
+		t.Errorf("expected the seed to be left alone, got %q", seed)
+	}
+	if changed < 80 {
+		t.Errorf("expected most mutations to change the seed, got %d of 100", changed)
+	}
+	a, b := rand.New(rand.NewSource(2)), rand.New(rand.NewSource(2))
+	for i := 0; i < 20; i++ {
+		if x, y := Mutate(a, seed), Mutate(b, seed); !reflect.DeepEqual(x, y) {
+			t.Fatalf("expected the same seed to mutate alike, got %q and %q", x, y)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	got := Expand([]string{"--app=" + App, User, "v1"}, map[string]string{App: "web", User: "fuzz"})
+	if !reflect.DeepEqual(got, []string{"--app=web", "fuzz", "v1"}) {
+		t.Errorf(`expected ["--app=web" "fuzz" "v1"], got %q`, got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	sh := &Runner{Binary: "/bin/sh", Timeout: 200 * time.Millisecond}
+	for script, problem := range map[string]string{
+		"echo ok":                                 "",
+		"echo 'Error: bad input' >&2; exit 1":     "",
+		"echo 'panic: runtime error' >&2; exit 2": "crashed with a stack trace",
+		"echo 'goroutine 1 [running]:' >&2":       "crashed with a stack trace",
+		"exit 3":                                  "exited 3",
+		"kill -9 $$":                              "was killed",
+		"sleep 5":                                 "hung",
+	} {
+		if got := sh.Run([]string{"-c", script}).Problem(); got != problem {
+			t.Errorf("expected %q to have problem %q, got %q", script, problem, got)
+		}
+	}
+	if result := sh.Run([]string{"-c", "echo out; echo err >&2; exit 1"}); result.Stdout != "out\n" || result.Stderr != "err\n" || result.ExitCode != 1 {
+		t.Errorf("expected out, err and exit 1, got %+v", result)
+	}
+}
+
+func TestCorpus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "corpus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if entries, err := LoadCorpus(dir + "/missing"); err != nil || len(entries) != 0 {
+		t.Errorf("expected a missing corpus to be empty, got %v, %v", entries, err)
+	}
+	e := Entry{Args: []string{"ps:scale", "cmd=-1", "-a", App}, Problem: "exited 2", Found: time.Unix(0, 0).UTC()}
+	first, err := SaveEntry(dir, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := SaveEntry(dir, e); again != first {
+		t.Errorf("expected the same input to be kept once, got %s and %s", first, again)
+	}
+	SaveEntry(dir, Entry{Args: []string{"version", "--bogus"}, Problem: "hung"})
+	entries, err := LoadCorpus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %v", entries)
+	}
+	if entries[0].Problem == "exited 2" && !reflect.DeepEqual(entries[0], e) || entries[1].Problem == "exited 2" && !reflect.DeepEqual(entries[1], e) {
+		t.Errorf("expected %v to be read back, got %v", e, entries)
+	}
+}
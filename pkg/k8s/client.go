@@ -0,0 +1,238 @@
+// Package k8s is a minimal client for the parts of the Kubernetes API which the suite inspects to
+// see what the Deis controller scheduled. Each Deis app lives in a namespace named after it.
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+)
+
+// Client talks to a Kubernetes API server.
+type Client struct {
+	Server             string
+	Token              string
+	Username, Password string
+	HTTPClient         *http.Client
+}
+
+// NewClient returns a Client for the unauthenticated API server at server.
+func NewClient(server string) *Client {
+	return &Client{Server: server, HTTPClient: http.DefaultClient}
+}
+
+// StatusError is returned when the API server responds with anything but 2xx.
+type StatusError struct {
+	Method, Path string
+	Code         int
+	Body         string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.Code, strings.TrimSpace(e.Body))
+}
+
+// IsNotFound reports whether err is a 404 from the API server.
+func IsNotFound(err error) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.Code == http.StatusNotFound
+}
+
+// ObjectMeta is the metadata common to all objects.
+type ObjectMeta struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	// DeletionTimestamp is set once the object is being deleted
+	DeletionTimestamp string `json:"deletionTimestamp,omitempty"`
+}
+
+// Pod is a group of containers scheduled together.
+type Pod struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     PodSpec    `json:"spec"`
+	Status   struct {
+		Phase string `json:"phase"`
+	} `json:"status"`
+}
+
+// PodSpec describes the containers of a Pod and where it may run.
+type PodSpec struct {
+	Containers   []Container       `json:"containers"`
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+// Container is a single container of a Pod.
+type Container struct {
+	Name      string `json:"name"`
+	Image     string `json:"image,omitempty"`
+	Resources struct {
+		Limits map[string]string `json:"limits,omitempty"`
+	} `json:"resources"`
+}
+
+// Namespace is a namespace. The Deis controller makes one for each app.
+type Namespace struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Status   struct {
+		Phase string `json:"phase"`
+	} `json:"status"`
+}
+
+// ReplicationController keeps a number of pods like its template running.
+type ReplicationController struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Replicas int               `json:"replicas"`
+		Selector map[string]string `json:"selector"`
+		Template PodTemplate       `json:"template"`
+	} `json:"spec"`
+}
+
+// PodTemplate describes the pods a controller creates.
+type PodTemplate struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     PodSpec    `json:"spec"`
+}
+
+// Service routes traffic to the pods its selector matches.
+type Service struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Selector map[string]string `json:"selector"`
+		Ports    []ServicePort     `json:"ports"`
+	} `json:"spec"`
+}
+
+// ServicePort is a port a Service serves.
+type ServicePort struct {
+	Name       string `json:"name,omitempty"`
+	Port       int    `json:"port"`
+	TargetPort int    `json:"targetPort"`
+}
+
+// Secret holds sensitive data, such as an app's config, base64-encoded.
+type Secret struct {
+	Metadata ObjectMeta        `json:"metadata"`
+	Data     map[string]string `json:"data"`
+}
+
+// Get returns the body of a GET request for path, such as "/api/v1/namespaces".
+func (c *Client) Get(path string) ([]byte, error) {
+	return c.do("GET", path, nil)
+}
+
+// Create creates obj in the collection at path, such as "/api/v1/namespaces/app/pods".
+func (c *Client) Create(path string, obj interface{}) error {
+	return c.send("POST", path, obj)
+}
+
+// Replace replaces the object at path with obj.
+func (c *Client) Replace(path string, obj interface{}) error {
+	return c.send("PUT", path, obj)
+}
+
+// Delete deletes the object at path.
+func (c *Client) Delete(path string) error {
+	_, err := c.do("DELETE", path, nil)
+	return err
+}
+
+// Namespace returns the named namespace.
+func (c *Client) Namespace(name string) (*Namespace, error) {
+	ns := new(Namespace)
+	if err := c.getJSON("/api/v1/namespaces/"+name, ns); err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// Pods returns the pods in namespace.
+func (c *Client) Pods(namespace string) ([]Pod, error) {
+	var list struct {
+		Items []Pod `json:"items"`
+	}
+	if err := c.getJSON(fmt.Sprintf("/api/v1/namespaces/%s/pods", namespace), &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// PodsLabelled returns the pods in namespace with all of labels, such as the pods of an app's
+// web processes.
+func (c *Client) PodsLabelled(namespace string, labels map[string]string) ([]Pod, error) {
+	var selector []string
+	for key, value := range labels {
+		selector = append(selector, key+"="+value)
+	}
+	sort.Strings(selector)
+	var list struct {
+		Items []Pod `json:"items"`
+	}
+	path := fmt.Sprintf("/api/v1/namespaces/%s/pods?labelSelector=%s", namespace, neturl.QueryEscape(strings.Join(selector, ",")))
+	if err := c.getJSON(path, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// PodLog returns the log of container in pod.
+func (c *Client) PodLog(namespace, pod, container string) (string, error) {
+	data, err := c.Get(fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/log?container=%s",
+		namespace, pod, neturl.QueryEscape(container)))
+	return string(data), err
+}
+
+func (c *Client) getJSON(path string, v interface{}) error {
+	data, err := c.Get(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *Client) send(method, path string, obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(method, path, data)
+	return err
+}
+
+func (c *Client) do(method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.Server, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{Method: method, Path: path, Code: resp.StatusCode, Body: string(data)}
+	}
+	return data, nil
+}
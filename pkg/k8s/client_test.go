@@ -0,0 +1,137 @@
+package k8s
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deis/workflow/_tests/pkg/k8s/fake"
+)
+
+func TestClientAgainstFake(t *testing.T) {
+	cluster := fake.NewServer()
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	client := NewClient(server.URL)
+
+	pod := Pod{Metadata: ObjectMeta{Name: "app-v2-web-1", Labels: map[string]string{"type": "web"}}}
+	pod.Spec.Containers = []Container{{Name: "app-web", Image: "app:v2"}}
+	pod.Status.Phase = "Running"
+	if err := cluster.Put("app", "pods", pod.Metadata.Name, pod); err != nil {
+		t.Fatal(err)
+	}
+	cluster.SetLog("app", pod.Metadata.Name, "app-web", "hello\n")
+
+	pods, err := client.Pods("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 || pods[0].Metadata.Name != "app-v2-web-1" || pods[0].Status.Phase != "Running" ||
+		pods[0].Spec.Containers[0].Image != "app:v2" {
+		t.Errorf("unexpected pods %+v", pods)
+	}
+	if log, err := client.PodLog("app", pod.Metadata.Name, "app-web"); err != nil || log != "hello\n" {
+		t.Errorf("expected log %q, got %q (%v)", "hello\n", log, err)
+	}
+	if _, err := client.Pods("missing"); !IsNotFound(err) {
+		t.Errorf("expected not found for a missing namespace, got %v", err)
+	}
+}
+
+func TestNewClientFromKubeconfig(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	err = ioutil.WriteFile(path, []byte(`apiVersion: v1
+kind: Config
+current-context: e2e
+clusters:
+- name: other
+  cluster:
+    server: https://example.com
+- name: e2e
+  cluster:
+    server: `+server.URL+`
+users:
+- name: tester
+  user:
+    token: s3cret
+contexts:
+- name: e2e
+  context:
+    cluster: e2e
+    user: tester
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClientFromKubeconfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Server != server.URL {
+		t.Errorf("expected server %s, got %s", server.URL, client.Server)
+	}
+	if _, err := client.Pods("default"); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer s3cret" {
+		t.Errorf("expected the token to be sent, got %q", auth)
+	}
+}
+
+func TestWriteObjects(t *testing.T) {
+	server := httptest.NewServer(fake.NewServer())
+	defer server.Close()
+	client := NewClient(server.URL)
+
+	ns := Namespace{Metadata: ObjectMeta{Name: "app"}}
+	if err := client.Create("/api/v1/namespaces", ns); err != nil {
+		t.Fatal(err)
+	}
+	for i, procType := range []string{"web", "web", "worker"} {
+		pod := Pod{Metadata: ObjectMeta{Name: fmt.Sprintf("app-v2-%s-%d", procType, i), Labels: map[string]string{"app": "app", "type": procType}}}
+		if err := client.Create("/api/v1/namespaces/app/pods", pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	web, err := client.PodsLabelled("app", map[string]string{"app": "app", "type": "web"})
+	if err != nil || len(web) != 2 {
+		t.Errorf("expected the 2 web pods, got %+v, %v", web, err)
+	}
+
+	pod := web[0]
+	pod.Spec.NodeSelector = map[string]string{"environ": "prod"}
+	if err := client.Replace("/api/v1/namespaces/app/pods/"+pod.Metadata.Name, pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Delete("/api/v1/namespaces/app/pods/app-v2-worker-2"); err != nil {
+		t.Fatal(err)
+	}
+	pods, err := client.Pods("app")
+	if err != nil || len(pods) != 2 || pods[0].Spec.NodeSelector["environ"] != "prod" {
+		t.Errorf("expected the replaced pod and no worker, got %+v, %v", pods, err)
+	}
+
+	if err := client.Delete("/api/v1/namespaces/app"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Namespace("app"); !IsNotFound(err) {
+		t.Errorf("expected the namespace to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+// Package fake is an in-memory stand-in for the Kubernetes API server, so that code which inspects
+// a cluster can be exercised without one.
+//
+// It stores objects of any resource type as JSON, under paths of the form
+// /api/v1/namespaces/<namespace>/<resource>/<name> (or /apis/<group>/<version>/... for API groups),
+// and serves container logs from /api/v1/namespaces/<namespace>/pods/<name>/log. Lists may be
+// filtered with equality-based label selectors, such as ?labelSelector=app=foo,type=web.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var pathRegex = regexp.MustCompile(`^/(?:api/v1|apis/[^/]+/[^/]+)/namespaces(?:/([^/]+)(?:/([^/]+)(?:/([^/]+)(?:/(log))?)?)?)?/?$`)
+
+// Server is a fake Kubernetes API server. It is safe for concurrent use.
+type Server struct {
+	mu         sync.Mutex
+	namespaces map[string]*namespace
+}
+
+type namespace struct {
+	object json.RawMessage
+	// objects holds each resource's objects by name
+	objects map[string]map[string]json.RawMessage
+	// logs holds container logs, keyed by "<pod>/<container>"
+	logs map[string]string
+}
+
+type meta struct {
+	Metadata struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+}
+
+// NewServer returns a Server with no namespaces.
+func NewServer() *Server {
+	return &Server{namespaces: make(map[string]*namespace)}
+}
+
+// Put stores obj as the resource named name in ns, creating the namespace if needed.
+func (s *Server) Put(ns, resource, name string, obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.namespace(ns).put(resource, name, data)
+	return nil
+}
+
+// SetLog sets the log served for container in pod.
+func (s *Server) SetLog(ns, pod, container, log string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.namespace(ns).logs[pod+"/"+container] = log
+}
+
+// namespace returns the named namespace, creating it if needed. s.mu must be held.
+func (s *Server) namespace(name string) *namespace {
+	if ns, ok := s.namespaces[name]; ok {
+		return ns
+	}
+	ns := &namespace{
+		object:  json.RawMessage(fmt.Sprintf(`{"metadata":{"name":%q},"status":{"phase":"Active"}}`, name)),
+		objects: make(map[string]map[string]json.RawMessage),
+		logs:    make(map[string]string),
+	}
+	s.namespaces[name] = ns
+	return ns
+}
+
+func (ns *namespace) put(resource, name string, data json.RawMessage) {
+	if ns.objects[resource] == nil {
+		ns.objects[resource] = make(map[string]json.RawMessage)
+	}
+	ns.objects[resource][name] = data
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	match := pathRegex.FindStringSubmatch(r.URL.Path)
+	if match == nil {
+		writeStatus(w, http.StatusNotFound, "no such path %s", r.URL.Path)
+		return
+	}
+	nsName, resource, name, log := match[1], match[2], match[3], match[4]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if nsName == "" {
+		s.serveNamespaces(w, r)
+		return
+	}
+	ns, ok := s.namespaces[nsName]
+	if !ok {
+		writeStatus(w, http.StatusNotFound, "namespace %s not found", nsName)
+		return
+	}
+	switch {
+	case resource == "":
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, ns.object)
+		case "DELETE":
+			delete(s.namespaces, nsName)
+			writeJSON(w, http.StatusOK, ns.object)
+		default:
+			writeStatus(w, http.StatusMethodNotAllowed, "%s not allowed", r.Method)
+		}
+	case log != "":
+		text, ok := ns.logs[name+"/"+r.URL.Query().Get("container")]
+		if !ok {
+			writeStatus(w, http.StatusNotFound, "no log for container %q of pod %s", r.URL.Query().Get("container"), name)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(text))
+	case name == "":
+		s.serveCollection(w, r, ns, resource)
+	default:
+		s.serveObject(w, r, ns, resource, name)
+	}
+}
+
+func (s *Server) serveNamespaces(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		names := make([]string, 0, len(s.namespaces))
+		for name := range s.namespaces {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		items := make([]json.RawMessage, 0, len(names))
+		for _, name := range names {
+			items = append(items, s.namespaces[name].object)
+		}
+		writeList(w, items)
+	case "POST":
+		data, name, ok := readObject(w, r)
+		if !ok {
+			return
+		}
+		if _, exists := s.namespaces[name]; exists {
+			writeStatus(w, http.StatusConflict, "namespace %s already exists", name)
+			return
+		}
+		s.namespace(name).object = data
+		writeJSON(w, http.StatusCreated, data)
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, "%s not allowed", r.Method)
+	}
+}
+
+func (s *Server) serveCollection(w http.ResponseWriter, r *http.Request, ns *namespace, resource string) {
+	switch r.Method {
+	case "GET":
+		selector, ok := parseSelector(r.URL.Query().Get("labelSelector"))
+		if !ok {
+			writeStatus(w, http.StatusBadRequest, "unsupported label selector %q", r.URL.Query().Get("labelSelector"))
+			return
+		}
+		names := make([]string, 0, len(ns.objects[resource]))
+		for name := range ns.objects[resource] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		items := make([]json.RawMessage, 0, len(names))
+		for _, name := range names {
+			if obj := ns.objects[resource][name]; selects(selector, obj) {
+				items = append(items, obj)
+			}
+		}
+		writeList(w, items)
+	case "POST":
+		data, name, ok := readObject(w, r)
+		if !ok {
+			return
+		}
+		if _, exists := ns.objects[resource][name]; exists {
+			writeStatus(w, http.StatusConflict, "%s %s already exists", resource, name)
+			return
+		}
+		ns.put(resource, name, data)
+		writeJSON(w, http.StatusCreated, data)
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, "%s not allowed", r.Method)
+	}
+}
+
+func (s *Server) serveObject(w http.ResponseWriter, r *http.Request, ns *namespace, resource, name string) {
+	obj, exists := ns.objects[resource][name]
+	switch r.Method {
+	case "GET":
+		if !exists {
+			writeStatus(w, http.StatusNotFound, "%s %s not found", resource, name)
+			return
+		}
+		writeJSON(w, http.StatusOK, obj)
+	case "PUT":
+		data, _, ok := readObject(w, r)
+		if !ok {
+			return
+		}
+		if !exists {
+			writeStatus(w, http.StatusNotFound, "%s %s not found", resource, name)
+			return
+		}
+		ns.put(resource, name, data)
+		writeJSON(w, http.StatusOK, data)
+	case "DELETE":
+		if !exists {
+			writeStatus(w, http.StatusNotFound, "%s %s not found", resource, name)
+			return
+		}
+		delete(ns.objects[resource], name)
+		writeJSON(w, http.StatusOK, obj)
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, "%s not allowed", r.Method)
+	}
+}
+
+// parseSelector parses an equality-based label selector, the only kind the fake supports.
+func parseSelector(selector string) (map[string]string, bool) {
+	labels := make(map[string]string)
+	if selector == "" {
+		return labels, true
+	}
+	for _, term := range strings.Split(selector, ",") {
+		pair := strings.SplitN(strings.Replace(term, "==", "=", 1), "=", 2)
+		if len(pair) != 2 || strings.HasSuffix(pair[0], "!") {
+			return nil, false
+		}
+		labels[strings.TrimSpace(pair[0])] = strings.TrimSpace(pair[1])
+	}
+	return labels, true
+}
+
+// selects reports whether the object obj has every label in selector.
+func selects(selector map[string]string, obj json.RawMessage) bool {
+	if len(selector) == 0 {
+		return true
+	}
+	var m meta
+	if json.Unmarshal(obj, &m) != nil {
+		return false
+	}
+	for key, value := range selector {
+		if m.Metadata.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// readObject reads a JSON object with a name from the request body, responding with an error if
+// it is invalid.
+func readObject(w http.ResponseWriter, r *http.Request) (json.RawMessage, string, bool) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, "%v", err)
+		return nil, "", false
+	}
+	var m meta
+	if err := json.Unmarshal(data, &m); err != nil || m.Metadata.Name == "" {
+		writeStatus(w, http.StatusBadRequest, "object must have metadata.name")
+		return nil, "", false
+	}
+	return data, m.Metadata.Name, true
+}
+
+func writeList(w http.ResponseWriter, items []json.RawMessage) {
+	data, _ := json.Marshal(map[string]interface{}{"items": items})
+	writeJSON(w, http.StatusOK, data)
+}
+
+func writeJSON(w http.ResponseWriter, code int, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// writeStatus responds with a Kubernetes Status object describing an error.
+func writeStatus(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"kind":    "Status",
+		"status":  "Failure",
+		"message": fmt.Sprintf(format, args...),
+		"code":    code,
+	})
+	writeJSON(w, code, data)
+}
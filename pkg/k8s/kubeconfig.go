@@ -0,0 +1,159 @@
+package k8s
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// kubeconfig is the subset of a kubectl config file needed to talk to a cluster.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			Username              string `yaml:"username"`
+			Password              string `yaml:"password"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// NewClientFromKubeconfig returns a Client for the current context of the kubectl config file at
+// path.
+func NewClientFromKubeconfig(path string) (*Client, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg kubeconfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+
+	var clusterName, userName string
+	for _, context := range cfg.Contexts {
+		if context.Name == cfg.CurrentContext {
+			clusterName, userName = context.Context.Cluster, context.Context.User
+		}
+	}
+	if clusterName == "" {
+		return nil, fmt.Errorf("%s: current context %q not found", path, cfg.CurrentContext)
+	}
+
+	// kubectl resolves relative certificate and key paths against the config file's directory
+	dir := filepath.Dir(path)
+	client := &Client{}
+	tlsConfig := &tls.Config{}
+	found := false
+	for _, cluster := range cfg.Clusters {
+		if cluster.Name != clusterName {
+			continue
+		}
+		found = true
+		client.Server = cluster.Cluster.Server
+		tlsConfig.InsecureSkipVerify = cluster.Cluster.InsecureSkipTLSVerify
+		ca, err := fileOrData(dir, cluster.Cluster.CertificateAuthority, cluster.Cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, err
+		}
+		if ca != nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("%s: invalid certificate authority for cluster %q", path, clusterName)
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s: cluster %q not found", path, clusterName)
+	}
+
+	for _, user := range cfg.Users {
+		if user.Name != userName {
+			continue
+		}
+		client.Token = user.User.Token
+		client.Username, client.Password = user.User.Username, user.User.Password
+		cert, err := fileOrData(dir, user.User.ClientCertificate, user.User.ClientCertificateData)
+		if err != nil {
+			return nil, err
+		}
+		key, err := fileOrData(dir, user.User.ClientKey, user.User.ClientKeyData)
+		if err != nil {
+			return nil, err
+		}
+		if cert != nil && key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid client certificate for user %q: %v", path, userName, err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+	}
+
+	client.HTTPClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	return client, nil
+}
+
+// WriteKubeconfig writes a kubectl config file to path for the unauthenticated API server at
+// server, such as a fake one.
+func WriteKubeconfig(path, server string) error {
+	data := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: e2e
+clusters:
+- name: e2e
+  cluster:
+    server: %s
+users:
+- name: e2e
+  user: {}
+contexts:
+- name: e2e
+  context:
+    cluster: e2e
+    user: e2e
+`, server)
+	return ioutil.WriteFile(path, []byte(data), 0600)
+}
+
+// fileOrData returns the contents of file, relative to dir unless it is absolute, or else the
+// base64-decoded data, or nil if both are empty.
+func fileOrData(dir, file, data string) ([]byte, error) {
+	if file != "" {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		return ioutil.ReadFile(file)
+	}
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	return nil, nil
+}
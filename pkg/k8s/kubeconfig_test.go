@@ -0,0 +1,36 @@
+package k8s
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileOrData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "ca.crt"), []byte("from file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct{ file, data, expected string }{
+		{"ca.crt", "", "from file"},
+		{filepath.Join(dir, "ca.crt"), "", "from file"},
+		{"", "ZnJvbSBkYXRh", "from data"},
+		{"", "", ""},
+	} {
+		got, err := fileOrData(dir, c.file, c.data)
+		if err != nil {
+			t.Errorf("reading %q: %v", c.file, err)
+		} else if string(got) != c.expected {
+			t.Errorf("expected %q from %q, got %q", c.expected, c.file, got)
+		}
+	}
+	if _, err := fileOrData(os.TempDir(), "ca.crt", ""); err == nil {
+		t.Error("expected a relative path to be read from the given directory only")
+	}
+}
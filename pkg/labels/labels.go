@@ -0,0 +1,70 @@
+// Package labels reads the labels specs carry in their text, such as "[smoke]", which let runs
+// include or exclude tiers of specs. Ginkgo matches -focus and -skip against the text, so a label
+// on a Describe or Context applies to every spec inside it.
+package labels
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The labels in use.
+const (
+	// Smoke specs check the basics quickly, and are run after every platform change.
+	Smoke = "smoke"
+	// Deploy specs push or pull apps, so need a working builder and registry.
+	Deploy = "deploy"
+	// Slow specs take minutes each.
+	Slow = "slow"
+	// Admin specs need the admin user.
+	Admin = "admin"
+	// Destructive specs affect users besides the suite's own, such as by logging everyone out, so
+	// must not run on clusters other people use.
+	Destructive = "destructive"
+	// MultiUser specs act as more than one user.
+	MultiUser = "multi-user"
+	// Kube specs inspect what the controller scheduled through the Kubernetes API, so need
+	// KUBECONFIG. The local target serves a fake one.
+	Kube = "kube"
+)
+
+// Known holds every label in use.
+var Known = []string{Smoke, Deploy, Slow, Admin, Destructive, MultiUser, Kube}
+
+var labelRegex = regexp.MustCompile(`\s*\[([a-z0-9-]+)\]`)
+
+// IsKnown reports whether label is one of Known.
+func IsKnown(label string) bool {
+	for _, known := range Known {
+		if label == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse returns the labels in a spec's text, in order.
+func Parse(text string) []string {
+	var labels []string
+	for _, match := range labelRegex.FindAllStringSubmatch(text, -1) {
+		labels = append(labels, match[1])
+	}
+	return labels
+}
+
+// Strip returns a spec's text without its labels.
+func Strip(text string) string {
+	return strings.TrimSpace(labelRegex.ReplaceAllString(text, ""))
+}
+
+// Regexp returns a regular expression matching the text of specs with any of labels.
+func Regexp(labels []string) string {
+	quoted := make([]string, len(labels))
+	for i, label := range labels {
+		quoted[i] = regexp.QuoteMeta(label)
+	}
+	return `\[(` + strings.Join(quoted, "|") + `)\]`
+}
+
+// Optional is a regular expression matching any labels which may follow a word of a spec's text.
+const Optional = `(?: \[[a-z0-9-]+\])*`
@@ -0,0 +1,34 @@
+package labels
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParseAndStrip(t *testing.T) {
+	text := "Auth when logged in as an admin [admin] regenerates the token for all users [destructive]"
+	if actual := Parse(text); !reflect.DeepEqual(actual, []string{Admin, Destructive}) {
+		t.Errorf("unexpected labels %v", actual)
+	}
+	expected := "Auth when logged in as an admin regenerates the token for all users"
+	if actual := Strip(text); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if actual := Parse("Help prints help on \"-h\""); len(actual) != 0 {
+		t.Errorf("expected no labels, got %v", actual)
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	re := regexp.MustCompile(Regexp([]string{Smoke, MultiUser}))
+	for text, expected := range map[string]bool{
+		"Apps [smoke] can create an app":      true,
+		"Perms [multi-user] can share an app": true,
+		"Apps can create a smoke app":         false,
+	} {
+		if re.MatchString(text) != expected {
+			t.Errorf("expected matching %q to be %v", text, expected)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package load
+
+import (
+	"fmt"
+
+	"github.com/deis/workflow/_tests/pkg/api"
+)
+
+// APIFlow returns the flow of a new user through the controller's API at url: registering, logging
+// in, creating an app, setting its config, listing its releases, destroying it and cancelling the
+// account. Each step is named after its endpoint. Users and apps are named with prefix.
+func APIFlow(url, prefix, password string) Flow {
+	client := func(u *User) *api.Client {
+		return u.Values["client"].(*api.Client)
+	}
+	app := func(u *User) string {
+		return u.Values["app"].(string)
+	}
+	return Flow{
+		{Name: "POST /v2/auth/register/", Run: func(u *User) error {
+			c := api.New(url, "")
+			u.Values["client"] = c
+			username := u.Name(prefix)
+			u.Values["username"] = username
+			_, err := c.Register(username, password, username+"@example.com")
+			if err == nil {
+				u.Values["registered"] = true
+			}
+			return err
+		}},
+		{Name: "POST /v2/auth/login/", Run: func(u *User) error {
+			return client(u).Login(u.Values["username"].(string), password)
+		}},
+		{Name: "POST /v2/apps/", Run: func(u *User) error {
+			a, err := client(u).CreateApp(u.Name(prefix))
+			if err == nil {
+				u.Values["app"] = a.ID
+			}
+			return err
+		}},
+		{Name: "POST /v2/apps/{app}/config/", Run: func(u *User) error {
+			_, err := client(u).SetConfig(app(u), map[string]interface{}{"LOAD": fmt.Sprint(u.Iteration)})
+			return err
+		}},
+		{Name: "GET /v2/apps/{app}/releases/", Run: func(u *User) error {
+			releases, err := client(u).Releases(app(u))
+			if err == nil && len(releases) < 2 {
+				err = fmt.Errorf("%s has %d releases, expected at least 2", app(u), len(releases))
+			}
+			return err
+		}},
+		{Name: "DELETE /v2/apps/{app}/", Cleanup: true, Run: func(u *User) error {
+			if u.Values["app"] == nil {
+				return ErrSkipped
+			}
+			return client(u).DeleteApp(app(u))
+		}},
+		{Name: "DELETE /v2/auth/cancel/", Cleanup: true, Run: func(u *User) error {
+			if u.Values["registered"] == nil {
+				return ErrSkipped
+			}
+			// the flow may have failed before it could log in
+			if client(u).Token == "" {
+				if err := client(u).Login(u.Values["username"].(string), password); err != nil {
+					return err
+				}
+			}
+			return client(u).Cancel()
+		}},
+	}
+}
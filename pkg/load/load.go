@@ -0,0 +1,147 @@
+// Package load drives virtual users through scripted flows against the controller, starting flows
+// at a target rate for a set duration, and reports how long each step took and how often it failed.
+package load
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Drivers the suite can run its load flows through.
+const (
+	// DriverCLI runs each step as a deis CLI command.
+	DriverCLI = "cli"
+	// DriverAPI makes each step's request of the controller directly.
+	DriverAPI = "api"
+)
+
+// Step is one timed operation of a flow, such as a CLI command or an API request. Steps sharing a
+// Name are reported together.
+type Step struct {
+	Name string
+	// Cleanup steps run even after an earlier step of the flow failed, to undo what it did.
+	Cleanup bool
+	Run     func(u *User) error
+}
+
+// ErrSkipped is returned by a step with nothing to do, such as a cleanup step whose flow failed
+// before creating what it would clean up. Skipped steps are not reported.
+var ErrSkipped = errors.New("skipped")
+
+// Flow is a script of steps a virtual user runs in order.
+type Flow []Step
+
+// User is a virtual user. A user runs one flow at a time, so its fields need no locking.
+type User struct {
+	// ID numbers the users of a run from 0.
+	ID int
+	// Iteration counts the flows the user has started, from 0.
+	Iteration int
+	// Values carries whatever later steps of a flow need from earlier ones, such as an app's name.
+	Values map[string]interface{}
+}
+
+// Name returns a name for what the user creates in its current flow, unique within the run.
+func (u *User) Name(prefix string) string {
+	return fmt.Sprintf("%s%d-%d", prefix, u.ID, u.Iteration)
+}
+
+// Options describe a run.
+type Options struct {
+	// Users is how many virtual users run flows at once.
+	Users int
+	// Rate is how many flows start each second across all users. A flow waits for a free user, so
+	// too few users hold the rate down; 0 starts a flow whenever a user is free.
+	Rate float64
+	// Duration is how long flows keep starting. Flows already started are finished.
+	Duration time.Duration
+}
+
+// Validate reports whether o describes a run which can start.
+func (o Options) Validate() error {
+	switch {
+	case o.Users < 1:
+		return fmt.Errorf("a load run needs at least 1 user")
+	case o.Rate < 0:
+		return fmt.Errorf("the rate of a load run can't be negative")
+	case o.Duration <= 0:
+		return fmt.Errorf("a load run needs a duration")
+	}
+	return nil
+}
+
+// Run runs flow as o describes and returns the report of what happened.
+func Run(o Options, flow Flow) (*Report, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	report := newReport(o, flow)
+	starts := make(chan struct{})
+	go pace(starts, o.Rate, time.Now().Add(o.Duration))
+
+	var wg sync.WaitGroup
+	for id := 0; id < o.Users; id++ {
+		wg.Add(1)
+		go func(u *User) {
+			defer wg.Done()
+			for range starts {
+				report.flowDone(runFlow(u, flow, report))
+				u.Iteration++
+			}
+		}(&User{ID: id})
+	}
+	wg.Wait()
+	report.finish()
+	return report, nil
+}
+
+// pace sends on starts at rate per second, or as fast as it is received from if rate is 0, until
+// deadline, and then closes it.
+func pace(starts chan<- struct{}, rate float64, deadline time.Time) {
+	defer close(starts)
+	var tick <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	timeout := time.After(deadline.Sub(time.Now()))
+	for {
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-timeout:
+				return
+			}
+		}
+		select {
+		case starts <- struct{}{}:
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// runFlow runs the steps of flow as u, recording each in report, and reports whether they all
+// succeeded.
+func runFlow(u *User, flow Flow, report *Report) bool {
+	u.Values = make(map[string]interface{})
+	ok := true
+	for _, step := range flow {
+		if !ok && !step.Cleanup {
+			continue
+		}
+		started := time.Now()
+		err := step.Run(u)
+		if err == ErrSkipped {
+			continue
+		}
+		report.record(step.Name, time.Since(started), err)
+		if err != nil {
+			ok = false
+		}
+	}
+	return ok
+}
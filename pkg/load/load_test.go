@@ -0,0 +1,132 @@
+package load
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/api"
+	"github.com/deis/workflow/_tests/pkg/fakecontroller"
+)
+
+func TestRun(t *testing.T) {
+	var runs int32
+	flow := Flow{
+		{Name: "create", Run: func(u *User) error {
+			u.Values["created"] = true
+			if atomic.AddInt32(&runs, 1)%3 == 0 {
+				return errors.New("no room")
+			}
+			return nil
+		}},
+		{Name: "use", Run: func(u *User) error { return nil }},
+		{Name: "destroy", Cleanup: true, Run: func(u *User) error {
+			if u.Values["created"] == nil {
+				return ErrSkipped
+			}
+			return nil
+		}},
+	}
+	report, err := Run(Options{Users: 3, Duration: 50 * time.Millisecond}, flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	create, use, destroy := report.Steps[0], report.Steps[1], report.Steps[2]
+	if report.Flows == 0 || create.Count != report.Flows || destroy.Count != report.Flows {
+		t.Fatalf("expected every flow to create and destroy, got %d flows, %+v, %+v", report.Flows, create, destroy)
+	}
+	if create.Errors != report.FailedFlows || create.Errors != report.Flows/3 || create.FirstError != "no room" {
+		t.Errorf("expected every third flow to fail, got %d of %d flows, %+v", report.FailedFlows, report.Flows, create)
+	}
+	if use.Count != report.Flows-report.FailedFlows {
+		t.Errorf("expected failed flows to skip to their cleanup, got %d uses of %d flows", use.Count, report.Flows)
+	}
+	counted := 0
+	for _, n := range create.Histogram {
+		counted += n
+	}
+	if counted != create.Count || create.P50 > create.Max {
+		t.Errorf("expected the histogram to hold every sample, got %d of %d, %+v", counted, create.Count, create)
+	}
+}
+
+func TestRate(t *testing.T) {
+	report, err := Run(Options{Users: 5, Rate: 100, Duration: 200 * time.Millisecond}, Flow{
+		{Name: "wait", Run: func(*User) error { return nil }},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 20 flows, give or take the first and last ticks and a slow machine
+	if report.Flows < 10 || report.Flows > 21 {
+		t.Errorf("expected about 20 flows at 100/s for 200ms, got %d", report.Flows)
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	for _, o := range []Options{
+		{Duration: time.Second},
+		{Users: 1},
+		{Users: 1, Rate: -1, Duration: time.Second},
+	} {
+		if _, err := Run(o, nil); err == nil {
+			t.Errorf("expected %+v to be refused", o)
+		}
+	}
+}
+
+func TestAPIFlow(t *testing.T) {
+	server := httptest.NewServer(fakecontroller.NewServer())
+	defer server.Close()
+	flow := APIFlow(server.URL, "load-", "password")
+	report, err := Run(Options{Users: 4, Duration: 100 * time.Millisecond}, flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Flows == 0 || report.FailedFlows > 0 || report.MaxErrorRate() > 0 {
+		var summary bytes.Buffer
+		report.WriteSummary(&summary)
+		t.Fatalf("expected flows to succeed against the fake controller, got\n%s", summary.String())
+	}
+	if len(report.Steps) != len(flow) {
+		t.Fatalf("expected a row for each of %d steps, got %d", len(flow), len(report.Steps))
+	}
+	for _, stats := range report.Steps {
+		if stats.Count != report.Flows {
+			t.Errorf("expected %s to run in each of %d flows, got %d", stats.Name, report.Flows, stats.Count)
+		}
+	}
+
+	var summary bytes.Buffer
+	report.WriteSummary(&summary)
+	for _, expected := range []string{"STEP", "POST /v2/apps/", "GET /v2/apps/{app}/releases/", "0 failed"} {
+		if !strings.Contains(summary.String(), expected) {
+			t.Errorf("expected %q in the summary, got\n%s", expected, summary.String())
+		}
+	}
+}
+
+func TestAPIFlowCancelsAfterFailedLogin(t *testing.T) {
+	server := httptest.NewServer(fakecontroller.NewServer())
+	defer server.Close()
+	flow := APIFlow(server.URL, "load-", "password")
+	u := &User{Values: make(map[string]interface{})}
+	if err := flow[0].Run(u); err != nil {
+		t.Fatal(err)
+	}
+	// skip to the cleanup, as a flow does when logging in fails
+	for _, step := range flow {
+		if step.Cleanup {
+			if err := step.Run(u); err != nil && err != ErrSkipped {
+				t.Errorf("%s: %v", step.Name, err)
+			}
+		}
+	}
+	if err := api.New(server.URL, "").Login(u.Name("load-"), "password"); err == nil {
+		t.Error("expected the registered user to be cancelled")
+	}
+}
@@ -0,0 +1,186 @@
+package load
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Bounds are the upper bounds of the latency histogram's buckets. Slower samples fall in a last,
+// unbounded bucket.
+var Bounds = []time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// Report is what happened during a run.
+type Report struct {
+	Started  time.Time `json:"started"`
+	Users    int       `json:"users"`
+	Rate     float64   `json:"rate"`
+	Duration float64   `json:"duration"`
+	// Elapsed is how long the run took in seconds, including finishing the last flows.
+	Elapsed     float64 `json:"elapsed"`
+	Flows       int     `json:"flows"`
+	FailedFlows int     `json:"failedFlows"`
+	// Steps are in the order of the flow.
+	Steps []*Stats `json:"steps"`
+
+	mu     sync.Mutex
+	byName map[string]*Stats
+}
+
+// Stats are the timings and failures of one step.
+type Stats struct {
+	Name   string `json:"name"`
+	Count  int    `json:"count"`
+	Errors int    `json:"errors"`
+	// FirstError is the message of the step's first failure.
+	FirstError string `json:"firstError,omitempty"`
+	// Latencies are in milliseconds.
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+	// Histogram counts the samples in each of Bounds, and then those slower than all of them.
+	Histogram []int `json:"histogram"`
+
+	samples []time.Duration
+}
+
+// ErrorRate returns the share of the step's runs which failed.
+func (s *Stats) ErrorRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Count)
+}
+
+func newReport(o Options, flow Flow) *Report {
+	r := &Report{
+		Started:  time.Now(),
+		Users:    o.Users,
+		Rate:     o.Rate,
+		Duration: o.Duration.Seconds(),
+		byName:   make(map[string]*Stats),
+	}
+	for _, step := range flow {
+		if r.byName[step.Name] == nil {
+			stats := &Stats{Name: step.Name, Histogram: make([]int, len(Bounds)+1)}
+			r.byName[step.Name] = stats
+			r.Steps = append(r.Steps, stats)
+		}
+	}
+	return r
+}
+
+func (r *Report) record(step string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.byName[step]
+	stats.Count++
+	stats.samples = append(stats.samples, latency)
+	if err != nil {
+		stats.Errors++
+		if stats.FirstError == "" {
+			stats.FirstError = err.Error()
+		}
+	}
+	bucket := sort.Search(len(Bounds), func(i int) bool { return latency <= Bounds[i] })
+	stats.Histogram[bucket]++
+}
+
+func (r *Report) flowDone(ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Flows++
+	if !ok {
+		r.FailedFlows++
+	}
+}
+
+func (r *Report) finish() {
+	r.Elapsed = time.Since(r.Started).Seconds()
+	for _, stats := range r.Steps {
+		samples := stats.samples
+		sort.Sort(durations(samples))
+		stats.P50 = percentile(samples, 0.5)
+		stats.P90 = percentile(samples, 0.9)
+		stats.P99 = percentile(samples, 0.99)
+		stats.Max = percentile(samples, 1)
+	}
+}
+
+// percentile returns the p-th percentile of sorted samples in milliseconds, or 0 if there are none.
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return float64(sorted[i]) / float64(time.Millisecond)
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// MaxErrorRate returns the highest error rate of any step.
+func (r *Report) MaxErrorRate() float64 {
+	max := 0.0
+	for _, stats := range r.Steps {
+		if rate := stats.ErrorRate(); rate > max {
+			max = rate
+		}
+	}
+	return max
+}
+
+// WriteSummary writes a table of every step's latencies and error rate to w, followed by the
+// first error of each step which failed.
+func (r *Report) WriteSummary(w io.Writer) {
+	fmt.Fprintf(w, "%d flows by %d users in %.1fs, %.2f flows/s, %d failed\n\n",
+		r.Flows, r.Users, r.Elapsed, float64(r.Flows)/r.Elapsed, r.FailedFlows)
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "STEP\tCOUNT\tERRORS\tP50\tP90\tP99\tMAX")
+	for _, s := range r.Steps {
+		fmt.Fprintf(table, "%s\t%d\t%d (%.1f%%)\t%.0fms\t%.0fms\t%.0fms\t%.0fms\n",
+			s.Name, s.Count, s.Errors, 100*s.ErrorRate(), s.P50, s.P90, s.P99, s.Max)
+	}
+	table.Flush()
+	for _, s := range r.Steps {
+		if s.FirstError != "" {
+			fmt.Fprintf(w, "\n%s first failed with: %s\n", s.Name, s.FirstError)
+		}
+	}
+}
+
+// Save writes r to path as JSON.
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,154 @@
+// Package parse turns the human-readable output of the deis CLI into data that specs and tools can
+// compare.
+package parse
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headerRegex  = regexp.MustCompile(`^=== `)
+	sectionRegex = regexp.MustCompile(`^--- (.+)$`)
+	pairRegex    = regexp.MustCompile(`^(\S+)\s+(.*)$`)
+	fieldRegex   = regexp.MustCompile(`^(\w+):\s+(.*)$`)
+	releaseRegex = regexp.MustCompile(`^v(\d+)\s+(.*)$`)
+)
+
+// Release is one line of "deis releases:list" output.
+type Release struct {
+	Version int
+	// Rest is everything printed after the version: the creation time and the summary.
+	Rest string
+}
+
+// ReleaseInfo parses the output of "deis releases:info" into its fields, keyed by field name
+// ("config", "owner", "summary", "updated", "uuid", ...).
+func ReleaseInfo(output string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range lines(output) {
+		if match := fieldRegex.FindStringSubmatch(line); match != nil {
+			fields[match[1]] = strings.TrimSpace(match[2])
+		}
+	}
+	return fields
+}
+
+// ReleasesList parses the output of "deis releases:list", newest release first.
+func ReleasesList(output string) ([]Release, error) {
+	var releases []Release
+	for _, line := range lines(output) {
+		match := releaseRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, Release{Version: version, Rest: strings.TrimSpace(match[2])})
+	}
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("no releases found in output:\n%s", output)
+	}
+	return releases, nil
+}
+
+// ConfigList parses the output of "deis config:list" into the app's environment.
+//
+// The CLI prints values as-is, so a value containing a newline can't be told apart from the
+// following lines; only the first line of such a value is returned.
+func ConfigList(output string) map[string]string {
+	return pairs(output)
+}
+
+// TagsList parses the output of "deis tags:list" into the app's node selector tags.
+func TagsList(output string) map[string]string {
+	return pairs(output)
+}
+
+// LimitsList parses the output of "deis limits:list". Keys are prefixed with the lowercased
+// section they were listed under, such as "memory.web" or "cpu.worker". Unlimited sections
+// contribute no keys.
+func LimitsList(output string) map[string]string {
+	limits := make(map[string]string)
+	section := ""
+	for _, line := range lines(output) {
+		if headerRegex.MatchString(line) {
+			continue
+		}
+		if match := sectionRegex.FindStringSubmatch(line); match != nil {
+			section = strings.ToLower(strings.TrimSpace(match[1]))
+			continue
+		}
+		if line == "Unlimited" {
+			continue
+		}
+		if match := pairRegex.FindStringSubmatch(line); match != nil {
+			limits[section+"."+match[1]] = strings.TrimSpace(match[2])
+		}
+	}
+	return limits
+}
+
+// AppsList parses the output of "deis apps:list" into app names.
+func AppsList(output string) []string {
+	return names(output)
+}
+
+// DomainsList parses the output of "deis domains:list" into the app's domains.
+func DomainsList(output string) []string {
+	return names(output)
+}
+
+// PermsList parses the output of "deis perms:list" into the usernames listed.
+func PermsList(output string) []string {
+	return names(output)
+}
+
+// KeysList parses the output of "deis keys:list" into public keys, keyed by ID. The CLI shortens
+// long keys, so the keys are only good for comparing with other output of keys:list.
+func KeysList(output string) map[string]string {
+	return pairs(output)
+}
+
+// names parses one name per line, skipping the "=== Title" header.
+func names(output string) []string {
+	var result []string
+	for _, line := range lines(output) {
+		if !headerRegex.MatchString(line) {
+			result = append(result, strings.TrimSpace(line))
+		}
+	}
+	return result
+}
+
+// pairs parses "KEY   value" lines, skipping the "=== app Title" header.
+func pairs(output string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range lines(output) {
+		if headerRegex.MatchString(line) {
+			continue
+		}
+		if match := pairRegex.FindStringSubmatch(line); match != nil {
+			values[match[1]] = strings.TrimSpace(match[2])
+		}
+	}
+	return values
+}
+
+// lines splits output into lines, dropping carriage returns and blank lines.
+func lines(output string) []string {
+	var result []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
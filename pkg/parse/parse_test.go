@@ -0,0 +1,117 @@
+package parse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReleaseInfo(t *testing.T) {
+	output := `=== test-123 Release v2
+build:   b9e6a3a2-02bc-4d1a-9e58-1e1c5e8e1c2d
+config:  0ad4ef2b-7d62-4e1c-bd47-6e2c3e0e0b1a
+owner:   test-42
+created: 2015-12-22T21:20:31UTC
+summary: test-42 added FOO
+updated: 2015-12-22T21:20:31UTC
+uuid:    5f0e1b0c-4e7c-4c1a-8a8b-4f3e5a3c2d1e
+`
+	expected := map[string]string{
+		"build":   "b9e6a3a2-02bc-4d1a-9e58-1e1c5e8e1c2d",
+		"config":  "0ad4ef2b-7d62-4e1c-bd47-6e2c3e0e0b1a",
+		"owner":   "test-42",
+		"created": "2015-12-22T21:20:31UTC",
+		"summary": "test-42 added FOO",
+		"updated": "2015-12-22T21:20:31UTC",
+		"uuid":    "5f0e1b0c-4e7c-4c1a-8a8b-4f3e5a3c2d1e",
+	}
+	if actual := ReleaseInfo(output); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestReleasesList(t *testing.T) {
+	output := `=== test-123 Releases
+v3      2015-12-22T21:22:01UTC    test-42 added FOO
+v2      2015-12-22T21:21:11UTC    test-42 deployed 1f2e3d4
+v1      2015-12-22T21:20:31UTC    test-42 created initial release
+`
+	list, err := ReleasesList(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expected 3 releases, got %d", len(list))
+	}
+	if list[0].Version != 3 || list[0].Rest != "2015-12-22T21:22:01UTC    test-42 added FOO" {
+		t.Errorf("unexpected newest release %+v", list[0])
+	}
+	if list[2].Version != 1 {
+		t.Errorf("expected the oldest release to be v1, got v%d", list[2].Version)
+	}
+
+	if _, err := ReleasesList("=== test-123 Releases\n"); err == nil {
+		t.Error("expected an error for output without releases")
+	}
+}
+
+func TestConfigList(t *testing.T) {
+	output := "=== test-123 Config\r\nFOO             bar\r\nPOWERED_BY      the Deis team\r\nUNICODE         讲台\r\n"
+	expected := map[string]string{
+		"FOO":        "bar",
+		"POWERED_BY": "the Deis team",
+		"UNICODE":    "讲台",
+	}
+	if actual := ConfigList(output); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestLimitsList(t *testing.T) {
+	output := `=== test-123 Limits
+
+--- Memory
+web       128M
+worker    64M
+
+--- CPU
+Unlimited
+`
+	expected := map[string]string{
+		"memory.web":    "128M",
+		"memory.worker": "64M",
+	}
+	if actual := LimitsList(output); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestAppsList(t *testing.T) {
+	output := "=== Apps\ntest-123\ntest-456\n"
+	expected := []string{"test-123", "test-456"}
+	if actual := AppsList(output); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if actual := AppsList("=== Apps\n"); len(actual) != 0 {
+		t.Errorf("expected no apps, got %v", actual)
+	}
+}
+
+func TestDomainsAndPermsList(t *testing.T) {
+	output := "=== test-123 Domains\ntest-123\ntest-123.example.com\n"
+	expected := []string{"test-123", "test-123.example.com"}
+	if actual := DomainsList(output); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	output = "=== test-123's Users\ntest-7-collab\n"
+	if actual := PermsList(output); !reflect.DeepEqual(actual, []string{"test-7-collab"}) {
+		t.Errorf("expected test-7-collab, got %v", actual)
+	}
+}
+
+func TestKeysList(t *testing.T) {
+	output := "=== test-7 Keys\ntest-key-7 ssh-rsa AAAAB3Nza...XrwQ== test-key-7\n"
+	expected := map[string]string{"test-key-7": "ssh-rsa AAAAB3Nza...XrwQ== test-key-7"}
+	if actual := KeysList(output); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
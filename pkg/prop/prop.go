@@ -0,0 +1,168 @@
+// Package prop checks properties of the platform against generated inputs: strings drawn from
+// Unicode, whitespace, shell metacharacters and around length boundaries. A failing input is
+// shrunk to a minimal one which still fails, so that it can be reproduced by hand.
+package prop
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing/quick"
+)
+
+// Runes of the classes generated strings mix into their valid runes.
+const (
+	Lower      = "abcdefghijklmnopqrstuvwxyz"
+	Upper      = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits     = "0123456789"
+	Whitespace = " \t\n\r\v\f\u00a0\u2003\u3000"
+	// ShellMeta are the runes /bin/sh gives a meaning to, plus the CLI's "=" and "-".
+	ShellMeta = "$`\\\"';&|<>()*?[]{}!#~%^=-+ "
+	// Unicode holds accented Latin, CJK, right-to-left, combining, zero-width and astral runes.
+	Unicode = "éñüß讲台日本שלום\u0301\u200b\u200d😀𝔘"
+)
+
+// adversarial are the classes mixed into generated strings.
+var adversarial = []string{Lower, Upper, Digits, Whitespace, ShellMeta, Unicode}
+
+// Gen makes a random string from r.
+type Gen func(r *rand.Rand) string
+
+// Strings describes a generator of strings which are mostly, but not all, valid.
+type Strings struct {
+	// Valid holds the runes valid anywhere, and First those valid first, if they differ.
+	Valid, First string
+	// Max is the longest string to generate, besides Boundaries.
+	Max int
+	// Boundaries are lengths worth trying often, such as a limit and one past it.
+	Boundaries []int
+}
+
+// Gen returns a generator of strings as s describes. A quarter of them hold valid runes only; the
+// others have runes from the other classes mixed in.
+func (s Strings) Gen() Gen {
+	return func(r *rand.Rand) string {
+		length := r.Intn(s.Max + 1)
+		if len(s.Boundaries) > 0 && r.Intn(3) == 0 {
+			length = s.Boundaries[r.Intn(len(s.Boundaries))]
+		}
+		clean := r.Intn(4) == 0
+		runes := make([]rune, length)
+		for i := range runes {
+			alphabet := s.Valid
+			if i == 0 && s.First != "" {
+				alphabet = s.First
+			}
+			if !clean && r.Intn(5) == 0 {
+				alphabet = adversarial[r.Intn(len(adversarial))]
+			}
+			runes[i] = pick(r, alphabet)
+		}
+		return string(runes)
+	}
+}
+
+func pick(r *rand.Rand, alphabet string) rune {
+	runes := []rune(alphabet)
+	return runes[r.Intn(len(runes))]
+}
+
+// Failure is an input for which a property failed.
+type Failure struct {
+	// Seed reproduces the inputs of the check which found the failure.
+	Seed int64
+	// Input failed first, and Shrunk is the smallest variant of it found to fail too.
+	Input, Shrunk []string
+	// Err is why Shrunk failed.
+	Err error
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("failed for %s (shrunk from %s, seed %d): %v", quote(f.Shrunk), quote(f.Input), f.Seed, f.Err)
+}
+
+func quote(input []string) string {
+	quoted := make([]string, len(input))
+	for i, s := range input {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return "[" + strings.Join(quoted, " ") + "]"
+}
+
+// MaxShrinks is how many variants of a failing input Check tries before settling for the
+// smallest found so far.
+var MaxShrinks = 100
+
+// Check tries property on count inputs, each holding one string from each of gens, drawn from a
+// source seeded with seed. It returns the first failure, shrunk, or nil if every input passed.
+func Check(seed int64, count int, gens []Gen, property func(input []string) error) *Failure {
+	cfg := &quick.Config{
+		MaxCount: count,
+		Rand:     rand.New(rand.NewSource(seed)),
+		Values: func(values []reflect.Value, r *rand.Rand) {
+			input := make([]string, len(gens))
+			for i, gen := range gens {
+				input[i] = gen(r)
+			}
+			values[0] = reflect.ValueOf(input)
+		},
+	}
+	err := quick.Check(func(input []string) bool { return property(input) == nil }, cfg)
+	checkErr, ok := err.(*quick.CheckError)
+	if !ok {
+		return nil
+	}
+	f := &Failure{Seed: seed, Input: checkErr.In[0].([]string)}
+	f.Shrunk = Shrink(f.Input, func(input []string) bool { return property(input) != nil }, MaxShrinks)
+	f.Err = property(f.Shrunk)
+	return f
+}
+
+// Shrink returns the smallest variant of input it finds for which fails is true, trying at most
+// attempts variants. Each string of input is shrunk in turn, by emptying it, halving it, dropping
+// one rune, or replacing one rune with a plain letter.
+func Shrink(input []string, fails func([]string) bool, attempts int) []string {
+	input = append([]string(nil), input...)
+	for shrunk := true; shrunk; {
+		shrunk = false
+	candidates:
+		for i := range input {
+			for _, candidate := range smaller(input[i]) {
+				if attempts == 0 {
+					return input
+				}
+				attempts--
+				variant := append([]string(nil), input...)
+				variant[i] = candidate
+				if fails(variant) {
+					input, shrunk = variant, true
+					break candidates
+				}
+			}
+		}
+	}
+	return input
+}
+
+// smaller returns the variants of s Shrink tries, smallest first.
+func smaller(s string) []string {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return nil
+	}
+	variants := []string{""}
+	if len(runes) > 1 {
+		half := len(runes) / 2
+		variants = append(variants, string(runes[:half]), string(runes[half:]))
+	}
+	for i := range runes {
+		variants = append(variants, string(runes[:i])+string(runes[i+1:]))
+	}
+	for i, r := range runes {
+		if r != 'a' {
+			variants = append(variants, string(runes[:i])+"a"+string(runes[i+1:]))
+		}
+	}
+	return variants
+}
@@ -0,0 +1,72 @@
+package prop
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestGen(t *testing.T) {
+	gen := Strings{Valid: Lower, First: "x", Max: 5, Boundaries: []int{63}}.Gen()
+	r := rand.New(rand.NewSource(1))
+	var boundary, clean, mixed bool
+	for i := 0; i < 300; i++ {
+		s := gen(r)
+		if n := utf8.RuneCountInString(s); n == 63 {
+			boundary = true
+		} else if n > 5 {
+			t.Fatalf("expected at most 5 runes off the boundaries, got %q", s)
+		}
+		if s == "" {
+			continue
+		}
+		if strings.Trim(s, Lower) == "" && s[0] == 'x' {
+			clean = true
+		} else {
+			mixed = true
+		}
+	}
+	if !boundary || !clean || !mixed {
+		t.Errorf("expected boundary lengths, clean and mixed strings, got %v, %v, %v", boundary, clean, mixed)
+	}
+}
+
+func TestShrink(t *testing.T) {
+	fails := func(input []string) bool {
+		return strings.Contains(input[0], "$") && input[1] != ""
+	}
+	shrunk := Shrink([]string{"FOO$(rm -rf)", "讲台 bar"}, fails, 1000)
+	if !reflect.DeepEqual(shrunk, []string{"$", "a"}) {
+		t.Errorf(`expected ["$" "a"], got %q`, shrunk)
+	}
+	if shrunk := Shrink([]string{"FOO$(rm -rf)", "bar"}, fails, 2); len(shrunk[0]) < len("FOO$(r") {
+		t.Errorf("expected shrinking to stop after 2 attempts, got %q", shrunk)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	gens := []Gen{Strings{Valid: Lower, Max: 20}.Gen()}
+	noUpper := func(input []string) error {
+		if strings.IndexFunc(input[0], unicode.IsUpper) >= 0 {
+			return errors.New("uppercase")
+		}
+		return nil
+	}
+	f := Check(7, 200, gens, noUpper)
+	if f == nil {
+		t.Fatal("expected an uppercase string among 200")
+	}
+	if len([]rune(f.Shrunk[0])) != 1 || !unicode.IsUpper([]rune(f.Shrunk[0])[0]) || f.Err == nil {
+		t.Errorf("expected a single uppercase letter, got %q, %v", f.Shrunk, f.Err)
+	}
+	if again := Check(7, 200, gens, noUpper); !reflect.DeepEqual(again.Input, f.Input) {
+		t.Errorf("expected the same seed to find %q again, got %q", f.Input, again.Input)
+	}
+	if f := Check(7, 200, []Gen{Strings{Valid: Lower, Max: 20}.Gen()}, func([]string) error { return nil }); f != nil {
+		t.Errorf("expected no failure, got %v", f)
+	}
+}
@@ -0,0 +1,76 @@
+// Package quarantine reads the list of specs which are known to be flaky or broken, so that they
+// can be kept out of normal runs and run on their own without failing the build.
+package quarantine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/deis/workflow/_tests/pkg/labels"
+	"gopkg.in/yaml.v2"
+)
+
+// Entry is a quarantined spec.
+type Entry struct {
+	// Spec is the spec's full text: the text of its containers and its own, separated by spaces,
+	// without labels.
+	Spec   string `yaml:"spec"`
+	Reason string `yaml:"reason"`
+}
+
+// List is the set of quarantined specs.
+type List []Entry
+
+// Load reads the list at path. A missing file is an empty list.
+func Load(path string) (List, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list List
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	for i, entry := range list {
+		if strings.TrimSpace(entry.Spec) == "" {
+			return nil, fmt.Errorf("%s: entry %d has no spec", path, i+1)
+		}
+		if strings.TrimSpace(entry.Reason) == "" {
+			return nil, fmt.Errorf("%s: %q needs a reason for being quarantined", path, entry.Spec)
+		}
+	}
+	return list, nil
+}
+
+// Lookup returns the entry for the spec with the given full text. Labels in the text are ignored.
+func (l List) Lookup(spec string) (Entry, bool) {
+	spec = labels.Strip(spec)
+	for _, entry := range l {
+		if entry.Spec == spec {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Regexp returns a regular expression, suitable for ginkgo's -focus and -skip flags, matching
+// exactly the quarantined specs, whatever labels they carry.
+func (l List) Regexp() string {
+	alternatives := make([]string, len(l))
+	for i, entry := range l {
+		words := strings.Fields(entry.Spec)
+		for j, word := range words {
+			words[j] = regexp.QuoteMeta(word)
+		}
+		alternatives[i] = strings.Join(words, labels.Optional+" ") + labels.Optional
+	}
+	// ginkgo matches against the suite description followed by the spec's full text, which starts
+	// with the "[Top Level]" container
+	return `\[Top Level\] (` + strings.Join(alternatives, "|") + `)$`
+}
@@ -0,0 +1,130 @@
+package quarantine
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/onsi/ginkgo/types"
+)
+
+func writeList(t *testing.T, dir, contents string) string {
+	path := filepath.Join(dir, "quarantine.yaml")
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list, err := Load(filepath.Join(dir, "missing.yaml"))
+	if err != nil || len(list) != 0 {
+		t.Errorf("expected a missing file to be an empty list, got %v, %v", list, err)
+	}
+
+	list, err = Load(writeList(t, dir, `
+- spec: Apps with a deployed app can get app logs
+  reason: V broken
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := list.Lookup("Apps with a deployed app can get app logs")
+	if !ok || entry.Reason != "V broken" {
+		t.Errorf("expected to find the entry, got %+v", entry)
+	}
+	if _, ok := list.Lookup("Apps with a deployed app [deploy] can get app logs"); !ok {
+		t.Error("expected lookups to ignore labels")
+	}
+	if _, ok := list.Lookup("Apps with a deployed app"); ok {
+		t.Error("expected lookups to match the whole spec text")
+	}
+
+	if _, err := Load(writeList(t, dir, "- spec: Apps can create\n")); err == nil || !strings.Contains(err.Error(), "reason") {
+		t.Errorf("expected an entry without a reason to be rejected, got %v", err)
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	list := List{{Spec: "Apps can run a command (with args)"}, {Spec: "Releases can deploy the app"}}
+	re := regexp.MustCompile(list.Regexp())
+	for text, expected := range map[string]bool{
+		"Deis Workflow [Top Level] Apps can run a command (with args)":                  true,
+		"Deis Workflow [Top Level] Releases can deploy the app":                         true,
+		"Deis Workflow [Top Level] Releases can deploy the app twice":                   false,
+		"Deis Workflow [Top Level] Old Releases can deploy the app":                     false,
+		"Deis Workflow [Top Level] Releases [deploy] can deploy the app [slow] [admin]": true,
+	} {
+		if re.MatchString(text) != expected {
+			t.Errorf("expected %q matching %s to be %v", list.Regexp(), text, expected)
+		}
+	}
+}
+
+func TestReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list := List{
+		{Spec: "Apps can get app logs", Reason: "V broken"},
+		{Spec: "Apps can run a command", Reason: "V broken"},
+		{Spec: "Releases can deploy the app", Reason: "500's everytime"},
+		{Spec: "Healthcheck can stay running", Reason: "broken"},
+	}
+	path := filepath.Join(dir, "quarantine.json")
+	var out bytes.Buffer
+	reporter := NewReporter(list, &out, path)
+	complete := func(spec string, state types.SpecState) {
+		reporter.SpecDidComplete(&types.SpecSummary{
+			ComponentTexts: append([]string{"[Top Level]"}, strings.SplitN(spec, " ", 2)...),
+			State:          state,
+		})
+	}
+	complete("Apps can get app logs", types.SpecStatePassed)
+	complete("Apps can run a command", types.SpecStateFailed)
+	complete("Apps can run a command", types.SpecStatePassed)
+	complete("Releases can deploy the app", types.SpecStateFailed)
+	complete("Releases can deploy the app", types.SpecStateFailed)
+	complete("Apps can create", types.SpecStateFailed)
+	reporter.SpecSuiteDidEnd(&types.SuiteSummary{})
+
+	expected := []Result{
+		{Spec: "Apps can get app logs", Reason: "V broken", Status: Stable, Attempts: 1},
+		{Spec: "Apps can run a command", Reason: "V broken", Status: Flaky, Attempts: 2},
+		{Spec: "Releases can deploy the app", Reason: "500's everytime", Status: Failing, Attempts: 2},
+		{Spec: "Healthcheck can stay running", Reason: "broken", Status: NotRun, Attempts: 0},
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %+v", len(expected), results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], results[i])
+		}
+	}
+	if !strings.Contains(out.String(), "stable   Apps can get app logs (1 attempts)") {
+		t.Errorf("expected a summary, got %q", out.String())
+	}
+}
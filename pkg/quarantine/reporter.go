@@ -0,0 +1,124 @@
+package quarantine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/types"
+)
+
+// Statuses of a quarantined spec after a quarantine run.
+const (
+	// Stable specs passed on their first attempt, and are candidates for leaving quarantine.
+	Stable = "stable"
+	// Flaky specs passed, but only after being retried.
+	Flaky   = "flaky"
+	Failing = "failing"
+	NotRun  = "not run"
+)
+
+// Result is how a quarantined spec fared in a quarantine run.
+type Result struct {
+	Spec     string `json:"spec"`
+	Reason   string `json:"reason"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+}
+
+// Reporter is a Ginkgo reporter which tracks how often each quarantined spec was attempted and
+// whether it eventually passed. When the suite ends it prints a summary to out, and writes the
+// results as JSON to path if that is set.
+type Reporter struct {
+	list     List
+	out      io.Writer
+	path     string
+	attempts map[string]int
+	passed   map[string]bool
+}
+
+// NewReporter returns a Reporter for the specs in list.
+func NewReporter(list List, out io.Writer, path string) *Reporter {
+	return &Reporter{
+		list:     list,
+		out:      out,
+		path:     path,
+		attempts: make(map[string]int),
+		passed:   make(map[string]bool),
+	}
+}
+
+// Results returns the result of every quarantined spec, in the order they are listed.
+func (r *Reporter) Results() []Result {
+	results := make([]Result, 0, len(r.list))
+	for _, entry := range r.list {
+		result := Result{Spec: entry.Spec, Reason: entry.Reason, Attempts: r.attempts[entry.Spec]}
+		switch {
+		case result.Attempts == 0:
+			result.Status = NotRun
+		case !r.passed[entry.Spec]:
+			result.Status = Failing
+		case result.Attempts == 1:
+			result.Status = Stable
+		default:
+			result.Status = Flaky
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// SpecSuiteWillBegin implements ginkgo's Reporter interface.
+func (r *Reporter) SpecSuiteWillBegin(config.GinkgoConfigType, *types.SuiteSummary) {}
+
+// BeforeSuiteDidRun implements ginkgo's Reporter interface.
+func (r *Reporter) BeforeSuiteDidRun(*types.SetupSummary) {}
+
+// SpecWillRun implements ginkgo's Reporter interface.
+func (r *Reporter) SpecWillRun(*types.SpecSummary) {}
+
+// SpecDidComplete implements ginkgo's Reporter interface. With -ginkgo.flakeAttempts it is
+// called once per attempt.
+func (r *Reporter) SpecDidComplete(summary *types.SpecSummary) {
+	if summary.Skipped() || summary.Pending() || len(summary.ComponentTexts) == 0 {
+		return
+	}
+	entry, ok := r.list.Lookup(strings.Join(summary.ComponentTexts[1:], " "))
+	if !ok {
+		return
+	}
+	spec := entry.Spec
+	r.attempts[spec]++
+	r.passed[spec] = summary.Passed()
+}
+
+// AfterSuiteDidRun implements ginkgo's Reporter interface.
+func (r *Reporter) AfterSuiteDidRun(*types.SetupSummary) {}
+
+// SpecSuiteDidEnd implements ginkgo's Reporter interface.
+func (r *Reporter) SpecSuiteDidEnd(*types.SuiteSummary) {
+	results := r.Results()
+	fmt.Fprintln(r.out, "\nQuarantined specs:")
+	for _, result := range results {
+		fmt.Fprintf(r.out, "  %-8s %s (%d attempts)\n", result.Status, result.Spec, result.Attempts)
+	}
+	if r.path == "" {
+		return
+	}
+	err := os.MkdirAll(filepath.Dir(r.path), 0755)
+	var data []byte
+	if err == nil {
+		data, err = json.MarshalIndent(results, "", "  ")
+	}
+	if err == nil {
+		err = ioutil.WriteFile(r.path, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(r.out, "could not write quarantine report: %v\n", err)
+	}
+}
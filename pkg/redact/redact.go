@@ -0,0 +1,128 @@
+// Package redact masks secrets, such as passwords and tokens, in text which ends up in logs and
+// reports.
+package redact
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every secret.
+const Mask = "[REDACTED]"
+
+// minLength is the length below which a string is too short to be masked without masking much
+// that isn't secret.
+const minLength = 4
+
+// maxPending is how much of an unterminated line a Writer holds back before writing it anyway.
+const maxPending = 4096
+
+// Redactor masks registered secrets. It is safe for concurrent use.
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  []string
+	replacer *strings.Replacer
+}
+
+// New returns a Redactor with no secrets.
+func New() *Redactor {
+	return &Redactor{}
+}
+
+type byLength []string
+
+func (s byLength) Len() int           { return len(s) }
+func (s byLength) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byLength) Less(i, j int) bool { return len(s[i]) > len(s[j]) }
+
+// Add registers secrets to be masked. Surrounding whitespace is ignored, as are secrets shorter
+// than four characters.
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, secret := range secrets {
+		secret = strings.TrimSpace(secret)
+		if len(secret) < minLength || r.has(secret) {
+			continue
+		}
+		r.secrets = append(r.secrets, secret)
+	}
+	// mask longer secrets first, so that a secret containing another is masked whole
+	sort.Sort(byLength(r.secrets))
+	pairs := make([]string, 0, 2*len(r.secrets))
+	for _, secret := range r.secrets {
+		pairs = append(pairs, secret, Mask)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+func (r *Redactor) has(secret string) bool {
+	for _, s := range r.secrets {
+		if s == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns s with every registered secret masked.
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// Writer returns a Writer which masks secrets in everything written through it to w.
+func (r *Redactor) Writer(w io.Writer) *Writer {
+	return &Writer{redactor: r, w: w}
+}
+
+// Writer masks secrets before passing output on. Since a secret may be split across writes, it
+// holds back unterminated lines until they are finished or Flush is called.
+type Writer struct {
+	redactor *Redactor
+	w        io.Writer
+	mu       sync.Mutex
+	pending  []byte
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, p...)
+	end := bytes.LastIndexByte(w.pending, '\n') + 1
+	if len(w.pending) > maxPending {
+		end = len(w.pending)
+	}
+	if end == 0 {
+		return len(p), nil
+	}
+	if err := w.write(end); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes out any unterminated line being held back.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.write(len(w.pending))
+}
+
+// write passes on the first n pending bytes. w.mu must be held.
+func (w *Writer) write(n int) error {
+	if n == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w.w, w.redactor.String(string(w.pending[:n])))
+	w.pending = append(w.pending[:0], w.pending[n:]...)
+	return err
+}
@@ -0,0 +1,69 @@
+package redact
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	r := New()
+	if actual := r.String("deis login --password=asdf1234"); actual != "deis login --password=asdf1234" {
+		t.Errorf("expected no masking without secrets, got %q", actual)
+	}
+
+	r.Add("asdf1234", "  tok3n-abc  ", "abc", "")
+	r.Add("asdf1234") // registering twice is harmless
+	for input, expected := range map[string]string{
+		"deis login --password=asdf1234":        "deis login --password=" + Mask,
+		"token: tok3n-abc, again tok3n-abc":     "token: " + Mask + ", again " + Mask,
+		"abc is too short to be a secret":       "abc is too short to be a secret",
+		"asdf1234asdf1234 appears back to back": Mask + Mask + " appears back to back",
+	} {
+		if actual := r.String(input); actual != expected {
+			t.Errorf("String(%q) = %q, expected %q", input, actual, expected)
+		}
+	}
+}
+
+func TestLongestSecretFirst(t *testing.T) {
+	r := New()
+	r.Add("pass", "password1")
+	if actual := r.String("password1"); actual != Mask {
+		t.Errorf("expected the longer secret to be masked whole, got %q", actual)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	r := New()
+	r.Add("hunter22")
+	var buf bytes.Buffer
+	w := r.Writer(&buf)
+
+	// a secret split across writes is still masked
+	w.Write([]byte("the password is hun"))
+	if buf.Len() != 0 {
+		t.Errorf("expected an unterminated line to be held back, got %q", buf.String())
+	}
+	w.Write([]byte("ter22\nand the next line is hun"))
+	if expected := "the password is " + Mask + "\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+	w.Write([]byte("ter22 too"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "the password is " + Mask + "\nand the next line is " + Mask + " too"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriterLongLine(t *testing.T) {
+	var buf bytes.Buffer
+	w := New().Writer(&buf)
+	line := strings.Repeat("x", maxPending+1)
+	w.Write([]byte(line))
+	if buf.String() != line {
+		t.Errorf("expected a line longer than %d bytes to be written without waiting for its end", maxPending)
+	}
+}
@@ -0,0 +1,88 @@
+package releases
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Kinds of Change.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is a single difference between two releases.
+type Change struct {
+	// Field names what changed, such as "build", "config.FOO", "limits.memory.web" or "tags.zone".
+	// It is "config" when the config values of either release are unknown and only the config
+	// objects they point at could be compared.
+	Field string `json:"field"`
+	Kind  string `json:"kind"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s added: %s", c.Field, c.New)
+	case Removed:
+		return fmt.Sprintf("%s removed: %s", c.Field, c.Old)
+	default:
+		return fmt.Sprintf("%s changed: %s -> %s", c.Field, c.Old, c.New)
+	}
+}
+
+type byField []Change
+
+func (c byField) Len() int           { return len(c) }
+func (c byField) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byField) Less(i, j int) bool { return c[i].Field < c[j].Field }
+
+// Diff returns what changed going from release a to release b, sorted by field.
+//
+// Metadata which differs between any two releases (version, uuid, owner, summary and timestamps)
+// is not compared.
+func Diff(a, b *Release) []Change {
+	changes := diffValues("", pick(a.Info, "build"), pick(b.Info, "build"))
+	if a.Config != nil && b.Config != nil {
+		changes = append(changes, diffValues("config.", a.Config, b.Config)...)
+	} else {
+		changes = append(changes, diffValues("", pick(a.Info, "config"), pick(b.Info, "config"))...)
+	}
+	if a.Limits != nil && b.Limits != nil {
+		changes = append(changes, diffValues("limits.", a.Limits, b.Limits)...)
+	}
+	if a.Tags != nil && b.Tags != nil {
+		changes = append(changes, diffValues("tags.", a.Tags, b.Tags)...)
+	}
+	sort.Sort(byField(changes))
+	return changes
+}
+
+// pick returns a map holding only the named key of m, if it is set.
+func pick(m map[string]string, key string) map[string]string {
+	picked := make(map[string]string)
+	if value, ok := m[key]; ok {
+		picked[key] = value
+	}
+	return picked
+}
+
+func diffValues(prefix string, a, b map[string]string) []Change {
+	var changes []Change
+	for key, old := range a {
+		if value, ok := b[key]; !ok {
+			changes = append(changes, Change{Field: prefix + key, Kind: Removed, Old: old})
+		} else if value != old {
+			changes = append(changes, Change{Field: prefix + key, Kind: Changed, Old: old, New: value})
+		}
+	}
+	for key, value := range b {
+		if _, ok := a[key]; !ok {
+			changes = append(changes, Change{Field: prefix + key, Kind: Added, New: value})
+		}
+	}
+	return changes
+}
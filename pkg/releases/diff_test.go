@@ -0,0 +1,80 @@
+package releases
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	a := &Release{
+		Version: 2,
+		Info:    map[string]string{"build": "b1", "config": "c1", "uuid": "u1"},
+		Config:  map[string]string{"FOO": "bar", "GONE": "soon"},
+		Limits:  map[string]string{"memory.web": "64M"},
+		Tags:    map[string]string{},
+	}
+	b := &Release{
+		Version: 3,
+		Info:    map[string]string{"build": "b1", "config": "c2", "uuid": "u2"},
+		Config:  map[string]string{"FOO": "baz", "NEW": "value"},
+		Limits:  map[string]string{"memory.web": "128M"},
+		Tags:    map[string]string{"zone": "east"},
+	}
+	expected := []Change{
+		{Field: "config.FOO", Kind: Changed, Old: "bar", New: "baz"},
+		{Field: "config.GONE", Kind: Removed, Old: "soon"},
+		{Field: "config.NEW", Kind: Added, New: "value"},
+		{Field: "limits.memory.web", Kind: Changed, Old: "64M", New: "128M"},
+		{Field: "tags.zone", Kind: Added, New: "east"},
+	}
+	if actual := Diff(a, b); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if changes := Diff(b, b); len(changes) != 0 {
+		t.Errorf("expected no changes between a release and itself, got %v", changes)
+	}
+}
+
+func TestDiffMetadataOnly(t *testing.T) {
+	a := &Release{Version: 2, Info: map[string]string{"config": "c1", "owner": "alice"}}
+	b := &Release{
+		Version: 3,
+		Info:    map[string]string{"build": "b1", "config": "c2", "owner": "bob"},
+		Config:  map[string]string{"FOO": "bar"},
+	}
+	expected := []Change{
+		{Field: "build", Kind: Added, New: "b1"},
+		{Field: "config", Kind: Changed, Old: "c1", New: "c2"},
+	}
+	if actual := Diff(a, b); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	for _, test := range []struct {
+		change   Change
+		expected string
+	}{
+		{Change{Field: "config.FOO", Kind: Added, New: "bar"}, "config.FOO added: bar"},
+		{Change{Field: "config.FOO", Kind: Removed, Old: "bar"}, "config.FOO removed: bar"},
+		{Change{Field: "build", Kind: Changed, Old: "b1", New: "b2"}, "build changed: b1 -> b2"},
+	} {
+		if actual := test.change.String(); actual != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, actual)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	for input, expected := range map[string]int{"v3": 3, "12": 12} {
+		if actual, err := ParseVersion(input); err != nil || actual != expected {
+			t.Errorf("ParseVersion(%q) = %d, %v; expected %d", input, actual, err, expected)
+		}
+	}
+	for _, input := range []string{"", "v", "v0", "latest"} {
+		if _, err := ParseVersion(input); err == nil {
+			t.Errorf("expected ParseVersion(%q) to fail", input)
+		}
+	}
+}
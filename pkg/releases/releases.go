@@ -0,0 +1,128 @@
+// Package releases fetches what an app's releases contain and reports how two of them differ.
+//
+// The controller only keeps the values of an app's current config, limits and tags. A release
+// fetched after it has been superseded therefore carries its metadata alone, while a Snapshot taken
+// while it is current carries everything. Diff compares as much as both sides know.
+package releases
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/deis/workflow/_tests/pkg/parse"
+)
+
+// Runner runs the deis CLI with args and returns its combined output.
+type Runner func(args ...string) (string, error)
+
+// CLI is a Runner which executes the "deis" binary found in $PATH.
+func CLI(args ...string) (string, error) {
+	output, err := exec.Command("deis", args...).CombinedOutput()
+	return string(output), err
+}
+
+// Release describes one release of an app.
+type Release struct {
+	App     string `json:"app"`
+	Version int    `json:"version"`
+	// Info holds the fields printed by "deis releases:info", such as "build", "config" and "owner".
+	Info map[string]string `json:"info"`
+	// Config, Limits and Tags are nil, saved as null, when the release was not current when it
+	// was fetched, and empty when the app has none.
+	Config map[string]string `json:"config"`
+	Limits map[string]string `json:"limits"`
+	Tags   map[string]string `json:"tags"`
+}
+
+// Fetch returns the metadata of version of app.
+func Fetch(run Runner, app string, version int) (*Release, error) {
+	output, err := run("releases:info", fmt.Sprintf("v%d", version), "-a", app)
+	if err != nil {
+		return nil, fmt.Errorf("fetching v%d of %s: %v\n%s", version, app, err, output)
+	}
+	return &Release{App: app, Version: version, Info: parse.ReleaseInfo(output)}, nil
+}
+
+// Latest returns the version number of app's current release.
+func Latest(run Runner, app string) (int, error) {
+	output, err := run("releases:list", "-a", app)
+	if err != nil {
+		return 0, fmt.Errorf("listing releases of %s: %v\n%s", app, err, output)
+	}
+	list, err := parse.ReleasesList(output)
+	if err != nil {
+		return 0, err
+	}
+	return list[0].Version, nil
+}
+
+// Snapshot returns app's current release along with its config, limits and tags.
+func Snapshot(run Runner, app string) (*Release, error) {
+	version, err := Latest(run, app)
+	if err != nil {
+		return nil, err
+	}
+	release, err := Fetch(run, app, version)
+	if err != nil {
+		return nil, err
+	}
+	for _, list := range []struct {
+		command string
+		parse   func(string) map[string]string
+		into    *map[string]string
+	}{
+		{"config:list", parse.ConfigList, &release.Config},
+		{"limits:list", parse.LimitsList, &release.Limits},
+		{"tags:list", parse.TagsList, &release.Tags},
+	} {
+		output, err := run(list.command, "-a", app)
+		if err != nil {
+			return nil, fmt.Errorf("running %s for %s: %v\n%s", list.command, app, err, output)
+		}
+		*list.into = list.parse(output)
+	}
+	// make sure nothing was released while the snapshot was being taken
+	latest, err := Latest(run, app)
+	if err != nil {
+		return nil, err
+	}
+	if latest != version {
+		return nil, fmt.Errorf("%s was released as v%d while taking a snapshot of v%d", app, latest, version)
+	}
+	return release, nil
+}
+
+// Load reads a release previously written by Save.
+func Load(path string) (*Release, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	release := new(Release)
+	if err := json.Unmarshal(data, release); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return release, nil
+}
+
+// Save writes release to path as JSON.
+func Save(release *Release, path string) error {
+	data, err := json.MarshalIndent(release, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ParseVersion parses a version given as "v3" or "3".
+func ParseVersion(s string) (int, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(s, "v"))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid release version %q", s)
+	}
+	return version, nil
+}
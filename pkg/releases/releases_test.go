@@ -0,0 +1,74 @@
+package releases
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoadDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "releases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	before := &Release{
+		App:     "web",
+		Version: 2,
+		Info:    map[string]string{"build": "b1", "config": "c1"},
+		Config:  map[string]string{},
+		Limits:  map[string]string{},
+		Tags:    map[string]string{},
+	}
+	after := &Release{
+		App:     "web",
+		Version: 3,
+		Info:    map[string]string{"build": "b1", "config": "c2"},
+		Config:  map[string]string{"FOO": "bar"},
+		Limits:  map[string]string{"memory.web": "64M"},
+		Tags:    map[string]string{"zone": "east"},
+	}
+	older := &Release{App: "web", Version: 1, Info: map[string]string{"build": "b0", "config": "c0"}}
+	load := func(release *Release) *Release {
+		path := filepath.Join(dir, "release.json")
+		if err := Save(release, path); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loaded, release) {
+			t.Errorf("expected %+v to be read back, got %+v", release, loaded)
+		}
+		return loaded
+	}
+	before, after, older = load(before), load(after), load(older)
+
+	added := []Change{
+		{Field: "config.FOO", Kind: Added, New: "bar"},
+		{Field: "limits.memory.web", Kind: Added, New: "64M"},
+		{Field: "tags.zone", Kind: Added, New: "east"},
+	}
+	if changes := Diff(before, after); !reflect.DeepEqual(changes, added) {
+		t.Errorf("expected %v, got %v", added, changes)
+	}
+	removed := []Change{
+		{Field: "config.FOO", Kind: Removed, Old: "bar"},
+		{Field: "limits.memory.web", Kind: Removed, Old: "64M"},
+		{Field: "tags.zone", Kind: Removed, Old: "east"},
+	}
+	if changes := Diff(after, before); !reflect.DeepEqual(changes, removed) {
+		t.Errorf("expected %v, got %v", removed, changes)
+	}
+	fetched := []Change{
+		{Field: "build", Kind: Changed, Old: "b0", New: "b1"},
+		{Field: "config", Kind: Changed, Old: "c0", New: "c1"},
+	}
+	if changes := Diff(older, before); !reflect.DeepEqual(changes, fetched) {
+		t.Errorf("expected %v, got %v", fetched, changes)
+	}
+}
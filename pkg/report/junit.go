@@ -0,0 +1,107 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type junitSuite struct {
+	XMLName  xml.Name `xml:"testsuite"`
+	Name     string   `xml:"name,attr"`
+	Tests    int      `xml:"tests,attr"`
+	Failures int      `xml:"failures,attr"`
+	Errors   int      `xml:"errors,attr"`
+	Skipped  int      `xml:"skipped,attr"`
+	Time     float64  `xml:"time,attr"`
+	// Properties holds the versions under test.
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes r to w as JUnit XML. Each spec's transcript becomes the system-out of its test
+// case. BeforeSuite and AfterSuite are only included when they failed.
+func WriteJUnit(w io.Writer, r *Report) error {
+	suite := junitSuite{Name: r.Suite, Time: r.Duration}
+	names := make([]string, 0, len(r.Versions))
+	for name := range r.Versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		suite.Properties = append(suite.Properties, junitProperty{Name: name + ".version", Value: r.Versions[name]})
+	}
+	for _, spec := range r.Specs {
+		if spec.Setup && !spec.Failed() {
+			continue
+		}
+		testCase := junitCase{
+			Name:      spec.Name,
+			ClassName: r.Suite,
+			Time:      spec.Duration,
+			SystemOut: spec.Transcript(),
+		}
+		if spec.Attempts > 1 {
+			testCase.SystemOut = fmt.Sprintf("(%s after %d attempts)\n", spec.State, spec.Attempts) + testCase.SystemOut
+		}
+		switch {
+		case spec.Failed():
+			text := spec.Failure.Location + "\n" + spec.Failure.Message
+			if spec.Failure.Panic != "" {
+				text += "\n" + spec.Failure.Panic
+			}
+			testCase.Failure = &junitFailure{Message: spec.Failure.Message, Type: spec.State, Text: text}
+			suite.Failures++
+		case spec.State == Skipped || spec.State == Pending:
+			testCase.Skipped = &struct{}{}
+			suite.Skipped++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// SaveJUnit writes r to path as JUnit XML.
+func SaveJUnit(r *Report, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteJUnit(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
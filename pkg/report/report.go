@@ -0,0 +1,119 @@
+// Package report describes the results of a suite run, including every command each spec ran,
+// and writes them out as JSON and JUnit XML.
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/transcript"
+)
+
+// States a Spec can end in.
+const (
+	Passed   = "passed"
+	Failed   = "failed"
+	Panicked = "panicked"
+	TimedOut = "timedout"
+	Skipped  = "skipped"
+	Pending  = "pending"
+)
+
+// Report is the result of running a suite.
+type Report struct {
+	Suite     string    `json:"suite"`
+	Started   time.Time `json:"started"`
+	Duration  float64   `json:"duration"`
+	Succeeded bool      `json:"succeeded"`
+	// Versions are the versions of the components under test, such as the CLI's and the
+	// controller API's.
+	Versions map[string]string `json:"versions,omitempty"`
+	Passed   int               `json:"passed"`
+	Failed   int               `json:"failed"`
+	// Flaky counts the passed specs which needed more than one attempt.
+	Flaky   int    `json:"flaky"`
+	Skipped int    `json:"skipped"`
+	Pending int    `json:"pending"`
+	Specs   []Spec `json:"specs"`
+}
+
+// Spec is the result of running a single spec, or of BeforeSuite or AfterSuite when Setup is set.
+type Spec struct {
+	Name       string   `json:"name"`
+	Components []string `json:"components,omitempty"`
+	Location   string   `json:"location"`
+	Setup      bool     `json:"setup,omitempty"`
+	State      string   `json:"state"`
+	// Duration is in seconds, across all attempts.
+	Duration float64 `json:"duration"`
+	// Attempts is how many times the spec was run, which is more than once when it was retried
+	// with -ginkgo.flakeAttempts.
+	Attempts int                  `json:"attempts,omitempty"`
+	Failure  *Failure             `json:"failure,omitempty"`
+	Commands []transcript.Command `json:"commands"`
+}
+
+// Failure describes why a spec failed.
+type Failure struct {
+	Message  string `json:"message"`
+	Location string `json:"location"`
+	Panic    string `json:"panic,omitempty"`
+}
+
+// Failed reports whether the spec failed, panicked or timed out.
+func (s Spec) Failed() bool {
+	return s.State == Failed || s.State == Panicked || s.State == TimedOut
+}
+
+// Flaky reports whether the spec passed, but only after being retried.
+func (s Spec) Flaky() bool {
+	return s.State == Passed && s.Attempts > 1
+}
+
+// Transcript renders the commands the spec ran as a shell session.
+func (s Spec) Transcript() string {
+	var buf bytes.Buffer
+	for _, command := range s.Commands {
+		fmt.Fprintf(&buf, "$ %s\n", command.Command)
+		for _, output := range []string{command.Stdout, command.Stderr} {
+			if output != "" {
+				buf.WriteString(output)
+				if !strings.HasSuffix(output, "\n") {
+					buf.WriteString("\n")
+				}
+			}
+		}
+		if command.ExitCode == -1 {
+			fmt.Fprintf(&buf, "(still running after %.2fs)\n", command.Duration)
+		} else {
+			fmt.Fprintf(&buf, "(exit %d after %.2fs)\n", command.ExitCode, command.Duration)
+		}
+	}
+	return buf.String()
+}
+
+// Load reads a report written by Save.
+func Load(path string) (*Report, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := new(Report)
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return r, nil
+}
+
+// Save writes r to path as JSON.
+func Save(r *Report, path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,148 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/transcript"
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/types"
+)
+
+func TestTranscript(t *testing.T) {
+	spec := Spec{Commands: []transcript.Command{
+		{Command: "deis apps:create foo", ExitCode: 0, Duration: 1.5, Stdout: "created foo"},
+		{Command: "deis logs -a foo", ExitCode: 1, Duration: 0.25, Stderr: "Not found.\n"},
+		{Command: "git push deis master", ExitCode: -1, Duration: 120},
+	}}
+	expected := `$ deis apps:create foo
+created foo
+(exit 0 after 1.50s)
+$ deis logs -a foo
+Not found.
+(exit 1 after 0.25s)
+$ git push deis master
+(still running after 120.00s)
+`
+	if actual := spec.Transcript(); actual != expected {
+		t.Errorf("expected transcript\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	r := &Report{Suite: "Deis Workflow", Specs: []Spec{
+		{Name: "BeforeSuite", Setup: true, State: Passed},
+		{Name: "Apps can create", State: Passed, Commands: []transcript.Command{{Command: "deis apps:create"}}},
+		{Name: "Apps can destroy", State: Failed, Failure: &Failure{Message: "boom", Location: "apps_test.go:10"}},
+		{Name: "Apps can open", State: Pending},
+	}, Versions: map[string]string{"cli": "2.0.0-dev", "api": "2.0"}}
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var suite junitSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 {
+		t.Errorf("expected 3 tests with 1 failure and 1 skipped, got %+v", suite)
+	}
+	if !strings.Contains(suite.Cases[0].SystemOut, "$ deis apps:create") {
+		t.Errorf("expected the transcript in system-out, got %q", suite.Cases[0].SystemOut)
+	}
+	if f := suite.Cases[1].Failure; f == nil || f.Message != "boom" {
+		t.Errorf("expected a failure with message boom, got %+v", f)
+	}
+	if len(suite.Properties) != 2 || suite.Properties[1] != (junitProperty{"cli.version", "2.0.0-dev"}) {
+		t.Errorf("expected the versions as properties, got %+v", suite.Properties)
+	}
+}
+
+func TestReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recorder := transcript.NewRecorder()
+	reporter := NewReporter(dir, recorder)
+	reporter.SpecSuiteWillBegin(config.GinkgoConfigType{ParallelTotal: 1}, &types.SuiteSummary{SuiteDescription: "Deis Workflow"})
+	recorder.Begin("deis login", nil, nil).End(0)
+	reporter.BeforeSuiteDidRun(&types.SetupSummary{State: types.SpecStatePassed})
+
+	summary := &types.SpecSummary{ComponentTexts: []string{"[Top Level]", "Apps", "can create"}}
+	reporter.SpecWillRun(summary)
+	recorder.Begin("deis apps:create foo", nil, nil).End(0)
+	summary.State = types.SpecStateFailed
+	summary.RunTime = 2 * time.Second
+	summary.Failure = types.SpecFailure{Message: "boom"}
+	reporter.SpecDidComplete(summary)
+	reporter.SpecSuiteDidEnd(&types.SuiteSummary{})
+
+	r, err := Load(filepath.Join(dir, "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Specs) != 2 || r.Failed != 1 {
+		t.Fatalf("expected a setup node and one failed spec, got %+v", r)
+	}
+	spec := r.Specs[1]
+	if spec.Name != "Apps can create" || spec.Duration != 2 || spec.Failure.Message != "boom" {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+	if len(spec.Commands) != 1 || spec.Commands[0].Command != "deis apps:create foo" {
+		t.Errorf("expected the spec's own command, got %+v", spec.Commands)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "junit.xml")); err != nil {
+		t.Errorf("expected a JUnit report: %v", err)
+	}
+}
+
+func TestReporterRetries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recorder := transcript.NewRecorder()
+	reporter := NewReporter(dir, recorder)
+	reporter.SpecSuiteWillBegin(config.GinkgoConfigType{ParallelTotal: 1}, &types.SuiteSummary{SuiteDescription: "Deis Workflow"})
+
+	// the first attempt fails and the second passes, as with -ginkgo.flakeAttempts=2
+	for i, state := range []types.SpecState{types.SpecStateFailed, types.SpecStatePassed} {
+		summary := &types.SpecSummary{ComponentTexts: []string{"[Top Level]", "Apps", "can get app logs"}}
+		reporter.SpecWillRun(summary)
+		recorder.Begin(fmt.Sprintf("deis logs # attempt %d", i+1), nil, nil).End(0)
+		summary.State = state
+		summary.RunTime = time.Second
+		if state == types.SpecStateFailed {
+			summary.Failure = types.SpecFailure{Message: "no logs yet"}
+		}
+		reporter.SpecDidComplete(summary)
+	}
+	reporter.SpecSuiteDidEnd(&types.SuiteSummary{SuiteSucceeded: true})
+
+	r, err := Load(filepath.Join(dir, "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Specs) != 1 || r.Passed != 1 || r.Failed != 0 || r.Flaky != 1 {
+		t.Fatalf("expected one flaky spec, got %+v", r)
+	}
+	spec := r.Specs[0]
+	if spec.Attempts != 2 || spec.Duration != 2 || spec.Failure != nil || !spec.Flaky() {
+		t.Errorf("expected the attempts to be merged, got %+v", spec)
+	}
+	if len(spec.Commands) != 2 {
+		t.Errorf("expected the commands of both attempts, got %+v", spec.Commands)
+	}
+}
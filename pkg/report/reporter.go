@@ -0,0 +1,185 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/transcript"
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/types"
+)
+
+// Reporter is a Ginkgo reporter which attaches the commands recorded during each spec to its
+// result, and writes the results to report.json and junit.xml in a directory when the suite ends.
+//
+// When specs run in parallel, each node writes report_<node>.json and junit_<node>.xml instead.
+type Reporter struct {
+	// Redact, if set, is applied to every command, its output and failure messages before they
+	// are written.
+	Redact func(string) string
+	// Versions, if set before the suite ends, are recorded in the report as the versions under
+	// test.
+	Versions map[string]string
+
+	dir      string
+	commands *transcript.Recorder
+	suffix   string
+	report   Report
+}
+
+// NewReporter returns a Reporter which writes to dir, taking commands from recorder.
+func NewReporter(dir string, recorder *transcript.Recorder) *Reporter {
+	return &Reporter{dir: dir, commands: recorder}
+}
+
+// SpecSuiteWillBegin implements ginkgo's Reporter interface.
+func (r *Reporter) SpecSuiteWillBegin(cfg config.GinkgoConfigType, summary *types.SuiteSummary) {
+	r.report = Report{Suite: summary.SuiteDescription, Started: time.Now()}
+	if cfg.ParallelTotal > 1 {
+		r.suffix = fmt.Sprintf("_%d", cfg.ParallelNode)
+	}
+	r.commands.Flush()
+}
+
+// BeforeSuiteDidRun implements ginkgo's Reporter interface.
+func (r *Reporter) BeforeSuiteDidRun(summary *types.SetupSummary) {
+	r.addSetup("BeforeSuite", summary)
+}
+
+// SpecWillRun implements ginkgo's Reporter interface.
+func (r *Reporter) SpecWillRun(summary *types.SpecSummary) {
+	// anything recorded between specs doesn't belong to the one about to run
+	r.commands.Flush()
+}
+
+// SpecDidComplete implements ginkgo's Reporter interface. When a spec is retried it is called
+// once per attempt, and the attempts are merged into a single entry.
+func (r *Reporter) SpecDidComplete(summary *types.SpecSummary) {
+	texts := summary.ComponentTexts
+	if len(texts) > 0 {
+		// drop the "[Top Level]" container
+		texts = texts[1:]
+	}
+	location := ""
+	if n := len(summary.ComponentCodeLocations); n > 0 {
+		location = summary.ComponentCodeLocations[n-1].String()
+	}
+	r.add(Spec{
+		Name:       strings.Join(texts, " "),
+		Components: texts,
+		Location:   location,
+		State:      state(summary.State),
+		Duration:   summary.RunTime.Seconds(),
+		Attempts:   1,
+		Failure:    failure(summary.State, summary.Failure),
+		Commands:   r.commands.Flush(),
+	})
+}
+
+// AfterSuiteDidRun implements ginkgo's Reporter interface.
+func (r *Reporter) AfterSuiteDidRun(summary *types.SetupSummary) {
+	r.addSetup("AfterSuite", summary)
+}
+
+// SpecSuiteDidEnd implements ginkgo's Reporter interface.
+func (r *Reporter) SpecSuiteDidEnd(summary *types.SuiteSummary) {
+	r.report.Duration = summary.RunTime.Seconds()
+	r.report.Succeeded = summary.SuiteSucceeded
+	r.report.Versions = r.Versions
+	for _, spec := range r.report.Specs {
+		switch {
+		case spec.Setup:
+		case spec.Failed():
+			r.report.Failed++
+		case spec.State == Passed:
+			r.report.Passed++
+			if spec.Flaky() {
+				r.report.Flaky++
+			}
+		case spec.State == Skipped:
+			r.report.Skipped++
+		case spec.State == Pending:
+			r.report.Pending++
+		}
+	}
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "could not create report directory: %v\n", err)
+		return
+	}
+	if err := Save(&r.report, filepath.Join(r.dir, "report"+r.suffix+".json")); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write JSON report: %v\n", err)
+	}
+	if err := SaveJUnit(&r.report, filepath.Join(r.dir, "junit"+r.suffix+".xml")); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write JUnit report: %v\n", err)
+	}
+}
+
+func (r *Reporter) addSetup(name string, summary *types.SetupSummary) {
+	r.add(Spec{
+		Name:     name,
+		Location: summary.CodeLocation.String(),
+		Setup:    true,
+		State:    state(summary.State),
+		Duration: summary.RunTime.Seconds(),
+		Failure:  failure(summary.State, summary.Failure),
+		Commands: r.commands.Flush(),
+	})
+}
+
+// add appends spec to the report, redacting it first, or merges it with the previous entry when it
+// is a retry of that spec.
+func (r *Reporter) add(spec Spec) {
+	if r.Redact != nil {
+		for i := range spec.Commands {
+			command := &spec.Commands[i]
+			command.Command = r.Redact(command.Command)
+			command.Stdout = r.Redact(command.Stdout)
+			command.Stderr = r.Redact(command.Stderr)
+		}
+		if spec.Failure != nil {
+			spec.Failure.Message = r.Redact(spec.Failure.Message)
+			spec.Failure.Panic = r.Redact(spec.Failure.Panic)
+		}
+	}
+	if n := len(r.report.Specs); n > 0 && !spec.Setup {
+		// a retry follows its failed attempt immediately
+		last := &r.report.Specs[n-1]
+		if !last.Setup && last.Failed() && last.Name == spec.Name && last.Location == spec.Location {
+			spec.Attempts += last.Attempts
+			spec.Duration += last.Duration
+			spec.Commands = append(last.Commands, spec.Commands...)
+			*last = spec
+			return
+		}
+	}
+	r.report.Specs = append(r.report.Specs, spec)
+}
+
+func state(s types.SpecState) string {
+	switch s {
+	case types.SpecStatePassed:
+		return Passed
+	case types.SpecStateFailed:
+		return Failed
+	case types.SpecStatePanicked:
+		return Panicked
+	case types.SpecStateTimedOut:
+		return TimedOut
+	case types.SpecStateSkipped:
+		return Skipped
+	case types.SpecStatePending:
+		return Pending
+	}
+	return "invalid"
+}
+
+func failure(s types.SpecState, f types.SpecFailure) *Failure {
+	if !s.IsFailure() {
+		return nil
+	}
+	return &Failure{Message: f.Message, Location: f.Location.String(), Panic: f.ForwardedPanic}
+}
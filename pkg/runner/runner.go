@@ -0,0 +1,306 @@
+// Package runner turns a run's configuration into the environment and Ginkgo flags of the compiled
+// suite, so that operators can describe a run in a config file instead of remembering both.
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/cassette"
+	"github.com/deis/workflow/_tests/pkg/cluster"
+	"github.com/deis/workflow/_tests/pkg/labels"
+	"github.com/deis/workflow/_tests/pkg/load"
+	"github.com/deis/workflow/_tests/pkg/schema"
+	"gopkg.in/yaml.v2"
+)
+
+// Targets a run can be pointed at.
+const (
+	// TargetCluster runs against the real cluster behind the configured router.
+	TargetCluster = "cluster"
+	// TargetLocal runs against local stand-ins for the cluster.
+	TargetLocal = "local"
+)
+
+// SuiteBinary is the name "ginkgo build" gives the compiled suite.
+const SuiteBinary = "tests.test"
+
+// prefixRegexp matches the prefixes which keep app names valid.
+var prefixRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// Config describes a run of the suite.
+type Config struct {
+	Target string `yaml:"target"`
+	Router struct {
+		Host string `yaml:"host"`
+		Port string `yaml:"port"`
+	} `yaml:"router"`
+	// Suite is the path of the compiled suite. By default it is looked up on $PATH, and then in
+	// the tests directory.
+	Suite string `yaml:"suite"`
+	// Dir is the directory to run the suite in, which holds its fixtures and quarantine list. By
+	// default it is the tests directory when the suite is found there, and the current one
+	// otherwise.
+	Dir string `yaml:"dir"`
+
+	// Focus and Skip are regular expressions matched against specs' full text, like ginkgo's
+	// -focus and -skip. Specs matching any Focus expression or any of Labels are run; specs
+	// matching any Skip expression or any of ExcludeLabels are not.
+	Focus         []string `yaml:"focus"`
+	Skip          []string `yaml:"skip"`
+	Labels        []string `yaml:"labels"`
+	ExcludeLabels []string `yaml:"exclude-labels"`
+
+	FlakeAttempts int    `yaml:"flake-attempts"`
+	Quarantine    bool   `yaml:"quarantine"`
+	ReportDir     string `yaml:"report-dir"`
+	ArtifactsDir  string `yaml:"artifacts-dir"`
+	Kubeconfig    string `yaml:"kubeconfig"`
+	// Safe runs in safe mode, for clusters that real people use: destructive specs are skipped,
+	// the admin account is never registered or cancelled, and the run aborts if the test user
+	// already owns apps the suite did not create. The admin's password must be given in Env.
+	Safe bool `yaml:"safe"`
+	// ResourcePrefix starts the names of the users, apps and keys the suite creates, "test-" by
+	// default.
+	ResourcePrefix string `yaml:"resource-prefix"`
+	// CassetteMode is "record" to record the traffic between the CLI and the controller into
+	// cassettes in CassetteDir, or "replay" to run from them without a cluster. Deploy and kube
+	// specs are excluded from both, since cassettes hold nothing of the builder or Kubernetes.
+	CassetteMode string `yaml:"cassette-mode"`
+	CassetteDir  string `yaml:"cassette-dir"`
+	// SchemaCheck is "warn" or "fail" to check every controller response against the schemas in
+	// SchemaDir, tests/schemas by default, and report or fail the specs seeing responses which
+	// differ. Deploy specs are excluded, since the CLI would look for the builder at the checker's
+	// address.
+	SchemaCheck string `yaml:"schema-check"`
+	SchemaDir   string `yaml:"schema-dir"`
+	// LoadUsers, if set, runs only the load spec, with this many virtual users starting LoadRate
+	// flows a second, or as many as they can if 0, for LoadDuration. LoadDriver is "cli", the
+	// default, to run the flows through the deis CLI, or "api" to make their requests directly.
+	LoadUsers    int     `yaml:"load-users"`
+	LoadRate     float64 `yaml:"load-rate"`
+	LoadDuration string  `yaml:"load-duration"`
+	LoadDriver   string  `yaml:"load-driver"`
+	// CLIVersion is the version "deis --version" is expected to print, such as the release the
+	// run installed.
+	CLIVersion string `yaml:"cli-version"`
+	// BuilderVersion is the version of the builder under test, checked against the compatibility
+	// matrix. The builder can't be asked for it.
+	BuilderVersion string `yaml:"builder-version"`
+	// Env holds any other environment variables to run the suite with.
+	Env map[string]string `yaml:"env"`
+}
+
+// LoadConfig reads the YAML config file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Config)
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Validate checks c for mistakes, and fills in defaults.
+func (c *Config) Validate() error {
+	switch c.Target {
+	case "":
+		c.Target = TargetCluster
+	case TargetCluster, TargetLocal:
+	default:
+		return fmt.Errorf("unknown target %q; use %q or %q", c.Target, TargetCluster, TargetLocal)
+	}
+	switch c.CassetteMode {
+	case "":
+	case cassette.Record, cassette.Replay:
+		if c.CassetteDir == "" {
+			return fmt.Errorf("cassette-mode %s needs a cassette-dir", c.CassetteMode)
+		}
+		for _, label := range []string{labels.Deploy, labels.Kube} {
+			if !contains(c.ExcludeLabels, label) {
+				c.ExcludeLabels = append(c.ExcludeLabels, label)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown cassette-mode %q; use %q or %q", c.CassetteMode, cassette.Record, cassette.Replay)
+	}
+	switch c.SchemaCheck {
+	case "":
+	case schema.Warn, schema.Fail:
+		if !contains(c.ExcludeLabels, labels.Deploy) {
+			c.ExcludeLabels = append(c.ExcludeLabels, labels.Deploy)
+		}
+	default:
+		return fmt.Errorf("unknown schema-check %q; use %q or %q", c.SchemaCheck, schema.Warn, schema.Fail)
+	}
+	if err := c.validateLoad(); err != nil {
+		return err
+	}
+	if c.Target == TargetCluster && c.Router.Host == "" && c.CassetteMode != cassette.Replay {
+		return fmt.Errorf("the cluster target needs the router's host, from the config file or %s", cluster.RouterHostEnv)
+	}
+	for _, label := range append(append([]string(nil), c.Labels...), c.ExcludeLabels...) {
+		if !labels.IsKnown(label) {
+			return fmt.Errorf("unknown label %q; the labels are %s", label, strings.Join(labels.Known, ", "))
+		}
+	}
+	if c.Target == TargetLocal && !contains(c.ExcludeLabels, labels.Deploy) {
+		// the local stand-ins build and run nothing
+		c.ExcludeLabels = append(c.ExcludeLabels, labels.Deploy)
+	}
+	if c.Safe {
+		if contains(c.Labels, labels.Destructive) {
+			return fmt.Errorf("safe mode never runs %s specs", labels.Destructive)
+		}
+		if c.Env["TEST_ADMIN_PASSWORD"] == "" && os.Getenv("TEST_ADMIN_PASSWORD") == "" {
+			return fmt.Errorf("safe mode needs the admin's real password in TEST_ADMIN_PASSWORD")
+		}
+		if !contains(c.ExcludeLabels, labels.Destructive) {
+			c.ExcludeLabels = append(c.ExcludeLabels, labels.Destructive)
+		}
+	}
+	if c.ResourcePrefix != "" && !prefixRegexp.MatchString(c.ResourcePrefix) {
+		return fmt.Errorf("resource-prefix %q must be lowercase letters, digits and dashes, starting with a letter", c.ResourcePrefix)
+	}
+	for _, expr := range append(append([]string(nil), c.Focus...), c.Skip...) {
+		if _, err := regexp.Compile(expr); err != nil {
+			return err
+		}
+	}
+	if c.FlakeAttempts < 0 {
+		return fmt.Errorf("flake-attempts must be at least 1")
+	}
+	return nil
+}
+
+func (c *Config) validateLoad() error {
+	if c.LoadUsers < 0 || c.LoadRate < 0 {
+		return fmt.Errorf("load-users and load-rate can't be negative")
+	}
+	if c.LoadUsers == 0 {
+		if c.LoadRate != 0 || c.LoadDuration != "" || c.LoadDriver != "" {
+			return fmt.Errorf("load-rate, load-duration and load-driver need load-users")
+		}
+		return nil
+	}
+	if c.LoadDuration != "" {
+		if _, err := time.ParseDuration(c.LoadDuration); err != nil {
+			return fmt.Errorf("load-duration: %v", err)
+		}
+	}
+	switch c.LoadDriver {
+	case "", load.DriverCLI, load.DriverAPI:
+	default:
+		return fmt.Errorf("unknown load-driver %q; use %q or %q", c.LoadDriver, load.DriverCLI, load.DriverAPI)
+	}
+	return nil
+}
+
+// Args returns the flags to run the suite with.
+func (c *Config) Args() []string {
+	args := []string{"-ginkgo.v", "-ginkgo.noColor"}
+	for _, expr := range c.Focus {
+		args = append(args, "-ginkgo.focus="+expr)
+	}
+	if len(c.Labels) > 0 {
+		args = append(args, "-ginkgo.focus="+labels.Regexp(c.Labels))
+	}
+	for _, expr := range c.Skip {
+		args = append(args, "-ginkgo.skip="+expr)
+	}
+	if len(c.ExcludeLabels) > 0 {
+		args = append(args, "-ginkgo.skip="+labels.Regexp(c.ExcludeLabels))
+	}
+	if c.FlakeAttempts > 1 {
+		args = append(args, "-ginkgo.flakeAttempts="+strconv.Itoa(c.FlakeAttempts))
+	}
+	return args
+}
+
+// Environ returns base with the environment variables c sets for the suite added. Paths are made
+// absolute, since the suite may run in another directory.
+func (c *Config) Environ(base []string) []string {
+	env := append([]string(nil), base...)
+	set := func(name, value string) {
+		if value != "" {
+			env = append(env, name+"="+value)
+		}
+	}
+	setPath := func(name, path string) {
+		if abs, err := filepath.Abs(path); err == nil && path != "" {
+			path = abs
+		}
+		set(name, path)
+	}
+	set(cluster.RouterHostEnv, c.Router.Host)
+	set(cluster.RouterPortEnv, c.Router.Port)
+	setPath("REPORT_DIR", c.ReportDir)
+	setPath("ARTIFACTS_DIR", c.ArtifactsDir)
+	setPath("KUBECONFIG", c.Kubeconfig)
+	if c.Quarantine {
+		set("QUARANTINE", "1")
+	}
+	if c.Safe {
+		set("SAFE_MODE", "1")
+	}
+	set("RESOURCE_PREFIX", c.ResourcePrefix)
+	set("CASSETTE_MODE", c.CassetteMode)
+	setPath("CASSETTE_DIR", c.CassetteDir)
+	set("SCHEMA_CHECK", c.SchemaCheck)
+	setPath("SCHEMA_DIR", c.SchemaDir)
+	if c.LoadUsers > 0 {
+		set("LOAD_USERS", strconv.Itoa(c.LoadUsers))
+		set("LOAD_RATE", strconv.FormatFloat(c.LoadRate, 'g', -1, 64))
+	}
+	set("LOAD_DURATION", c.LoadDuration)
+	set("LOAD_DRIVER", c.LoadDriver)
+	set("EXPECTED_CLI_VERSION", c.CLIVersion)
+	set("BUILDER_VERSION", c.BuilderVersion)
+	for name, value := range c.Env {
+		set(name, value)
+	}
+	return env
+}
+
+// Locate returns the path of the compiled suite, and the directory to run it in.
+func (c *Config) Locate() (path, dir string, err error) {
+	switch {
+	case c.Suite != "":
+		path, dir = c.Suite, c.Dir
+	case c.Dir != "":
+		path, err = exec.LookPath(SuiteBinary)
+		dir = c.Dir
+	default:
+		if path, err = exec.LookPath(SuiteBinary); err != nil {
+			dir = "tests"
+			path = filepath.Join(dir, SuiteBinary)
+			_, err = os.Stat(path)
+		}
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("could not find %s on $PATH or in tests/; build it with \"make build\"", SuiteBinary)
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", "", err
+	}
+	return path, dir, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "e2e.yaml")
+	err = ioutil.WriteFile(path, []byte(`
+router:
+  host: 192.0.2.10
+  port: "31182"
+labels: [smoke]
+exclude-labels: [destructive]
+skip: ["Healthcheck"]
+flake-attempts: 3
+report-dir: /var/reports
+artifacts-dir: _artifacts
+cli-version: v2.1.0
+builder-version: v2.1.1
+env:
+  SENSITIVE_CONFIG_KEYS: DATABASE_URL
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Target != TargetCluster {
+		t.Errorf("expected the cluster target by default, got %q", cfg.Target)
+	}
+	expectedArgs := []string{
+		"-ginkgo.v", "-ginkgo.noColor",
+		`-ginkgo.focus=\[(smoke)\]`,
+		"-ginkgo.skip=Healthcheck",
+		`-ginkgo.skip=\[(destructive)\]`,
+		"-ginkgo.flakeAttempts=3",
+	}
+	if args := cfg.Args(); !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %q, got %q", expectedArgs, args)
+	}
+	env := strings.Join(cfg.Environ([]string{"HOME=/root"}), " ")
+	artifacts, _ := filepath.Abs("_artifacts")
+	for _, expected := range []string{
+		"HOME=/root",
+		"DEIS_ROUTER_SERVICE_HOST=192.0.2.10",
+		"DEIS_ROUTER_SERVICE_PORT=31182",
+		"REPORT_DIR=/var/reports",
+		"ARTIFACTS_DIR=" + artifacts,
+		"SENSITIVE_CONFIG_KEYS=DATABASE_URL",
+		"EXPECTED_CLI_VERSION=v2.1.0",
+		"BUILDER_VERSION=v2.1.1",
+	} {
+		if !strings.Contains(env, expected) {
+			t.Errorf("expected %s in the environment, got %s", expected, env)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, cfg := range []Config{
+		{Target: "staging"},
+		{Target: TargetCluster},
+		{Target: TargetLocal, Labels: []string{"smoke-tests"}},
+		{Target: TargetLocal, Focus: []string{"("}},
+		{Target: TargetLocal, Safe: true},
+		{Target: TargetLocal, Safe: true, Labels: []string{"destructive"}, Env: map[string]string{"TEST_ADMIN_PASSWORD": "s3cret"}},
+		{Target: TargetLocal, ResourcePrefix: "E2E_"},
+		{Target: TargetLocal, CassetteMode: "replay"},
+		{Target: TargetLocal, CassetteMode: "rewind", CassetteDir: "cassettes"},
+		{Target: TargetLocal, SchemaCheck: "strict"},
+		{Target: TargetLocal, LoadDuration: "1m"},
+		{Target: TargetLocal, LoadUsers: 10, LoadDuration: "a minute"},
+		{Target: TargetLocal, LoadUsers: 10, LoadDriver: "grpc"},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", cfg)
+		}
+	}
+	local := Config{Target: TargetLocal}
+	if err := local.Validate(); err != nil {
+		t.Errorf("expected the local target to need no router, got %v", err)
+	}
+	if !reflect.DeepEqual(local.ExcludeLabels, []string{"deploy"}) {
+		t.Errorf("expected the local target to skip deploy specs, got %v", local.ExcludeLabels)
+	}
+}
+
+func TestSafe(t *testing.T) {
+	cfg := Config{Target: TargetLocal, Safe: true, ResourcePrefix: "e2e-", Env: map[string]string{"TEST_ADMIN_PASSWORD": "s3cret"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !contains(cfg.ExcludeLabels, "destructive") {
+		t.Errorf("expected safe mode to skip destructive specs, got %v", cfg.ExcludeLabels)
+	}
+	env := strings.Join(cfg.Environ(nil), "\n")
+	for _, expected := range []string{"SAFE_MODE=1", "RESOURCE_PREFIX=e2e-"} {
+		if !strings.Contains(env, expected) {
+			t.Errorf("expected %s in the environment, got %s", expected, env)
+		}
+	}
+}
+
+func TestCassettes(t *testing.T) {
+	cfg := Config{CassetteMode: "replay", CassetteDir: "cassettes"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected replays to need no router, got %v", err)
+	}
+	for _, label := range []string{"deploy", "kube"} {
+		if !contains(cfg.ExcludeLabels, label) {
+			t.Errorf("expected replays to skip %s specs, got %v", label, cfg.ExcludeLabels)
+		}
+	}
+	dir, _ := filepath.Abs("cassettes")
+	env := strings.Join(cfg.Environ(nil), "\n")
+	for _, expected := range []string{"CASSETTE_MODE=replay", "CASSETTE_DIR=" + dir} {
+		if !strings.Contains(env, expected) {
+			t.Errorf("expected %s in the environment, got %s", expected, env)
+		}
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	cfg := Config{Target: TargetCluster, SchemaCheck: "warn"}
+	cfg.Router.Host = "192.0.2.10"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !contains(cfg.ExcludeLabels, "deploy") {
+		t.Errorf("expected schema checks to skip deploy specs, got %v", cfg.ExcludeLabels)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cfg := Config{Target: TargetLocal, LoadUsers: 10, LoadRate: 2.5, LoadDuration: "30s", LoadDriver: "api"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	env := strings.Join(cfg.Environ(nil), "\n")
+	for _, expected := range []string{"LOAD_USERS=10", "LOAD_RATE=2.5", "LOAD_DURATION=30s", "LOAD_DRIVER=api"} {
+		if !strings.Contains(env, expected) {
+			t.Errorf("expected %s in the environment, got %s", expected, env)
+		}
+	}
+}
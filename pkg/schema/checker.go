@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+)
+
+// What a run does about responses which differ from their schemas.
+const (
+	// Warn reports the differences.
+	Warn = "warn"
+	// Fail fails the spec which saw them.
+	Fail = "fail"
+)
+
+// Checker is an http.Handler which passes requests on, checking the JSON of each response against
+// a Set as it goes.
+type Checker struct {
+	set  Set
+	next http.Handler
+
+	mu       sync.Mutex
+	problems map[string]bool
+	unknown  map[string]bool
+}
+
+// NewChecker returns a Checker passing requests to next, such as a reverse proxy to the controller.
+func NewChecker(set Set, next http.Handler) *Checker {
+	return &Checker{set: set, next: next, problems: make(map[string]bool), unknown: make(map[string]bool)}
+}
+
+// ServeHTTP implements http.Handler.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	recorder := httptest.NewRecorder()
+	c.next.ServeHTTP(recorder, r)
+	for key, values := range recorder.Header() {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(recorder.Code)
+	w.Write(recorder.Body.Bytes())
+
+	problems, known := c.set.Check(r.Method, r.URL.RequestURI(), recorder.Code, recorder.Body.Bytes())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, problem := range problems {
+		c.problems[problem] = true
+	}
+	if !known {
+		c.unknown[fmt.Sprintf("%s %d", Endpoint(r.Method, r.URL.Path), recorder.Code)] = true
+	}
+}
+
+// Take returns, sorted, the problems found and the endpoints without schemas seen since the last
+// call.
+func (c *Checker) Take() (problems, unknown []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	problems, unknown = keys(c.problems), keys(c.unknown)
+	c.problems, c.unknown = make(map[string]bool), make(map[string]bool)
+	return problems, unknown
+}
+
+func keys(m map[string]bool) []string {
+	var result []string
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,277 @@
+// Package schema describes the JSON the controller answers with, endpoint by endpoint, and checks
+// responses against those descriptions, so that fields which appear, disappear or change type are
+// noticed before they break the tools consuming the API.
+//
+// Schemas are a subset of JSON Schema: type, properties, required, items and additionalProperties.
+// They are inferred from recorded responses rather than written by hand.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JSON types.
+const (
+	Object  = "object"
+	Array   = "array"
+	String  = "string"
+	Number  = "number"
+	Boolean = "boolean"
+	Null    = "null"
+)
+
+// MapFields are the fields whose objects are keyed by user data, such as config values keyed by
+// the config's names, rather than having fixed properties.
+var MapFields = []string{"values", "structure", "procfile", "memory", "cpu", "tags", "registry", "healthcheck"}
+
+// Schema describes a JSON value. An empty Schema allows any value.
+type Schema struct {
+	// Type lists the JSON types allowed, such as "string" and "null" for an optional string.
+	Type       []string           `json:"type,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// AdditionalProperties forbids any property but Properties, or describes the values of
+	// objects used as maps. Objects may have any properties when it is nil.
+	AdditionalProperties *Additional `json:"additionalProperties,omitempty"`
+	Items                *Schema     `json:"items,omitempty"`
+}
+
+// Additional is the value of additionalProperties: false when Schema is nil, and Schema otherwise.
+type Additional struct {
+	Schema *Schema
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a *Additional) MarshalJSON() ([]byte, error) {
+	if a.Schema == nil {
+		return []byte("false"), nil
+	}
+	return json.Marshal(a.Schema)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Additional) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data)) {
+	case "false":
+		a.Schema = nil
+		return nil
+	case "true":
+		a.Schema = &Schema{}
+		return nil
+	}
+	a.Schema = new(Schema)
+	return json.Unmarshal(data, a.Schema)
+}
+
+// Infer returns the schema of v, a value decoded from JSON.
+func Infer(v interface{}) *Schema {
+	return infer(v, false)
+}
+
+// infer returns the schema of v, treating it as a map if asMap is set.
+func infer(v interface{}, asMap bool) *Schema {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		s := &Schema{Type: []string{Object}}
+		if asMap {
+			// an empty map says nothing of its values, so leaves them to be merged from others
+			var values *Schema
+			for _, key := range sortedKeys(v) {
+				values = Merge(values, infer(v[key], false))
+			}
+			if values != nil {
+				s.AdditionalProperties = &Additional{Schema: values}
+			}
+			return s
+		}
+		s.Properties = make(map[string]*Schema, len(v))
+		s.AdditionalProperties = &Additional{}
+		for key, value := range v {
+			s.Properties[key] = infer(value, isMapField(key))
+			s.Required = append(s.Required, key)
+		}
+		sort.Strings(s.Required)
+		return s
+	case []interface{}:
+		s := &Schema{Type: []string{Array}}
+		for _, item := range v {
+			s.Items = Merge(s.Items, infer(item, false))
+		}
+		return s
+	default:
+		return &Schema{Type: []string{typeOf(v)}}
+	}
+}
+
+// Merge returns a schema allowing what a and b both describe: the union of their types and
+// properties, requiring only the properties both require.
+func Merge(a, b *Schema) *Schema {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case len(a.Type) == 0 || len(b.Type) == 0:
+		// one allows anything already
+		return &Schema{}
+	}
+	m := &Schema{Type: union(a.Type, b.Type), Items: Merge(a.Items, b.Items)}
+	if a.Properties != nil || b.Properties != nil {
+		m.Properties = make(map[string]*Schema)
+		for key, s := range a.Properties {
+			m.Properties[key] = s
+		}
+		for key, s := range b.Properties {
+			m.Properties[key] = Merge(m.Properties[key], s)
+		}
+		// an object which is sometimes absent is only required when both require it; a null
+		// answer, such as to a request for a missing object, has no properties to require
+		switch {
+		case !contains(a.Type, Object):
+			m.Required = b.Required
+		case !contains(b.Type, Object):
+			m.Required = a.Required
+		default:
+			m.Required = intersection(a.Required, b.Required)
+		}
+	}
+	switch {
+	case a.AdditionalProperties == nil:
+		m.AdditionalProperties = b.AdditionalProperties
+	case b.AdditionalProperties == nil:
+		m.AdditionalProperties = a.AdditionalProperties
+	default:
+		m.AdditionalProperties = &Additional{Schema: Merge(a.AdditionalProperties.Schema, b.AdditionalProperties.Schema)}
+	}
+	return m
+}
+
+// Validate checks v against s and describes every way it differs, naming fields by their path,
+// such as "results[].owner".
+func (s *Schema) Validate(v interface{}) []string {
+	var problems []string
+	s.validate("", v, &problems)
+	return problems
+}
+
+func (s *Schema) validate(path string, v interface{}, problems *[]string) {
+	if len(s.Type) == 0 {
+		return
+	}
+	name := path
+	if name == "" {
+		name = "the body"
+	}
+	t := typeOf(v)
+	if !contains(s.Type, t) {
+		*problems = append(*problems, fmt.Sprintf("%s is %s, expected %s", name, t, strings.Join(s.Type, " or ")))
+		return
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s is missing", join(path, key)))
+			}
+		}
+		for _, key := range sortedKeys(v) {
+			if property := s.Properties[key]; property != nil {
+				property.validate(join(path, key), v[key], problems)
+				continue
+			}
+			switch {
+			case s.AdditionalProperties == nil:
+			case s.AdditionalProperties.Schema == nil:
+				*problems = append(*problems, fmt.Sprintf("%s is unexpected", join(path, key)))
+			default:
+				s.AdditionalProperties.Schema.validate(join(path, key), v[key], problems)
+			}
+		}
+	case []interface{}:
+		if s.Items == nil {
+			return
+		}
+		// report each problem once, however many items have it
+		seen := make(map[string]bool)
+		for _, item := range v {
+			var itemProblems []string
+			s.Items.validate(path+"[]", item, &itemProblems)
+			for _, problem := range itemProblems {
+				if !seen[problem] {
+					seen[problem] = true
+					*problems = append(*problems, problem)
+				}
+			}
+		}
+	}
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return Object
+	case []interface{}:
+		return Array
+	case string:
+		return String
+	case float64, json.Number:
+		return Number
+	case bool:
+		return Boolean
+	default:
+		return Null
+	}
+}
+
+func isMapField(key string) bool {
+	return contains(MapFields, key)
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func union(a, b []string) []string {
+	result := append([]string(nil), a...)
+	for _, s := range b {
+		if !contains(result, s) {
+			result = append(result, s)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func intersection(a, b []string) []string {
+	var result []string
+	for _, s := range a {
+		if contains(b, s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
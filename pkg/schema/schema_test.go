@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeJSON(t *testing.T, body string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestInferAndValidate(t *testing.T) {
+	s := Merge(
+		Infer(decodeJSON(t, `{"id": "a", "build": null, "values": {}, "results": [{"n": 1}]}`)),
+		Infer(decodeJSON(t, `{"id": "b", "build": "x", "values": {"FOO": "bar"}, "results": [], "extra": true}`)),
+	)
+	if !reflect.DeepEqual(s.Required, []string{"build", "id", "results", "values"}) {
+		t.Errorf("expected only the fields both have to be required, got %v", s.Required)
+	}
+	if !reflect.DeepEqual(s.Properties["build"].Type, []string{Null, String}) {
+		t.Errorf("expected build to be an optional string, got %v", s.Properties["build"].Type)
+	}
+
+	for body, expected := range map[string][]string{
+		`{"id": "c", "build": null, "values": {"A": "1", "B": "2"}, "results": [{"n": 2}]}`: nil,
+		`{"build": "x", "values": {}, "results": [], "owner": "me"}`:                        {"id is missing", "owner is unexpected"},
+		`{"id": 1, "build": null, "values": {"A": 1}, "results": [{"n": "2"}, {"n": "3"}]}`: {"id is number, expected string", "results[].n is string, expected number", "values.A is number, expected string"},
+		`[]`: {"the body is array, expected object"},
+	} {
+		if problems := s.Validate(decodeJSON(t, body)); !reflect.DeepEqual(problems, expected) {
+			t.Errorf("%s: expected %v, got %v", body, expected, problems)
+		}
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/v2/apps/":                           "GET /v2/apps/",
+		"/v2/apps/test-123/config/?limit=10":  "GET /v2/apps/{app}/config/",
+		"/v2/apps/test-123/releases/v4/":      "GET /v2/apps/{app}/releases/{version}/",
+		"/v2/apps/test-123/perms/test-456/":   "GET /v2/apps/{app}/perms/{username}/",
+		"/v2/apps/test-123/domains/a.b.co/":   "GET /v2/apps/{app}/domains/{domain}/",
+		"/v2/keys/test-key-1/":                "GET /v2/keys/{key}/",
+		"/v2/auth/whoami/":                    "GET /v2/auth/whoami/",
+		"/v2/certs/test-cert/domain/a.b.co/":  "GET /v2/certs/{cert}/domain/{domain}/",
+		"/v2/apps/test-123/pods/web/restart/": "GET /v2/apps/{app}/pods/{type}/restart/",
+	} {
+		if actual := Endpoint("GET", path); actual != expected {
+			t.Errorf("%s: expected %s, got %s", path, expected, actual)
+		}
+	}
+}
+
+func TestSetAndChecker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recorded := make(Set)
+	recorded.Add("GET", "/v2/apps/test-1/", 200, []byte(`{"id": "test-1", "owner": "a"}`))
+	recorded.Add("GET", "/v2/apps/test-1/", 404, []byte(`{"detail": "Not found."}`))
+	recorded.Add("DELETE", "/v2/apps/test-1/", 204, nil)
+	if err := recorded.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	set, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoints := set.Endpoints(); !reflect.DeepEqual(endpoints, []string{"GET /v2/apps/{app}/"}) {
+		t.Fatalf("expected the one endpoint with a body, got %v", endpoints)
+	}
+
+	body := `{"id": "test-2", "owner": "b", "structure": {}}`
+	server := httptest.NewServer(NewChecker(set, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	})))
+	defer server.Close()
+	for _, path := range []string{"/v2/apps/test-2/", "/v2/apps/test-3/", "/v2/keys/"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(data) != body {
+			t.Errorf("expected the response to pass through, got %s", data)
+		}
+	}
+	problems, unknown := server.Config.Handler.(*Checker).Take()
+	if len(problems) != 1 || !strings.Contains(problems[0], "GET /v2/apps/{app}/ 200: structure is unexpected") {
+		t.Errorf("expected the new field to be reported once, got %v", problems)
+	}
+	if !reflect.DeepEqual(unknown, []string{"GET /v2/keys/ 200"}) {
+		t.Errorf("expected the endpoint without a schema to be noted, got %v", unknown)
+	}
+	if problems, unknown := server.Config.Handler.(*Checker).Take(); len(problems)+len(unknown) > 0 {
+		t.Errorf("expected Take to start afresh, got %v and %v", problems, unknown)
+	}
+}
@@ -0,0 +1,170 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// routeWords are the fixed segments of the controller's paths, after the API version. Any other
+// segment is a name, such as an app's, and is replaced by a placeholder.
+var routeWords = map[string]bool{
+	"auth": true, "register": true, "login": true, "logout": true, "whoami": true,
+	"cancel": true, "tokens": true, "passwd": true, "users": true, "admin": true, "apps": true,
+	"config": true, "releases": true, "rollback": true, "builds": true, "perms": true, "keys": true,
+	"domains": true, "certs": true, "domain": true, "limits": true, "pods": true, "scale": true,
+	"logs": true, "run": true, "settings": true, "whitelist": true, "hooks": true, "push": true,
+	"build": true, "restart": true,
+}
+
+// placeholders name the placeholder of a segment by the collection it follows.
+var placeholders = map[string]string{
+	"apps":     "{app}",
+	"releases": "{version}",
+	"keys":     "{key}",
+	"perms":    "{username}",
+	"users":    "{username}",
+	"domains":  "{domain}",
+	"certs":    "{cert}",
+	"domain":   "{domain}",
+	"pods":     "{type}",
+}
+
+// Endpoint names the endpoint a request was made to, such as "GET /v2/apps/{app}/config/" for
+// "GET /v2/apps/test-123/config/?limit=10".
+func Endpoint(method, path string) string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		// the first segment is the API version, such as v2, which releases' names look like
+		if i < 2 || segment == "" || routeWords[segment] {
+			continue
+		}
+		placeholder := "{id}"
+		if i > 0 && placeholders[segments[i-1]] != "" {
+			placeholder = placeholders[segments[i-1]]
+		}
+		segments[i] = placeholder
+	}
+	return method + " " + strings.Join(segments, "/")
+}
+
+// File is the schemas of one endpoint's responses, keyed by status code.
+type File struct {
+	Endpoint  string             `json:"endpoint"`
+	Responses map[string]*Schema `json:"responses"`
+}
+
+// Set holds the schemas of every endpoint, keyed by endpoint.
+type Set map[string]*File
+
+var unsafeRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FileName returns the name of the file endpoint's schemas are kept in.
+func FileName(endpoint string) string {
+	return strings.Trim(unsafeRegexp.ReplaceAllString(strings.ToLower(endpoint), "-"), "-") + ".json"
+}
+
+// Load reads every endpoint's schemas from dir. A missing dir holds no schemas.
+func Load(dir string) (Set, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	set := make(Set)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		f := new(File)
+		if err := json.Unmarshal(data, f); err != nil {
+			return nil, fmt.Errorf("reading %s: %v", path, err)
+		}
+		if f.Endpoint == "" {
+			return nil, fmt.Errorf("%s names no endpoint", path)
+		}
+		set[f.Endpoint] = f
+	}
+	return set, nil
+}
+
+// Save writes every endpoint's schemas to dir, one file each.
+func (s Set) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for endpoint, f := range s {
+		data, err := json.MarshalIndent(f, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, FileName(endpoint)), append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Endpoints returns the endpoints with schemas, sorted.
+func (s Set) Endpoints() []string {
+	endpoints := make([]string, 0, len(s))
+	for endpoint := range s {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// Add merges the schema of a response into the set. Responses without a JSON body are ignored.
+func (s Set) Add(method, path string, status int, body []byte) {
+	v, ok := decode(body)
+	if !ok {
+		return
+	}
+	endpoint := Endpoint(method, path)
+	f := s[endpoint]
+	if f == nil {
+		f = &File{Endpoint: endpoint, Responses: make(map[string]*Schema)}
+		s[endpoint] = f
+	}
+	code := strconv.Itoa(status)
+	f.Responses[code] = Merge(f.Responses[code], Infer(v))
+}
+
+// Check describes how a response differs from its schema. known is false if the set has no
+// schema for the endpoint and status, which is worth a mention but isn't drift in itself.
+func (s Set) Check(method, path string, status int, body []byte) (problems []string, known bool) {
+	v, ok := decode(body)
+	if !ok {
+		return nil, true
+	}
+	endpoint := Endpoint(method, path)
+	f := s[endpoint]
+	if f == nil || f.Responses[strconv.Itoa(status)] == nil {
+		return nil, false
+	}
+	for _, problem := range f.Responses[strconv.Itoa(status)].Validate(v) {
+		problems = append(problems, fmt.Sprintf("%s %d: %s", endpoint, status, problem))
+	}
+	return problems, true
+}
+
+func decode(body []byte) (interface{}, bool) {
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil, false
+	}
+	var v interface{}
+	if json.Unmarshal(body, &v) != nil {
+		return nil, false
+	}
+	return v, true
+}
@@ -0,0 +1,77 @@
+// Package settings reads, writes and checks the client settings file which the deis CLI writes
+// under $HOME/.deis when a user logs in.
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Settings is the content of a client settings file.
+type Settings struct {
+	Username   string `json:"username"`
+	SslVerify  bool   `json:"ssl_verify"`
+	Controller string `json:"controller"`
+	Token      string `json:"token"`
+	Limit      int    `json:"response_limit"`
+}
+
+// Path returns the path of the settings file for profile under home. The default profile, "",
+// is stored in client.json.
+func Path(home, profile string) string {
+	if profile == "" {
+		profile = "client"
+	}
+	return filepath.Join(home, ".deis", profile+".json")
+}
+
+// Load reads the settings file at path.
+func Load(path string) (*Settings, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := new(Settings)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Save writes s to path as the CLI would, readable only by its owner since it holds a token.
+func Save(s *Settings, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Verify checks that s holds a login as username to controller: the controller's URL, the
+// username and a well-formed token. The token itself is never part of the error.
+func (s *Settings) Verify(controller, username string) error {
+	var problems []string
+	if strings.TrimRight(s.Controller, "/") != strings.TrimRight(controller, "/") {
+		problems = append(problems, fmt.Sprintf("the controller is %q, not %q", s.Controller, controller))
+	}
+	if s.Username != username {
+		problems = append(problems, fmt.Sprintf("the username is %q, not %q", s.Username, username))
+	}
+	if s.Token == "" {
+		problems = append(problems, "there is no token")
+	} else if strings.IndexFunc(s.Token, func(r rune) bool { return r <= ' ' || r > '~' }) >= 0 {
+		problems = append(problems, "the token holds whitespace or unprintable characters")
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package settings
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	home, err := ioutil.TempDir("", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	path := Path(home, "staging")
+	if path != filepath.Join(home, ".deis", "staging.json") {
+		t.Errorf("expected the staging profile under .deis, got %s", path)
+	}
+	s := &Settings{Username: "alice", Controller: "http://deis.example.com", Token: "abc123", Limit: 100}
+	if err := Save(s, path); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the settings to be readable only by their owner, got %v, %v", info, err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loaded != *s {
+		t.Errorf("expected %+v, got %+v", s, loaded)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	s := &Settings{Username: "alice", Controller: "http://deis.example.com/", Token: "abc123"}
+	if err := s.Verify("http://deis.example.com", "alice"); err != nil {
+		t.Errorf("expected the settings to be verified, got %v", err)
+	}
+	err := s.Verify("http://prod.example.com", "bob")
+	if err == nil || !strings.Contains(err.Error(), "prod.example.com") || !strings.Contains(err.Error(), `"bob"`) {
+		t.Errorf("expected the wrong controller and username, got %v", err)
+	}
+	for _, token := range []string{"", "abc 123", "abc123\n"} {
+		s.Token = token
+		if err := s.Verify("http://deis.example.com", "alice"); err == nil {
+			t.Errorf("expected the token %q to be refused", token)
+		} else if token != "" && strings.Contains(err.Error(), token) {
+			t.Errorf("expected the token to be kept out of %v", err)
+		}
+	}
+}
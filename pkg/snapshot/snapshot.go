@@ -0,0 +1,123 @@
+// Package snapshot keeps golden copies of command output, such as the CLI's help, and shows how
+// the output has changed since.
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Path returns the file in dir holding the snapshot called name. The ":" of a command such as
+// apps:create, which doesn't belong in a file name, becomes "_".
+func Path(dir, name string) string {
+	return filepath.Join(dir, strings.Replace(name, ":", "_", -1)+".txt")
+}
+
+// Names returns the names of the snapshots in dir, sorted.
+func Names(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = strings.Replace(strings.TrimSuffix(filepath.Base(path), ".txt"), "_", ":", -1)
+	}
+	return names, nil
+}
+
+// Normalize strips the trailing whitespace from each line of output, and ends it with exactly one
+// newline, so that snapshots don't change with invisible differences.
+func Normalize(output string) string {
+	lines := strings.Split(strings.TrimRight(output, " \t\r\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Load reads the snapshot at path. It returns an error satisfying os.IsNotExist if there is none.
+func Load(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	return string(data), err
+}
+
+// Save writes output, normalized, as the snapshot at path.
+func Save(path, output string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(Normalize(output)), 0644)
+}
+
+// Diff compares output, normalized, with the snapshot golden, and returns their differences line
+// by line, marked "-" for lines only in golden and "+" for lines only in output, with a line of
+// context either side. It returns "" if they are the same.
+func Diff(golden, output string) string {
+	output = Normalize(output)
+	if golden == output {
+		return ""
+	}
+	a := strings.Split(strings.TrimSuffix(golden, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	return context(lines)
+}
+
+// context keeps the changed lines, and one unchanged line either side of each run of them.
+func context(lines []string) string {
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "  ") {
+			for j := i - 1; j <= i+1; j++ {
+				if j >= 0 && j < len(lines) {
+					keep[j] = true
+				}
+			}
+		}
+	}
+	var kept []string
+	for i, line := range lines {
+		if keep[i] {
+			kept = append(kept, line)
+		} else if i > 0 && keep[i-1] {
+			kept = append(kept, "  ...")
+		}
+	}
+	return strings.Join(kept, "\n") + "\n"
+}
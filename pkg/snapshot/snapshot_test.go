@@ -0,0 +1,59 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	if got := Path("help", "apps:create"); got != "help/apps_create.txt" {
+		t.Errorf("expected help/apps_create.txt, got %s", got)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := Path(dir+"/help", "apps:list")
+	if names, err := Names(dir + "/help"); err != nil || len(names) != 0 {
+		t.Errorf("expected no snapshots, got %q, %v", names, err)
+	}
+	if _, err := Load(path); !os.IsNotExist(err) {
+		t.Errorf("expected a missing snapshot, got %v", err)
+	}
+	if err := Save(path, "Usage: deis apps:list   \r\n\n  -l --limit=<num>\n\n\n"); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := Names(dir + "/help"); err != nil || len(names) != 1 || names[0] != "apps:list" {
+		t.Errorf("expected the apps:list snapshot, got %q, %v", names, err)
+	}
+	golden, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if golden != "Usage: deis apps:list\n\n  -l --limit=<num>\n" {
+		t.Errorf("expected the snapshot to be normalized, got %q", golden)
+	}
+	if diff := Diff(golden, "Usage: deis apps:list\n\n  -l --limit=<num>  \n"); diff != "" {
+		t.Errorf("expected no differences, got:\n%s", diff)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	golden := "Usage: deis ps:scale\n\nOptions:\n  -a --app=<app>\n    the app.\n  -x --extra\n    removed.\n"
+	output := "Usage: deis ps:scale\n\nOptions:\n  -a --application=<app>\n    the app.\n  -x --extra\n"
+	expected := "  Options:\n" +
+		"- " + "  -a --app=<app>\n" +
+		"+ " + "  -a --application=<app>\n" +
+		"      the app.\n" +
+		"    -x --extra\n" +
+		"-     removed.\n"
+	if diff := Diff(golden, output); diff != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, diff)
+	}
+}
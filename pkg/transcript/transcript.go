@@ -0,0 +1,152 @@
+// Package transcript records the commands a spec runs, along with how they exited, how long they
+// took and what they printed.
+package transcript
+
+import (
+	"bytes"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Command is one command run during a spec.
+type Command struct {
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+	// Duration is in seconds.
+	Duration float64 `json:"duration"`
+	// ExitCode is -1 if the command was still running when the transcript was taken, or could
+	// not be started at all.
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// Contents is implemented by buffers capturing a command's output, such as the gbytes.Buffers of
+// a gexec.Session.
+type Contents interface {
+	Contents() []byte
+}
+
+// Buffer is a Contents which may be written to from several goroutines at once.
+type Buffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write appends p to the buffer.
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Contents returns a copy of everything written so far.
+func (b *Buffer) Contents() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+// Entry is a command which has been recorded as started.
+type Entry struct {
+	recorder       *Recorder
+	command        string
+	started        time.Time
+	ended          time.Time
+	exitCode       int
+	stdout, stderr Contents
+}
+
+// End records that the command exited with exitCode.
+func (e *Entry) End(exitCode int) {
+	if e == nil {
+		return
+	}
+	e.recorder.mu.Lock()
+	defer e.recorder.mu.Unlock()
+	e.ended = time.Now()
+	e.exitCode = exitCode
+}
+
+// Recorder collects commands until they are flushed. It is safe for concurrent use.
+//
+// A nil *Recorder records nothing, so callers need not check whether recording is enabled.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Begin records that command has started. Its output is read from stdout and stderr, either of
+// which may be nil, when the transcript is flushed.
+func (r *Recorder) Begin(command string, stdout, stderr Contents) *Entry {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := &Entry{
+		recorder: r,
+		command:  command,
+		started:  time.Now(),
+		exitCode: -1,
+		stdout:   stdout,
+		stderr:   stderr,
+	}
+	r.entries = append(r.entries, entry)
+	return entry
+}
+
+// Flush returns the commands begun since the last Flush, in the order they were started.
+func (r *Recorder) Flush() []Command {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	commands := make([]Command, 0, len(r.entries))
+	now := time.Now()
+	for _, entry := range r.entries {
+		ended := entry.ended
+		if ended.IsZero() {
+			ended = now
+		}
+		commands = append(commands, Command{
+			Command:  entry.command,
+			Started:  entry.started,
+			Duration: ended.Sub(entry.started).Seconds(),
+			ExitCode: entry.exitCode,
+			Stdout:   contents(entry.stdout),
+			Stderr:   contents(entry.stderr),
+		})
+	}
+	r.entries = nil
+	return commands
+}
+
+func contents(c Contents) string {
+	if c == nil {
+		return ""
+	}
+	return string(c.Contents())
+}
+
+// ExitCode returns the exit code of a command given the error returned by running it: 0 if err
+// is nil, the process's exit status if it ran, and -1 if it could not be run.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus()
+		}
+	}
+	return -1
+}
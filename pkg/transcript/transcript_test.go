@@ -0,0 +1,52 @@
+package transcript
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestRecorderFlush(t *testing.T) {
+	recorder := NewRecorder()
+	var stdout, stderr Buffer
+
+	finished := recorder.Begin("deis apps:create foo", &stdout, &stderr)
+	stdout.Write([]byte("Creating Application... done, created foo\n"))
+	stderr.Write([]byte("warning\n"))
+	finished.End(0)
+	recorder.Begin("deis logs", nil, nil)
+
+	commands := recorder.Flush()
+	if len(commands) != 2 {
+		t.Fatalf("expected 2 commands, got %d", len(commands))
+	}
+	if c := commands[0]; c.Command != "deis apps:create foo" || c.ExitCode != 0 ||
+		c.Stdout != "Creating Application... done, created foo\n" || c.Stderr != "warning\n" {
+		t.Errorf("unexpected finished command %+v", c)
+	}
+	if c := commands[1]; c.ExitCode != -1 || c.Stdout != "" {
+		t.Errorf("expected a running command with no output, got %+v", c)
+	}
+	if commands := recorder.Flush(); len(commands) != 0 {
+		t.Errorf("expected flushing to clear the recorder, got %v", commands)
+	}
+}
+
+func TestNilRecorder(t *testing.T) {
+	var recorder *Recorder
+	recorder.Begin("deis apps", nil, nil).End(0)
+	if commands := recorder.Flush(); commands != nil {
+		t.Errorf("expected a nil recorder to record nothing, got %v", commands)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(nil); code != 0 {
+		t.Errorf("expected 0 for no error, got %d", code)
+	}
+	if code := ExitCode(exec.Command("/bin/sh", "-c", "exit 3").Run()); code != 3 {
+		t.Errorf("expected 3, got %d", code)
+	}
+	if code := ExitCode(exec.Command("/nonexistent/command").Run()); code != -1 {
+		t.Errorf("expected -1 for a command which could not start, got %d", code)
+	}
+}
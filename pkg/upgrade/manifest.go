@@ -0,0 +1,120 @@
+// Package upgrade describes the state the suite seeds a cluster with before a platform upgrade, so
+// that a later run can verify the state survived it.
+package upgrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Version is the manifest format this package writes. Bump it when the format changes in a way
+// older readers would misunderstand, and teach Load to read the older formats.
+const Version = 1
+
+// Manifest is what the seed phase created, and what the verify phase expects to find.
+type Manifest struct {
+	Version int       `json:"version"`
+	Seeded  time.Time `json:"seeded"`
+	// Versions are the versions of the components the state was seeded on.
+	Versions map[string]string `json:"versions,omitempty"`
+	Users    []User            `json:"users"`
+	Apps     []App             `json:"apps"`
+}
+
+// User is a seeded user.
+type User struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+	// Keys are the user's public keys, keyed by ID.
+	Keys map[string]string `json:"keys,omitempty"`
+}
+
+// App is a seeded app, as its owner sees it through the CLI.
+type App struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+	// Releases are the app's release versions, newest first.
+	Releases []int             `json:"releases"`
+	Config   map[string]string `json:"config,omitempty"`
+	Domains  []string          `json:"domains,omitempty"`
+	// Perms are the users other than the owner who may use the app.
+	Perms  []string          `json:"perms,omitempty"`
+	Limits map[string]string `json:"limits,omitempty"`
+	// Body is what the app serves at its root, if it was deployed.
+	Body string `json:"body,omitempty"`
+}
+
+// Load reads the manifest at path.
+func Load(path string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := new(Manifest)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	switch {
+	case m.Version == 0:
+		return nil, fmt.Errorf("%s has no format version, so it is not an upgrade manifest", path)
+	case m.Version > Version:
+		return nil, fmt.Errorf("%s has format version %d, but this suite only reads up to %d; verify with the suite that seeded it", path, m.Version, Version)
+	}
+	return m, nil
+}
+
+// Save writes m to path, readable only by its owner since it holds the seeded users' passwords.
+func Save(m *Manifest, path string) error {
+	m.Version = Version
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// User returns the seeded user with the given name.
+func (m *Manifest) User(username string) (User, bool) {
+	for _, u := range m.Users {
+		if u.Username == username {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// Diff describes how got differs from want, one difference per line. The order of domains and
+// perms does not matter.
+func Diff(want, got App) []string {
+	var diffs []string
+	check := func(what string, expected, actual interface{}) {
+		if !reflect.DeepEqual(expected, actual) {
+			diffs = append(diffs, fmt.Sprintf("%s's %s: expected %v, got %v", want.Name, what, expected, actual))
+		}
+	}
+	check("releases", want.Releases, got.Releases)
+	check("config", nonNil(want.Config), nonNil(got.Config))
+	check("domains", sorted(want.Domains), sorted(got.Domains))
+	check("perms", sorted(want.Perms), sorted(got.Perms))
+	check("limits", nonNil(want.Limits), nonNil(got.Limits))
+	check("body", want.Body, got.Body)
+	return diffs
+}
+
+func sorted(list []string) []string {
+	result := append([]string{}, list...)
+	sort.Strings(result)
+	return result
+}
+
+func nonNil(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
@@ -0,0 +1,73 @@
+package upgrade
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "manifest.json")
+
+	m := &Manifest{
+		Users: []User{{Username: "test-1-owner", Password: "asdf1234", Keys: map[string]string{"test-1-key": "ssh-rsa AAAA"}}},
+		Apps:  []App{{Name: "test-2", Owner: "test-1-owner", Releases: []int{3, 2, 1}, Body: "Powered by Deis\n"}},
+	}
+	if err := Save(m, path); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the manifest to be private, got %v, %v", info.Mode(), err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Version != Version || !reflect.DeepEqual(loaded.Apps, m.Apps) {
+		t.Errorf("expected %+v back, got %+v", m, loaded)
+	}
+	if u, ok := loaded.User("test-1-owner"); !ok || u.Keys["test-1-key"] != "ssh-rsa AAAA" {
+		t.Errorf("expected to find the owner and their key, got %+v", u)
+	}
+
+	for contents, message := range map[string]string{
+		`{"apps": []}`:                "no format version",
+		`{"version": 99, "apps": []}`: "only reads up to",
+	} {
+		ioutil.WriteFile(path, []byte(contents), 0600)
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("loading %s: expected an error about %q, got %v", contents, message, err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	want := App{
+		Name:     "test-2",
+		Releases: []int{3, 2, 1},
+		Config:   map[string]string{"POWERED_BY": "upgrades"},
+		Domains:  []string{"test-2", "test-2.example.com"},
+		Body:     "Powered by upgrades\n",
+	}
+	got := want
+	got.Domains = []string{"test-2.example.com", "test-2"}
+	got.Limits = map[string]string{}
+	if diffs := Diff(want, got); len(diffs) != 0 {
+		t.Errorf("expected no differences, got %v", diffs)
+	}
+
+	got.Releases = []int{1}
+	got.Config = nil
+	diffs := Diff(want, got)
+	if len(diffs) != 2 || !strings.HasPrefix(diffs[0], "test-2's releases: ") || !strings.HasPrefix(diffs[1], "test-2's config: ") {
+		t.Errorf("expected the releases and config to differ, got %v", diffs)
+	}
+}
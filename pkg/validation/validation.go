@@ -0,0 +1,74 @@
+// Package validation models which app names and config the platform accepts, and what
+// "deis config:list" shows of the config it accepted. The property specs hold the CLI and
+// controller to this model, and the fake controller enforces it.
+//
+// The model:
+//
+//   - An app name is 1 to 63 lowercase letters, digits and hyphens, neither starting nor ending
+//     with a hyphen nor holding two in a row, since it names the app's Kubernetes namespace. "deis"
+//     is reserved.
+//   - A config key starts with a letter or underscore, followed by letters, digits and
+//     underscores. Any case is kept as given.
+//   - A config value is any non-empty string.
+//   - "deis config:list" shows a value without its leading and trailing whitespace. A value
+//     holding a line break can't be told apart from the lines after it, so isn't shown reliably.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxAppNameLength is the longest app name, that of a Kubernetes namespace.
+const MaxAppNameLength = 63
+
+// ReservedAppNames can't be given to apps.
+var ReservedAppNames = []string{"deis"}
+
+var (
+	appNameRegex   = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	configKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// AppName returns why name can't be given to an app, or nil if it can.
+func AppName(name string) error {
+	switch {
+	case !appNameRegex.MatchString(name):
+		return errors.New("App IDs can only contain [a-z0-9-].")
+	case len(name) > MaxAppNameLength:
+		return fmt.Errorf("App IDs can be at most %d characters.", MaxAppNameLength)
+	}
+	for _, reserved := range ReservedAppNames {
+		if name == reserved {
+			return fmt.Errorf("App IDs cannot be %s", name)
+		}
+	}
+	return nil
+}
+
+// ConfigKey returns why key can't be set in an app's config, or nil if it can.
+func ConfigKey(key string) error {
+	if !configKeyRegex.MatchString(key) {
+		return errors.New("Config keys must start with a letter or underscore and only contain [A-z0-9_]")
+	}
+	return nil
+}
+
+// ConfigValue returns why value can't be set in an app's config, or nil if it can.
+func ConfigValue(value string) error {
+	if value == "" {
+		return errors.New("Config values can't be empty")
+	}
+	return nil
+}
+
+// Listed returns what "deis config:list" shows of an accepted config value, and whether it can
+// be shown reliably at all.
+func Listed(value string) (string, bool) {
+	if strings.ContainsAny(value, "\n\r") {
+		return "", false
+	}
+	return strings.TrimSpace(value), true
+}
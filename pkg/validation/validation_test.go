@@ -0,0 +1,51 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAppName(t *testing.T) {
+	for _, name := range []string{"a", "test-1", "0", "a-b-c", strings.Repeat("a", MaxAppNameLength)} {
+		if err := AppName(name); err != nil {
+			t.Errorf("expected %q to be accepted, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "-a", "a-", "a--b", "A", "a_b", "a b", "讲台", "deis", strings.Repeat("a", MaxAppNameLength+1)} {
+		if err := AppName(name); err == nil {
+			t.Errorf("expected %q to be refused", name)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	for _, key := range []string{"FOO", "_", "foo_Bar9", "A" + strings.Repeat("1", 300)} {
+		if err := ConfigKey(key); err != nil {
+			t.Errorf("expected %q to be accepted, got %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "1FOO", "FOO-BAR", "FOO BAR", "FOO=", "FÖO", "$FOO"} {
+		if err := ConfigKey(key); err == nil {
+			t.Errorf("expected %q to be refused", key)
+		}
+	}
+	if ConfigValue("") == nil || ConfigValue(" ") != nil {
+		t.Error("expected only empty values to be refused")
+	}
+}
+
+func TestListed(t *testing.T) {
+	for value, listed := range map[string]string{
+		"bar":              "bar",
+		"  the Deis team ": "the Deis team",
+		"讲台":               "讲台",
+		"$(rm -rf /)":      "$(rm -rf /)",
+	} {
+		if got, ok := Listed(value); !ok || got != listed {
+			t.Errorf("expected %q to be listed as %q, got %q, %v", value, listed, got, ok)
+		}
+	}
+	if _, ok := Listed("This is a\n multiline\r string"); ok {
+		t.Error("expected a multi-line value to be unreliable")
+	}
+}
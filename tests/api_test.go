@@ -0,0 +1,271 @@
+package tests
+
+import (
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+
+	"github.com/deis/workflow/_tests/pkg/api"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The fields the controller's resources must have.
+var (
+	apiAppFields     = []string{"uuid", "id", "owner", "url", "structure", "created", "updated"}
+	apiConfigFields  = []string{"uuid", "app", "owner", "values", "memory", "cpu", "tags", "registry", "healthcheck", "created", "updated"}
+	apiReleaseFields = []string{"uuid", "app", "version", "owner", "summary", "build", "config", "created", "updated"}
+	apiBuildFields   = []string{"uuid", "app", "owner", "image", "procfile", "created", "updated"}
+	apiKeyFields     = []string{"uuid", "id", "owner", "public", "created", "updated"}
+	apiUserFields    = []string{"id", "username", "email", "is_superuser", "is_active", "date_joined"}
+	apiPageFields    = []string{"count", "next", "previous", "results"}
+)
+
+// These specs check the controller's REST API directly, without the CLI, so that a failure of the
+// CLI specs can be pinned on the CLI or the controller. Against the local target they hold the
+// fake controller to the same contract.
+var _ = Describe("API", func() {
+	var client *api.Client
+
+	// newClient logs in as username through the API
+	newClient := func(username, password string) *api.Client {
+		c := api.New(url, "")
+		Expect(c.Login(username, password)).To(Succeed())
+		secrets.Add(c.Token)
+		return c
+	}
+
+	// request sends a request, checks the controller answered with status and JSON, and returns
+	// the JSON object it answered with, if any
+	request := func(c *api.Client, method, path string, body interface{}, status int) map[string]interface{} {
+		resp, err := c.Do(method, path, body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Status).To(Equal(status), "%s %s answered %s", method, path, resp.Body)
+		if len(resp.Body) == 0 {
+			return nil
+		}
+		obj, err := resp.Object()
+		Expect(err).NotTo(HaveOccurred())
+		return obj
+	}
+
+	expectFields := func(obj map[string]interface{}, fields []string) {
+		for _, field := range fields {
+			ExpectWithOffset(1, obj).To(HaveKey(field))
+		}
+	}
+
+	// expectDetail checks an error body explains itself in its detail field
+	expectDetail := func(obj map[string]interface{}) {
+		ExpectWithOffset(1, obj).To(HaveKeyWithValue("detail", BeAssignableToTypeOf("")))
+		ExpectWithOffset(1, obj["detail"]).NotTo(BeEmpty())
+	}
+
+	// expectFieldError checks a validation error body lists messages for field
+	expectFieldError := func(obj map[string]interface{}, field string) {
+		ExpectWithOffset(1, obj).To(HaveKey(field))
+		messages, ok := obj[field].([]interface{})
+		ExpectWithOffset(1, ok).To(BeTrue(), "expected a list of messages for %s, got %v", field, obj[field])
+		ExpectWithOffset(1, messages).NotTo(BeEmpty())
+	}
+
+	// newApp creates an app through the API, which AfterEach destroys
+	newApp := func() string {
+		name := getRandAppName()
+		_, err := client.CreateApp(name)
+		Expect(err).NotTo(HaveOccurred())
+		return name
+	}
+
+	BeforeEach(func() {
+		client = newClient(testUser, testPassword)
+	})
+
+	AfterEach(func() {
+		specAppsMu.Lock()
+		names := append([]string(nil), specApps...)
+		specAppsMu.Unlock()
+		for _, name := range names {
+			client.DeleteApp(name)
+		}
+	})
+
+	It("refuses requests without a token [smoke]", func() {
+		expectDetail(request(api.New(url, ""), "GET", "/v2/apps/", nil, http.StatusUnauthorized))
+	})
+
+	Context("apps", func() {
+		It("creates, shows, lists and destroys apps [smoke]", func() {
+			name := getRandAppName()
+			app := request(client, "POST", "/v2/apps/", map[string]string{"id": name}, http.StatusCreated)
+			expectFields(app, apiAppFields)
+			Expect(app).To(HaveKeyWithValue("id", name))
+			Expect(app).To(HaveKeyWithValue("owner", testUser))
+
+			expectFields(request(client, "GET", "/v2/apps/"+name+"/", nil, http.StatusOK), apiAppFields)
+			page := request(client, "GET", "/v2/apps/", nil, http.StatusOK)
+			expectFields(page, apiPageFields)
+			apps, err := client.Apps()
+			Expect(err).NotTo(HaveOccurred())
+			var names []string
+			for _, app := range apps {
+				names = append(names, app.ID)
+			}
+			Expect(names).To(ContainElement(name))
+
+			request(client, "DELETE", "/v2/apps/"+name+"/", nil, http.StatusNoContent)
+			expectDetail(request(client, "GET", "/v2/apps/"+name+"/", nil, http.StatusNotFound))
+		})
+
+		It("refuses invalid and duplicate app names", func() {
+			expectFieldError(request(client, "POST", "/v2/apps/", map[string]string{"id": "Not_Valid"}, http.StatusBadRequest), "id")
+			name := newApp()
+			expectFieldError(request(client, "POST", "/v2/apps/", map[string]string{"id": name}, http.StatusBadRequest), "id")
+		})
+
+		It("pages lists with limit and offset", func() {
+			for i := 0; i < 3; i++ {
+				newApp()
+			}
+			first, err := client.Page("/v2/apps/", 1, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first.Count).To(BeNumerically(">=", 3))
+			Expect(first.Results).To(HaveLen(1))
+			Expect(first.Previous).To(BeNil())
+			Expect(first.Next).NotTo(BeNil())
+
+			// the links may name the controller differently, such as by the router's host name
+			next, err := neturl.Parse(*first.Next)
+			Expect(err).NotTo(HaveOccurred())
+			second, err := client.Page(next.Path+"?"+next.RawQuery, 0, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second.Count).To(Equal(first.Count))
+			Expect(second.Results).To(HaveLen(1))
+			Expect(second.Results[0]).NotTo(Equal(first.Results[0]))
+			Expect(second.Previous).NotTo(BeNil())
+		})
+	})
+
+	Context("config and releases", func() {
+		It("releases each config change", func() {
+			name := newApp()
+			path := "/v2/apps/" + name + "/"
+			expectFields(request(client, "GET", path+"config/", nil, http.StatusOK), apiConfigFields)
+
+			config := request(client, "POST", path+"config/", map[string]interface{}{"values": map[string]string{"FOO": "bar"}}, http.StatusCreated)
+			expectFields(config, apiConfigFields)
+			Expect(config["values"]).To(HaveKeyWithValue("FOO", "bar"))
+
+			releases, err := client.Releases(name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(HaveLen(2))
+			Expect(releases[0].Version).To(Equal(2))
+			expectFields(request(client, "GET", path+"releases/v2/", nil, http.StatusOK), apiReleaseFields)
+
+			unset := map[string]interface{}{"values": map[string]interface{}{"MISSING": nil}}
+			expectDetail(request(client, "POST", path+"config/", unset, http.StatusUnprocessableEntity))
+		})
+
+		It("rolls back to an earlier release", func() {
+			name := newApp()
+			_, err := client.SetConfig(name, map[string]interface{}{"FOO": "bar"})
+			Expect(err).NotTo(HaveOccurred())
+			version, err := client.Rollback(name, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal(3))
+			config, err := client.Config(name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Values).NotTo(HaveKey("FOO"))
+			expectDetail(request(client, "GET", "/v2/apps/"+name+"/releases/v99/", nil, http.StatusNotFound))
+		})
+	})
+
+	Context("builds", func() {
+		It("lists no builds before a deploy, and refuses builds without an image", func() {
+			name := newApp()
+			page, err := client.Page("/v2/apps/"+name+"/builds/", 0, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(page.Count).To(BeZero())
+			expectFieldError(request(client, "POST", "/v2/apps/"+name+"/builds/", map[string]string{}, http.StatusBadRequest), "image")
+		})
+
+		It("releases a build of an image [deploy]", func() {
+			name := newApp()
+			build := request(client, "POST", "/v2/apps/"+name+"/builds/", map[string]string{"image": "deis/example-go"}, http.StatusCreated)
+			expectFields(build, apiBuildFields)
+			releases, err := client.Releases(name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases[0].Build).NotTo(BeNil())
+			Expect(*releases[0].Build).To(Equal(build["uuid"]))
+		})
+	})
+
+	Context("keys", func() {
+		It("adds and removes keys", func() {
+			id := keyName + "-api"
+			public, err := ioutil.ReadFile(createKey(id) + ".pub")
+			Expect(err).NotTo(HaveOccurred())
+			key := request(client, "POST", "/v2/keys/", map[string]string{"id": id, "public": string(public)}, http.StatusCreated)
+			expectFields(key, apiKeyFields)
+			expectFieldError(request(client, "POST", "/v2/keys/", map[string]string{"id": id, "public": string(public)}, http.StatusBadRequest), "id")
+			keys, err := client.Keys()
+			Expect(err).NotTo(HaveOccurred())
+			var ids []string
+			for _, key := range keys {
+				ids = append(ids, key.ID)
+			}
+			Expect(ids).To(ContainElement(id))
+			Expect(client.DeleteKey(id)).To(Succeed())
+			expectDetail(request(client, "DELETE", "/v2/keys/"+id+"/", nil, http.StatusNotFound))
+		})
+	})
+
+	Context("perms [multi-user]", func() {
+		It("shares apps with collaborators, who can't destroy them", func() {
+			collaborator := testUser + "-collab"
+			_, err := api.New(url, "").Register(collaborator, testPassword, collaborator+"@deis.io")
+			Expect(err).NotTo(HaveOccurred())
+			other := newClient(collaborator, testPassword)
+			defer other.Cancel()
+
+			name := newApp()
+			path := "/v2/apps/" + name + "/"
+			expectDetail(request(other, "GET", path, nil, http.StatusForbidden))
+			Expect(client.GrantPerm(name, collaborator)).To(Succeed())
+			Expect(client.Perms(name)).To(Equal([]string{collaborator}))
+			expectFields(request(other, "GET", path, nil, http.StatusOK), apiAppFields)
+			expectDetail(request(other, "DELETE", path, nil, http.StatusForbidden))
+
+			Expect(client.RevokePerm(name, collaborator)).To(Succeed())
+			expectDetail(request(other, "GET", path, nil, http.StatusForbidden))
+		})
+	})
+
+	Context("users", func() {
+		It("lets only admins list users [admin]", func() {
+			expectDetail(request(client, "GET", "/v2/users/", nil, http.StatusForbidden))
+			admin := newClient(testAdminUser, testAdminPassword)
+			page := request(admin, "GET", "/v2/users/", nil, http.StatusOK)
+			expectFields(page, apiPageFields)
+			results, ok := page["results"].([]interface{})
+			Expect(ok).To(BeTrue())
+			Expect(results).NotTo(BeEmpty())
+			Expect(results[0]).To(BeAssignableToTypeOf(map[string]interface{}{}))
+			expectFields(results[0].(map[string]interface{}), apiUserFields)
+
+			users, err := admin.Users()
+			Expect(err).NotTo(HaveOccurred())
+			var usernames []string
+			for _, user := range users {
+				usernames = append(usernames, user.Username)
+			}
+			Expect(usernames).To(ContainElement(testUser))
+		})
+
+		It("tells users who they are", func() {
+			me := request(client, "GET", "/v2/auth/whoami/", nil, http.StatusOK)
+			expectFields(me, apiUserFields)
+			Expect(me).To(HaveKeyWithValue("username", testUser))
+		})
+	})
+})
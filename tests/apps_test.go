@@ -62,7 +62,7 @@ var _ = Describe("Apps", func() {
 			}
 		})
 
-		It("creates an app with a git remote", func() {
+		It("creates an app with a git remote [smoke]", func() {
 			cmd, err := start("deis apps:create %s", appName)
 			Expect(err).NotTo(HaveOccurred())
 			Eventually(cmd).Should(Say("created %s", appName))
@@ -98,7 +98,7 @@ var _ = Describe("Apps", func() {
 		})
 	})
 
-	Context("with a deployed app", func() {
+	Context("with a deployed app [deploy]", func() {
 		var appName string
 
 		BeforeEach(func() {
@@ -139,8 +139,7 @@ var _ = Describe("Apps", func() {
 			Eventually(sess).Should(Exit(0))
 		})
 
-		// V broken
-		XIt("can get app logs", func() {
+		It("can get app logs", func() {
 			cmd, err := start("deis logs")
 			Expect(err).NotTo(HaveOccurred())
 			Eventually(cmd).Should(SatisfyAll(
@@ -163,8 +162,7 @@ var _ = Describe("Apps", func() {
 			Eventually(cmd).Should(Say("404 Not found"))
 		})
 
-		// V broken
-		XIt("can run a command in the app environment", func() {
+		It("can run a command in the app environment", func() {
 			cmd, err := start("deis apps:run echo Hello, 世界")
 			Expect(err).NotTo(HaveOccurred())
 			Eventually(cmd, (1 * time.Minute)).Should(SatisfyAll(
@@ -173,7 +171,7 @@ var _ = Describe("Apps", func() {
 		})
 
 		// TODO: this requires a second user account
-		XIt("can transfer the app to another owner", func() {
+		XIt("can transfer the app to another owner [multi-user]", func() {
 		})
 	})
 })
@@ -33,7 +33,7 @@ var _ = Describe("Auth", func() {
 			Expect(out).To(ContainSubstring("Registration failed"))
 		})
 
-		It("prints the current user", func() {
+		It("prints the current user [smoke]", func() {
 			sess, err := start("deis auth:whoami")
 			Expect(err).To(BeNil())
 			Eventually(sess).Should(Exit(0))
@@ -41,25 +41,29 @@ var _ = Describe("Auth", func() {
 		})
 
 		It("regenerates the token for the current user", func() {
+			requireFeature("auth-regenerate")
 			sess, err := start("deis auth:regenerate")
 			Expect(err).To(BeNil())
 			Eventually(sess).Should(Exit(0))
 			Eventually(sess).Should(Say("Token Regenerated"))
+			rememberToken()
 		})
 	})
 
-	Context("when logged in as an admin", func() {
+	Context("when logged in as an admin [admin]", func() {
 		BeforeEach(func() {
 			login(url, testAdminUser, testAdminPassword)
 		})
 
-		It("regenerates the token for a specified user", func() {
+		It("regenerates the token for a specified user [multi-user]", func() {
+			requireFeature("auth-regenerate")
 			output, err := execute("deis auth:regenerate -u %s", testUser)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(output).To(ContainSubstring("Token Regenerated"))
 		})
 
-		It("regenerates the token for all users", func() {
+		It("regenerates the token for all users [destructive]", func() {
+			requireFeature("auth-regenerate")
 			output, err := execute("deis auth:regenerate --all")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(output).To(ContainSubstring("Token Regenerated"))
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"os"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// benchSamples is how many times each benchmark is run, 3 by default
+func benchSamples() int {
+	if n, err := strconv.Atoi(os.Getenv("BENCH_SAMPLES")); err == nil && n > 0 {
+		return n
+	}
+	return 3
+}
+
+// Benchmarks only run when BENCH_HISTORY is set, and are skipped otherwise; see TestTests.
+var _ = Describe("Benchmarks [deploy] [slow]", func() {
+	var apps []string
+
+	BeforeEach(func() {
+		apps = nil
+		os.Chdir("example-go")
+	})
+
+	AfterEach(func() {
+		defer os.Chdir("..")
+		// clean up after samples which failed before destroying their app
+		for _, app := range apps {
+			destroyApp(app)
+		}
+	})
+
+	Measure("deploying, scaling, rolling back and destroying an app", func(b Benchmarker) {
+		appName := getRandAppName()
+		apps = append(apps, appName)
+
+		b.Time("create", func() {
+			sess := createApp(appName)
+			Eventually(sess).Should(Exit(0))
+		})
+		b.Time("push", func() {
+			sess, err := start("GIT_SSH=%s git push deis master", gitSSH)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(sess.Err, "5m").Should(Say("Done, %s:v2 deployed to Deis", appName))
+			Eventually(sess).Should(Exit(0))
+		})
+		b.Time("first 200", func() {
+			Eventually(func() error {
+				_, err := getAppBody(appName)
+				return err
+			}, "5m", "1s").Should(Succeed())
+		})
+		b.Time("scale up", func() {
+			sess, err := start("deis ps:scale web=3 -a %s", appName)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(sess, "5m").Should(Exit(0))
+		})
+
+		// make a release to roll back from, without timing it
+		sess, err := start("deis config:set BENCHMARK=1 -a %s", appName)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(sess, "5m").Should(Exit(0))
+		b.Time("rollback", func() {
+			sess, err := start("deis releases:rollback -a %s", appName)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(sess, "5m").Should(Exit(0))
+		})
+
+		b.Time("destroy", func() {
+			destroyApp(appName)
+		})
+		apps = apps[:len(apps)-1]
+	}, benchSamples())
+})
@@ -31,7 +31,7 @@ var _ = Describe("Builds", func() {
 			})
 		})
 
-		Context("with a deployed app", func() {
+		Context("with a deployed app [deploy]", func() {
 
 			XIt("can list app builds", func() {
 				// "deis builds:list --app=%s", app
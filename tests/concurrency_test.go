@@ -0,0 +1,301 @@
+package tests
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/parse"
+	"github.com/deis/workflow/_tests/pkg/settings"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// outcome is what one of several commands run at once printed, and how it exited.
+type outcome struct {
+	command string
+	output  string
+	err     error
+}
+
+// concurrently runs every command line through execute at the same time, and returns their
+// outcomes in the order given once all have exited. Failures are written to the spec's output.
+func concurrently(cmdLines ...string) []outcome {
+	outcomes := make([]outcome, len(cmdLines))
+	var wg sync.WaitGroup
+	for i, cmdLine := range cmdLines {
+		wg.Add(1)
+		go func(i int, cmdLine string) {
+			defer wg.Done()
+			output, err := execute("%s", cmdLine)
+			outcomes[i] = outcome{command: cmdLine, output: output, err: err}
+		}(i, cmdLine)
+	}
+	wg.Wait()
+	for _, o := range outcomes {
+		if o.err != nil {
+			fmt.Fprintf(ginkgoOut, "%s failed: %v\n%s\n", secrets.String(o.command), o.err, secrets.String(o.output))
+		}
+	}
+	return outcomes
+}
+
+// succeeded reports whether o exited zero. A command which lost a race may fail, but only plainly:
+// with an explanation and without crashing.
+func succeeded(o outcome) bool {
+	if o.err == nil {
+		return true
+	}
+	Expect(strings.TrimSpace(o.output)).NotTo(BeEmpty(), "%s failed without an explanation", o.command)
+	Expect(o.output).NotTo(ContainSubstring("panic:"))
+	return false
+}
+
+// countSucceeded returns how many of outcomes exited zero.
+func countSucceeded(outcomes []outcome) int {
+	n := 0
+	for _, o := range outcomes {
+		if succeeded(o) {
+			n++
+		}
+	}
+	return n
+}
+
+// expectConsistentReleases checks that app's releases run from v1 to vN with none repeated or
+// missing, and returns N.
+func expectConsistentReleases(app string) int {
+	output, err := execute("deis releases:list -a %s", app)
+	Expect(err).NotTo(HaveOccurred(), output)
+	list, err := parse.ReleasesList(output)
+	Expect(err).NotTo(HaveOccurred())
+	for i, release := range list {
+		Expect(release.Version).To(Equal(len(list)-i), "the releases of %s are not numbered in order:\n%s", app, output)
+	}
+	return len(list)
+}
+
+// commitFixture commits a version of the app in the git repository dir which responds with body.
+func commitFixture(dir, body string) {
+	err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(fmt.Sprintf(fixtureSource, body)), 0644)
+	Expect(err).NotTo(HaveOccurred())
+	output, err := execute(`cd %s && git -c user.name=%s -c user.email=%s commit -am "respond with %s"`,
+		dir, testUser, testEmail, body)
+	Expect(err).NotTo(HaveOccurred(), output)
+}
+
+// pushFixture returns a command line force pushing the repository dir to the deis remote.
+func pushFixture(dir string) string {
+	return fmt.Sprintf("cd %s && GIT_SSH=%s git push -f deis master", dir, gitSSH)
+}
+
+var _ = Describe("Concurrency [slow]", func() {
+
+	Context("with an app being deployed [deploy]", func() {
+		var appName, repo string
+
+		BeforeEach(func() {
+			os.Chdir("example-go")
+			var err error
+			repo, err = os.Getwd()
+			Expect(err).NotTo(HaveOccurred())
+			appName = getRandAppName()
+			Eventually(createApp(appName)).Should(Exit(0))
+		})
+
+		AfterEach(func() {
+			defer os.Chdir("..")
+			destroyApp(appName)
+		})
+
+		It("releases every one of two simultaneous pushes", func() {
+			other := repo + "-race"
+			output, err := execute("git clone -q %s %s", repo, other)
+			Expect(err).NotTo(HaveOccurred(), output)
+			defer os.RemoveAll(other)
+			remote, err := execute("git config remote.deis.url")
+			Expect(err).NotTo(HaveOccurred(), remote)
+			output, err = execute("cd %s && git remote add deis %s", other, strings.TrimSpace(remote))
+			Expect(err).NotTo(HaveOccurred(), output)
+
+			commitFixture(repo, "race one")
+			commitFixture(other, "race two")
+			outcomes := concurrently(pushFixture(repo), pushFixture(other))
+			pushed := countSucceeded(outcomes)
+			Expect(pushed).To(BeNumerically(">=", 1), "neither push was deployed")
+
+			// apps:create made v1, and every deployed push adds one
+			Expect(expectConsistentReleases(appName)).To(Equal(1 + pushed))
+			var bodies []string
+			for i, body := range []string{"race one", "race two"} {
+				if outcomes[i].err == nil {
+					bodies = append(bodies, body)
+				}
+			}
+			Eventually(func() (string, error) {
+				body, err := getAppBody(appName)
+				return strings.TrimSpace(body), err
+			}, "2m", "5s").Should(BeElementOf(bodies))
+		})
+
+		It("keeps config set while a push is deployed", func() {
+			commitFixture(repo, "race config")
+			cmdLines := []string{pushFixture(repo)}
+			keys := []string{"RACE_ONE", "RACE_TWO", "RACE_THREE"}
+			for _, key := range keys {
+				cmdLines = append(cmdLines, fmt.Sprintf("deis config:set %s=%s -a %s", key, strings.ToLower(key), appName))
+			}
+			outcomes := concurrently(cmdLines...)
+			Expect(succeeded(outcomes[0])).To(BeTrue(), "the push failed")
+
+			output, err := execute("deis config:list -a %s", appName)
+			Expect(err).NotTo(HaveOccurred(), output)
+			config := parse.ConfigList(output)
+			for i, key := range keys {
+				if succeeded(outcomes[i+1]) {
+					Expect(config).To(HaveKeyWithValue(key, strings.ToLower(key)), "config:set %s was lost", key)
+				}
+			}
+			Expect(expectConsistentReleases(appName)).To(Equal(1 + countSucceeded(outcomes)))
+		})
+	})
+
+	Context("with an app being rolled back [deploy]", func() {
+		var appName string
+
+		BeforeEach(func() {
+			appName = getRandAppName()
+			output, err := execute("deis apps:create %s --no-remote", appName)
+			Expect(err).NotTo(HaveOccurred(), output)
+			sess, err := start("deis pull deis/example-go -a %s", appName)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(sess, (10 * time.Minute)).Should(Exit(0))
+			output, err = execute("deis config:set RACE=before -a %s", appName)
+			Expect(err).NotTo(HaveOccurred(), output)
+		})
+
+		AfterEach(func() {
+			destroyApp(appName)
+		})
+
+		It("keeps the scale set during the rollback", func() {
+			outcomes := concurrently(
+				fmt.Sprintf("deis releases:rollback v2 -a %s", appName),
+				fmt.Sprintf("deis ps:scale cmd=3 -a %s", appName))
+			rolledBack, scaled := succeeded(outcomes[0]), succeeded(outcomes[1])
+
+			// v1 to v3 came before the race; a rollback adds a release and scaling doesn't
+			releaseCount := 3
+			if rolledBack {
+				releaseCount++
+			}
+			Expect(expectConsistentReleases(appName)).To(Equal(releaseCount))
+			output, err := execute("deis config:list -a %s", appName)
+			Expect(err).NotTo(HaveOccurred(), output)
+			if rolledBack {
+				Expect(parse.ConfigList(output)).NotTo(HaveKey("RACE"))
+			} else {
+				Expect(parse.ConfigList(output)).To(HaveKeyWithValue("RACE", "before"))
+			}
+			if scaled {
+				process := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(appName) + `-\S*cmd\S* up \(v\d+\)$`)
+				Eventually(func() (int, error) {
+					output, err := execute("deis ps:list -a %s", appName)
+					return len(process.FindAllString(output, -1)), err
+				}, "5m", "5s").Should(Equal(3))
+			}
+		})
+	})
+
+	Context("with many users creating apps at once [multi-user]", func() {
+		const userCount, appsPerUser = 4, 3
+		var users, profiles []string
+		var owned map[string][]string
+
+		// as returns a command line running the CLI as the i-th user
+		as := func(i int, format string, args ...interface{}) string {
+			return fmt.Sprintf("DEIS_PROFILE=%s deis %s", profiles[i], fmt.Sprintf(format, args...))
+		}
+
+		BeforeEach(func() {
+			users, profiles = nil, nil
+			owned = make(map[string][]string)
+			var cmdLines []string
+			for i := 0; i < userCount; i++ {
+				users = append(users, fmt.Sprintf("%s-race%d", testUser, i))
+				profiles = append(profiles, fmt.Sprintf("race%d", i))
+				cmdLines = append(cmdLines, as(i, "register %s --username=%s --password=%s --email=%s@deis.io",
+					url, users[i], testPassword, users[i]))
+			}
+			for _, o := range concurrently(cmdLines...) {
+				Expect(o.err).NotTo(HaveOccurred(), o.output)
+			}
+			for _, profile := range profiles {
+				s, err := settings.Load(settings.Path(os.Getenv("HOME"), profile))
+				Expect(err).NotTo(HaveOccurred())
+				secrets.Add(s.Token)
+			}
+		})
+
+		AfterEach(func() {
+			var cmdLines []string
+			for i, user := range users {
+				for _, app := range owned[user] {
+					cmdLines = append(cmdLines, as(i, "apps:destroy --app=%s --confirm=%s", app, app))
+				}
+			}
+			concurrently(cmdLines...)
+			cmdLines = nil
+			for i, user := range users {
+				cmdLines = append(cmdLines, as(i, "auth:cancel --username=%s --password=%s --yes", user, testPassword))
+			}
+			concurrently(cmdLines...)
+			for _, profile := range profiles {
+				os.Remove(settings.Path(os.Getenv("HOME"), profile))
+			}
+		})
+
+		It("creates every app once, for the user who asked first", func() {
+			var cmdLines, owners, apps []string
+			contested := getRandAppName()
+			for i, user := range users {
+				for j := 0; j < appsPerUser; j++ {
+					app := getRandAppName()
+					cmdLines = append(cmdLines, as(i, "apps:create %s --no-remote", app))
+					owners, apps = append(owners, user), append(apps, app)
+				}
+				cmdLines = append(cmdLines, as(i, "apps:create %s --no-remote", contested))
+				owners, apps = append(owners, user), append(apps, contested)
+			}
+			outcomes := concurrently(cmdLines...)
+			for i, o := range outcomes {
+				if apps[i] != contested {
+					Expect(o.err).NotTo(HaveOccurred(), o.output)
+				}
+				if succeeded(o) {
+					owned[owners[i]] = append(owned[owners[i]], apps[i])
+				}
+			}
+
+			winners := 0
+			for i, user := range users {
+				output, err := execute("%s", as(i, "apps:list"))
+				Expect(err).NotTo(HaveOccurred(), output)
+				listed := parse.AppsList(output)
+				Expect(listed).To(ConsistOf(owned[user]), "the apps %s can see", user)
+				for _, app := range owned[user] {
+					if app == contested {
+						winners++
+					}
+				}
+			}
+			Expect(winners).To(Equal(1), "users who created %s", contested)
+		})
+	})
+})
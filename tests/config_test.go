@@ -1,69 +1,79 @@
 package tests
 
 import (
+	"github.com/deis/workflow/_tests/pkg/parse"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
-// TODO: tests are broken
-var _ = XDescribe("Config", func() {
-	Context("with a deployed app", func() {
-		appName := getRandAppName()
+var _ = Describe("Config", func() {
+	Context("with an app", func() {
+		var appName string
+
+		// expectConfig checks that the output of config:set, config:unset or config:list lists
+		// the app's config as expected
+		expectConfig := func(output string, expected map[string]string) {
+			Expect(output).To(ContainSubstring("=== %s Config", appName))
+			Expect(parse.ConfigList(output)).To(Equal(expected))
+		}
+
+		BeforeEach(func() {
+			appName = getRandAppName()
+			output, err := execute("deis apps:create %s --no-remote", appName)
+			Expect(err).NotTo(HaveOccurred(), output)
+		})
+
+		AfterEach(func() {
+			destroyApp(appName)
+		})
 
 		It("can list environment variables", func() {
-			output, err := execute("deis apps:create %s", appName)
+			output, err := execute("deis config:set FOO=bar -a %s", appName)
 			Expect(err).NotTo(HaveOccurred(), output)
+			Expect(output).To(ContainSubstring("Creating config"))
+			expectConfig(output, map[string]string{"FOO": "bar"})
 
-			output, err = execute("deis config:set FOO=bar -a %s", appName)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(output).To(SatisfyAll(
-				ContainSubstring("Creating config"),
-				ContainSubstring("FOO      bar"),
-				ContainSubstring("=== %s Config", appName),
-			))
 			output, err = execute("deis config:list -a %s", appName)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(output).To(SatisfyAll(
-				ContainSubstring("=== %s Config", appName),
-				ContainSubstring("FOO      bar"),
-			))
+			Expect(err).NotTo(HaveOccurred(), output)
+			expectConfig(output, map[string]string{"FOO": "bar"})
 			// TODO: the following won't work as-is because there is no app running
 			// "deis run env -a %s"
-
 		})
 
 		It("can set an integer environment variable", func() {
 			output, err := execute("deis config:set FOO=1 -a %s", appName)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(output).To(ContainSubstring("FOO      1"))
+			Expect(err).NotTo(HaveOccurred(), output)
+			expectConfig(output, map[string]string{"FOO": "1"})
 		})
 
 		It("can set an environment variable containing spaces", func() {
 			output, err := execute(`deis config:set POWERED_BY=the\ Deis\ team -a %s`, appName)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(output).To(ContainSubstring("POWERED_BY      the Deis team"))
+			Expect(err).NotTo(HaveOccurred(), output)
+			expectConfig(output, map[string]string{"POWERED_BY": "the Deis team"})
 		})
 
 		It("can set a multi-line environment variable", func() {
 			mlString := "This is a\n multiline\r string"
 			output, err := execute(`deis config:set FOO="%s" -a %s`, mlString, appName)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(output).To(MatchRegexp(`FOO\s+%s`, mlString))
+			Expect(err).NotTo(HaveOccurred(), output)
+			// the value's other lines are listed on lines of their own
+			Expect(parse.ConfigList(output)).To(HaveKeyWithValue("FOO", "This is a"))
+			Expect(output).To(ContainSubstring("multiline"))
 		})
 
 		It("can set an environment variable with multibyte chars", func() {
 			output, err := execute("deis config:set FOO=讲台 -a %s", appName)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(output).To(ContainSubstring("FOO             讲台"))
+			Expect(err).NotTo(HaveOccurred(), output)
+			expectConfig(output, map[string]string{"FOO": "讲台"})
 		})
 
 		It("can unset an environment variable", func() {
-			output, err := execute("deis config:set FOO=bar -a %s", appName)
+			output, err := execute("deis config:set FOO=bar BAR=baz -a %s", appName)
 			Expect(err).NotTo(HaveOccurred(), output)
-			Expect(output).To(ContainSubstring("FOO             bar"))
+			expectConfig(output, map[string]string{"FOO": "bar", "BAR": "baz"})
 			output, err = execute("deis config:unset FOO -a %s", appName)
 			Expect(err).NotTo(HaveOccurred(), output)
-			Expect(output).NotTo(ContainSubstring("FOO"))
+			expectConfig(output, map[string]string{"BAR": "baz"})
 		})
 
 		XIt("can pull the configuration to an .env file", func() {
@@ -6,7 +6,7 @@ import (
 )
 
 var _ = Describe("Domains", func() {
-	Context("with a deployed app", func() {
+	Context("with a deployed app [deploy]", func() {
 
 		XIt("can add, list, and remove domains", func() {
 			// "deis domains:list --app=%s", app
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	neturl "net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/faultproxy"
+	"github.com/deis/workflow/_tests/pkg/settings"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// These specs put a fault-injecting proxy between the CLI and the controller, and check that the
+// CLI reports the faults plainly, exits nonzero, and leaves its settings as they were. The CLI
+// talks to the proxy through its own profile, so the suite's settings are never at risk.
+var _ = Describe("Faults", func() {
+	const profile = "faults"
+	var (
+		proxy        *faultproxy.Proxy
+		server       *httptest.Server
+		settingsPath string
+		original     []byte
+	)
+
+	// deis runs the CLI with the proxy's profile
+	deis := func(format string, args ...interface{}) (string, error) {
+		return execute("DEIS_PROFILE=%s deis %s", profile, fmt.Sprintf(format, args...))
+	}
+
+	// inject makes rule the proxy's whole scenario
+	inject := func(rule faultproxy.Rule) {
+		Expect(proxy.SetScenario(faultproxy.Scenario{rule})).To(Succeed())
+	}
+
+	// expectCleanFailure checks that a command failed with an explanation, without crashing or
+	// touching the client settings
+	expectCleanFailure := func(output string, err error) {
+		Expect(err).To(HaveOccurred(), "expected the command to exit nonzero")
+		Expect(strings.TrimSpace(output)).NotTo(BeEmpty(), "expected an explanation")
+		Expect(output).NotTo(ContainSubstring("panic:"))
+		Expect(output).NotTo(ContainSubstring("goroutine "))
+		current, err := ioutil.ReadFile(settingsPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(current)).To(Equal(string(original)), "the client settings changed")
+	}
+
+	BeforeEach(func() {
+		target, err := neturl.Parse(url)
+		Expect(err).NotTo(HaveOccurred())
+		proxy, err = faultproxy.New(target, nil)
+		Expect(err).NotTo(HaveOccurred())
+		proxy.Logf = func(format string, args ...interface{}) {
+			fmt.Fprintf(ginkgoOut, format+"\n", args...)
+		}
+		server = httptest.NewServer(proxy)
+
+		output, err := deis("login %s --username=%s --password=%s", server.URL, testUser, testPassword)
+		Expect(err).NotTo(HaveOccurred(), output)
+		settingsPath = settings.Path(os.Getenv("HOME"), profile)
+		s, err := settings.Load(settingsPath)
+		Expect(err).NotTo(HaveOccurred())
+		secrets.Add(s.Token)
+		original, err = ioutil.ReadFile(settingsPath)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.Remove(settingsPath)
+	})
+
+	for _, code := range []int{500, 502, 503} {
+		code := code
+		It(fmt.Sprintf("reports a %d from the controller", code), func() {
+			inject(faultproxy.Rule{Path: "^/v2/apps/$", Fault: faultproxy.Status, Status: code})
+			output, err := deis("apps:list")
+			expectCleanFailure(output, err)
+			Expect(output).To(ContainSubstring(strconv.Itoa(code)))
+		})
+	}
+
+	It("reports a reset connection", func() {
+		inject(faultproxy.Rule{Path: "^/v2/apps/$", Fault: faultproxy.Reset})
+		expectCleanFailure(deis("apps:list"))
+	})
+
+	It("reports a truncated response", func() {
+		inject(faultproxy.Rule{Path: "^/v2/apps/$", Fault: faultproxy.Truncate})
+		expectCleanFailure(deis("apps:list"))
+	})
+
+	It("waits out a slow controller", func() {
+		inject(faultproxy.Rule{Path: "^/v2/apps/$", Fault: faultproxy.Latency, Delay: "3s"})
+		started := time.Now()
+		output, err := deis("apps:list")
+		Expect(err).NotTo(HaveOccurred(), output)
+		Expect(time.Since(started)).To(BeNumerically(">=", 3*time.Second))
+	})
+
+	It("keeps its settings when logging in fails", func() {
+		inject(faultproxy.Rule{Method: "POST", Path: "^/v2/auth/login/$", Fault: faultproxy.Status, Status: 503})
+		expectCleanFailure(deis("login %s --username=%s --password=%s", server.URL, testUser, testPassword))
+	})
+
+	It("adds no git remote when the controller fails after creating an app", func() {
+		appName := getRandAppName()
+		dir, err := ioutil.TempDir("", "faults")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		output, err := execute("git init %s", dir)
+		Expect(err).NotTo(HaveOccurred(), output)
+
+		inject(faultproxy.Rule{Name: "create fails", Method: "POST", Path: "^/v2/apps/$", Fault: faultproxy.Replace, Status: 500})
+		output, err = execute("cd %s && DEIS_PROFILE=%s deis apps:create %s", dir, profile, appName)
+		Expect(proxy.Injected("create fails")).To(Equal(1))
+		expectCleanFailure(output, err)
+
+		// the controller did create the app, which the CLI was told had failed
+		Expect(proxy.SetScenario(nil)).To(Succeed())
+		defer deis("apps:destroy --app=%s --confirm=%s", appName, appName)
+		output, err = execute("cd %s && git remote", dir)
+		Expect(err).NotTo(HaveOccurred(), output)
+		Expect(output).NotTo(ContainSubstring("deis"))
+	})
+})
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/api"
+	"github.com/deis/workflow/_tests/pkg/fakecontroller"
+	"github.com/deis/workflow/_tests/pkg/fuzz"
+	"github.com/deis/workflow/_tests/pkg/settings"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/config"
+	. "github.com/onsi/gomega"
+)
+
+// fuzzInputs is how many mutated inputs the fuzzing spec tries, FUZZ_INPUTS or one per known
+// command by default.
+func fuzzInputs() int {
+	if n, err := strconv.Atoi(os.Getenv("FUZZ_INPUTS")); err == nil && n > 0 {
+		return n
+	}
+	return len(fuzz.Seeds)
+}
+
+// fuzzSeed seeds the mutations: FUZZ_SEED, to reproduce a run, or else Ginkgo's random seed.
+func fuzzSeed() int64 {
+	if seed, err := strconv.ParseInt(os.Getenv("FUZZ_SEED"), 10, 64); err == nil {
+		return seed
+	}
+	return config.GinkgoConfig.RandomSeed
+}
+
+// fuzzTimeout is how long the CLI may take over one input before it counts as hung.
+const fuzzTimeout = 30 * time.Second
+
+// describeArgs quotes args for a failure message, eliding the middle of long ones.
+func describeArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if runes := []rune(arg); len(runes) > 40 {
+			arg = fmt.Sprintf("%s...(%d runes)...%s", string(runes[:16]), len(runes), string(runes[len(runes)-16:]))
+		}
+		quoted[i] = strconv.Quote(arg)
+	}
+	return "[" + strings.Join(quoted, " ") + "]"
+}
+
+// These specs run the CLI with mangled arguments against an in-process fake controller, fresh for
+// every input, and check that it never crashes, hangs or exits with a code other than 0 or 1. The
+// CLI talks to the fake controller through a profile of its own, so neither the cluster nor the
+// suite's login is touched.
+var _ = Describe("Fuzzing", func() {
+	const (
+		profile = "fuzz"
+		user    = "fuzz"
+		app     = "fuzz-app"
+	)
+	var (
+		dir          string
+		settingsPath string
+		keyPath      string
+	)
+
+	// try runs the CLI with args, after expanding their placeholders, against a new fake
+	// controller on which user is logged in and owns app
+	try := func(args []string) fuzz.Result {
+		server := httptest.NewServer(fakecontroller.NewServer())
+		defer server.Close()
+		client := api.New(server.URL, "")
+		_, err := client.Register(user, "fuzz", "fuzz@example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Login(user, "fuzz")).To(Succeed())
+		_, err = client.CreateApp(app)
+		Expect(err).NotTo(HaveOccurred())
+		s := &settings.Settings{Username: user, Controller: server.URL, Token: client.Token, Limit: 100}
+		Expect(settings.Save(s, settingsPath)).To(Succeed())
+
+		runner := &fuzz.Runner{
+			Env:     append(os.Environ(), "DEIS_PROFILE="+profile),
+			Dir:     dir,
+			Timeout: fuzzTimeout,
+		}
+		return runner.Run(fuzz.Expand(args, map[string]string{
+			fuzz.App:  app,
+			fuzz.User: user,
+			fuzz.URL:  server.URL,
+			fuzz.Key:  keyPath,
+		}))
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "fuzz")
+		Expect(err).NotTo(HaveOccurred())
+		settingsPath = settings.Path(os.Getenv("HOME"), profile)
+		keyPath = createKey("deis-fuzz") + ".pub"
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+		os.Remove(settingsPath)
+	})
+
+	It("handles the inputs which once broke it", func() {
+		entries, err := fuzz.LoadCorpus(fuzzCorpus)
+		Expect(err).NotTo(HaveOccurred())
+		var problems []string
+		for _, e := range entries {
+			if problem := try(e.Args).Problem(); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s %s (first found: %s)", describeArgs(e.Args), problem, e.Problem))
+			}
+		}
+		Expect(problems).To(BeEmpty(), "the CLI still misbehaves for inputs in %s", fuzzCorpus)
+	})
+
+	It("handles mutated arguments to every command", func() {
+		seed := fuzzSeed()
+		fmt.Fprintf(ginkgoOut, "Trying %d inputs with FUZZ_SEED=%d\n", fuzzInputs(), seed)
+		r := rand.New(rand.NewSource(seed))
+		var problems []string
+		for i := 0; i < fuzzInputs(); i++ {
+			args := fuzz.Mutate(r, fuzz.Seeds[i%len(fuzz.Seeds)])
+			result := try(args)
+			problem := result.Problem()
+			if problem == "" {
+				continue
+			}
+			path, err := fuzz.SaveEntry(fuzzCorpus, fuzz.Entry{Args: args, Problem: problem, Found: time.Now().UTC()})
+			Expect(err).NotTo(HaveOccurred())
+			fmt.Fprintf(ginkgoOut, "deis %s %s, saved to %s:\n%s%s\n", describeArgs(args), problem, path, result.Stdout, result.Stderr)
+			problems = append(problems, fmt.Sprintf("%s %s", describeArgs(args), problem))
+		}
+		Expect(problems).To(BeEmpty(), "the CLI misbehaved; the inputs were added to %s", fuzzCorpus)
+	})
+})
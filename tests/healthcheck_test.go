@@ -12,7 +12,7 @@ import (
 
 var _ = Describe("Healthcheck", func() {
 	appName := getRandAppName()
-	Context("with a deployed app", func() {
+	Context("with a deployed app [deploy]", func() {
 		// create and deploy an app
 		BeforeEach(func() {
 			login(url, testUser, testPassword)
@@ -36,34 +36,42 @@ var _ = Describe("Healthcheck", func() {
 			Eventually(sess).Should(Say("Git remote deis removed"))
 		})
 
-		// TODO: test is broken
-		XIt("can stay running during a scale event", func() {
+		It("can stay running during a scale event [slow]", func() {
 			router, err := getRawRouter()
 			Expect(err).To(BeNil())
 			appURLStr := fmt.Sprintf("%s://%s.%s", router.Scheme, appName, router.Host)
-			stopCh := make(chan struct{})
+			scaleCh := make(chan int)
 			doneCh := make(chan struct{})
 
-			// start scaling the app
+			// scale the app to each number of web processes sent, until scaleCh is closed
 			go func() {
-				for range stopCh {
-					sess, err := start("deis ps:scale web=4 -a %s", appName)
+				defer GinkgoRecover()
+				defer close(doneCh)
+				for replicas := range scaleCh {
+					sess, err := start("deis ps:scale web=%d -a %s", replicas, appName)
 					Expect(err).To(BeNil())
-					Eventually(sess).Should(Exit(0))
+					Eventually(sess, "5m").Should(Exit(0))
 				}
-				close(doneCh)
 			}()
 
 			for i := 0; i < 10; i++ {
-				// start the scale operation. waits until the last scale op has finished
-				stopCh <- struct{}{}
+				// start the scale operation, between 2 and 4 web processes. waits until the last
+				// scale op has finished
+				select {
+				case scaleCh <- 2 + i%2*2:
+				case <-doneCh:
+					// the scaling goroutine stopped, having failed the spec
+					return
+				}
 				resp, err := http.Get(appURLStr)
 				Expect(err).To(BeNil())
+				resp.Body.Close()
 				Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusOK))
 			}
+			close(scaleCh)
 
-			// wait until the goroutine that was scaling the app shuts down. not strictly necessary, just good practice
-			Eventually(doneCh).Should(BeClosed())
+			// wait until the goroutine that was scaling the app shuts down
+			Eventually(doneCh, "5m").Should(BeClosed())
 		})
 
 	})
@@ -1,8 +1,13 @@
 package tests
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"sort"
 
+	"github.com/deis/workflow/_tests/pkg/coverage"
+	"github.com/deis/workflow/_tests/pkg/snapshot"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -10,7 +15,27 @@ import (
 const noMatch string = "Found no matching command, try 'deis help'"
 const usage string = "Usage: deis <command> [<args>...]"
 
-var _ = Describe("Help", func() {
+// updateSnapshots rewrites the help snapshots from what the CLI prints, instead of checking it
+var updateSnapshots = flag.Bool("update", false, "rewrite the help snapshots in HELP_SNAPSHOTS from the CLI's output")
+
+// checkHelp compares `deis help <command>` with its snapshot, or rewrites the snapshot if
+// updateSnapshots is set.
+func checkHelp(command string) {
+	output, err := execute("deis help %s", command)
+	Expect(err).NotTo(HaveOccurred(), output)
+	path := snapshot.Path(helpSnapshots, command)
+	if *updateSnapshots {
+		Expect(snapshot.Save(path, output)).To(Succeed())
+		return
+	}
+	golden, err := snapshot.Load(path)
+	Expect(err).NotTo(HaveOccurred())
+	if diff := snapshot.Diff(golden, output); diff != "" {
+		Fail(fmt.Sprintf("deis help %s differs from %s:\n%s\nRun with -update if the change is intended.", command, path, diff))
+	}
+}
+
+var _ = Describe("Help [smoke]", func() {
 
 	for _, flag := range []string{"--help", "-h", "help"} {
 		It(fmt.Sprintf("prints help on \"%s\"", flag), func() {
@@ -34,3 +59,49 @@ var _ = Describe("Help", func() {
 			ContainSubstring(usage)))
 	})
 })
+
+// The help snapshots keep what `deis help <command>` printed for every command, so that changes to
+// the help or flags of a new CLI show up as differences to review. The docs are generated from
+// the same help.
+var _ = Describe("Help snapshots [smoke]", func() {
+	names, err := snapshot.Names(helpSnapshots)
+	if err != nil {
+		panic(err)
+	}
+	for _, name := range names {
+		name := name
+		It(fmt.Sprintf("prints the help of %s as its snapshot", name), func() {
+			checkHelp(name)
+		})
+	}
+
+	It("has a snapshot of the help of every command", func() {
+		cli, err := coverage.Discover(deisCLI)
+		Expect(err).NotTo(HaveOccurred())
+		var missing []string
+		for command := range cli.Commands {
+			if _, err := snapshot.Load(snapshot.Path(helpSnapshots, command)); os.IsNotExist(err) {
+				missing = append(missing, command)
+			}
+		}
+		// the commands the CLI no longer has
+		var stale []string
+		for _, name := range names {
+			if cli.Commands[name] == nil {
+				stale = append(stale, name)
+			}
+		}
+		if !*updateSnapshots {
+			sort.Strings(missing)
+			Expect(missing).To(BeEmpty(), "no snapshots in %s; run make update-help-snapshots to take them", helpSnapshots)
+			Expect(stale).To(BeEmpty(), "snapshots in %s of commands the CLI lacks; run make update-help-snapshots to remove them", helpSnapshots)
+			return
+		}
+		for _, command := range missing {
+			checkHelp(command)
+		}
+		for _, name := range stale {
+			Expect(os.Remove(snapshot.Path(helpSnapshots, name))).To(Succeed())
+		}
+	})
+})
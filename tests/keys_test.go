@@ -6,7 +6,7 @@ import (
 )
 
 var _ = Describe("Keys", func() {
-	It("can list and remove a key", func() {
+	It("can list and remove a key [smoke]", func() {
 		output, err := execute("deis keys:list")
 		Expect(err).NotTo(HaveOccurred())
 		Expect(output).To(ContainSubstring("%s ssh-rsa", keyName))
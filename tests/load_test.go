@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/load"
+	"github.com/deis/workflow/_tests/pkg/settings"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// loadOptions reads the options of the load spec from LOAD_USERS, LOAD_RATE and LOAD_DURATION,
+// which is a minute by default.
+func loadOptions() (load.Options, error) {
+	o := load.Options{Duration: time.Minute}
+	var err error
+	if o.Users, err = strconv.Atoi(loadUsers); err != nil {
+		return o, fmt.Errorf("LOAD_USERS: %v", err)
+	}
+	if rate := os.Getenv("LOAD_RATE"); rate != "" {
+		if o.Rate, err = strconv.ParseFloat(rate, 64); err != nil {
+			return o, fmt.Errorf("LOAD_RATE: %v", err)
+		}
+	}
+	if duration := os.Getenv("LOAD_DURATION"); duration != "" {
+		if o.Duration, err = time.ParseDuration(duration); err != nil {
+			return o, fmt.Errorf("LOAD_DURATION: %v", err)
+		}
+	}
+	return o, o.Validate()
+}
+
+// cliLoadFlow returns the flow of a new user through the deis CLI: registering, logging in,
+// creating an app, setting its config, listing its releases, destroying it and cancelling the
+// account. Each step is named after its command, and each virtual user has its own profile.
+func cliLoadFlow(prefix string) load.Flow {
+	// deis runs a CLI command as u
+	deis := func(u *load.User, format string, args ...interface{}) error {
+		output, err := execute("DEIS_PROFILE=%s%d deis %s", prefix, u.ID, fmt.Sprintf(format, args...))
+		if err != nil {
+			return fmt.Errorf("%v: %s", err, output)
+		}
+		return nil
+	}
+	return load.Flow{
+		{Name: "deis register", Run: func(u *load.User) error {
+			username := u.Name(prefix)
+			u.Values["username"] = username
+			err := deis(u, "register %s --username=%s --password=%s --email=%s@deis.io", url, username, testPassword, username)
+			if err == nil {
+				u.Values["registered"] = true
+			}
+			return err
+		}},
+		{Name: "deis login", Run: func(u *load.User) error {
+			err := deis(u, "login %s --username=%s --password=%s", url, u.Values["username"], testPassword)
+			if s, loadErr := settings.Load(settings.Path(os.Getenv("HOME"), fmt.Sprintf("%s%d", prefix, u.ID))); loadErr == nil {
+				secrets.Add(s.Token)
+			}
+			return err
+		}},
+		{Name: "deis apps:create", Run: func(u *load.User) error {
+			err := deis(u, "apps:create %s --no-remote", u.Name(prefix))
+			if err == nil {
+				u.Values["app"] = u.Name(prefix)
+			}
+			return err
+		}},
+		{Name: "deis config:set", Run: func(u *load.User) error {
+			return deis(u, "config:set LOAD=%d -a %s", u.Iteration, u.Values["app"])
+		}},
+		{Name: "deis releases:list", Run: func(u *load.User) error {
+			return deis(u, "releases:list -a %s", u.Values["app"])
+		}},
+		{Name: "deis apps:destroy", Cleanup: true, Run: func(u *load.User) error {
+			if u.Values["app"] == nil {
+				return load.ErrSkipped
+			}
+			return deis(u, "apps:destroy --app=%s --confirm=%s", u.Values["app"], u.Values["app"])
+		}},
+		{Name: "deis auth:cancel", Cleanup: true, Run: func(u *load.User) error {
+			if u.Values["registered"] == nil {
+				return load.ErrSkipped
+			}
+			return deis(u, "auth:cancel --username=%s --password=%s --yes", u.Values["username"], testPassword)
+		}},
+	}
+}
+
+// The load spec only runs when LOAD_USERS is set, and then alone; see TestTests. It fails if more
+// of any step's runs fail than LOAD_MAX_ERROR_RATE allows, none by default.
+var _ = Describe("Load", func() {
+	It("drives virtual users through the controller [slow]", func() {
+		o, err := loadOptions()
+		Expect(err).NotTo(HaveOccurred())
+		maxErrorRate := 0.0
+		if rate := os.Getenv("LOAD_MAX_ERROR_RATE"); rate != "" {
+			maxErrorRate, err = strconv.ParseFloat(rate, 64)
+			Expect(err).NotTo(HaveOccurred(), "LOAD_MAX_ERROR_RATE")
+		}
+		prefix := fmt.Sprintf("%sload%d-", resourcePrefix, randSuffix)
+		var flow load.Flow
+		switch driver := envOr("LOAD_DRIVER", load.DriverCLI); driver {
+		case load.DriverCLI:
+			flow = cliLoadFlow(prefix)
+			defer func() {
+				for id := 0; id < o.Users; id++ {
+					os.Remove(settings.Path(os.Getenv("HOME"), fmt.Sprintf("%s%d", prefix, id)))
+				}
+			}()
+		case load.DriverAPI:
+			flow = load.APIFlow(url, prefix, testPassword)
+		default:
+			Fail(fmt.Sprintf("LOAD_DRIVER must be %s or %s, not %q", load.DriverCLI, load.DriverAPI, driver))
+		}
+
+		report, err := load.Run(o, flow)
+		Expect(err).NotTo(HaveOccurred())
+		for _, step := range report.Steps {
+			step.FirstError = secrets.String(step.FirstError)
+		}
+		report.WriteSummary(os.Stdout)
+		if reportDir != "" {
+			Expect(os.MkdirAll(reportDir, 0755)).To(Succeed())
+			Expect(report.Save(filepath.Join(reportDir, "load.json"))).To(Succeed())
+		}
+		Expect(report.Flows).NotTo(BeZero(), "no flows finished")
+		for _, step := range report.Steps {
+			Expect(step.ErrorRate()).To(BeNumerically("<=", maxErrorRate),
+				"%s failed %d of %d times, first with: %s", step.Name, step.Errors, step.Count, step.FirstError)
+		}
+	})
+})
@@ -6,7 +6,7 @@ import (
 )
 
 var _ = Describe("Perms", func() {
-	Context("when logged in as an admin user", func() {
+	Context("when logged in as an admin user [admin]", func() {
 		BeforeEach(func() {
 			login(url, testAdminUser, testAdminPassword)
 		})
@@ -43,7 +43,7 @@ var _ = Describe("Perms", func() {
 		})
 	})
 
-	Context("when logged in as a normal user", func() {
+	Context("when logged in as a normal user [multi-user]", func() {
 		It("can't create, list, or delete admin permissions", func() {
 			output, err := execute("deis perms:create %s --admin", testAdminUser)
 			Expect(err).To(HaveOccurred())
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/deis/workflow/_tests/pkg/parse"
+	"github.com/deis/workflow/_tests/pkg/prop"
+	"github.com/deis/workflow/_tests/pkg/validation"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/config"
+	. "github.com/onsi/gomega"
+)
+
+// propertyInputs is how many generated inputs each property spec tries, PROPERTY_INPUTS or 20 by
+// default.
+func propertyInputs() int {
+	if n, err := strconv.Atoi(os.Getenv("PROPERTY_INPUTS")); err == nil && n > 0 {
+		return n
+	}
+	return 20
+}
+
+// propertySeed seeds the generated inputs: PROPERTY_SEED, to reproduce a failure, or else Ginkgo's
+// random seed.
+func propertySeed() int64 {
+	if seed, err := strconv.ParseInt(os.Getenv("PROPERTY_SEED"), 10, 64); err == nil {
+		return seed
+	}
+	return config.GinkgoConfig.RandomSeed
+}
+
+// checkProperty fails the spec with the shrunk input, and the seed to reproduce it, if property
+// fails for any input generated by gens.
+func checkProperty(gens []prop.Gen, property func(input []string) error) {
+	seed := propertySeed()
+	if deck != nil {
+		// replays must generate the recorded inputs
+		var err error
+		seed, err = strconv.ParseInt(deck.Name(strconv.FormatInt(seed, 10)), 10, 64)
+		Expect(err).NotTo(HaveOccurred(), "the recorded property seed")
+	}
+	fmt.Fprintf(ginkgoOut, "Checking %d inputs with PROPERTY_SEED=%d\n", propertyInputs(), seed)
+	if f := prop.Check(seed, propertyInputs(), gens, property); f != nil {
+		Fail(secrets.String(f.Error()))
+	}
+}
+
+// The property specs check that the CLI and controller accept and refuse app names and config as
+// pkg/validation models them.
+var _ = Describe("Properties", func() {
+
+	Context("of config", func() {
+		var appName string
+
+		BeforeEach(func() {
+			appName = getRandAppName()
+			output, err := execute("deis apps:create %s --no-remote", appName)
+			Expect(err).NotTo(HaveOccurred(), output)
+		})
+
+		AfterEach(func() {
+			destroyApp(appName)
+		})
+
+		It("sets the keys and values the model accepts, and lists them as it predicts", func() {
+			keys := prop.Strings{
+				Valid:      prop.Upper + prop.Lower + prop.Digits + "_",
+				First:      prop.Upper + "_",
+				Max:        12,
+				Boundaries: []int{0, 1, 256},
+			}
+			values := prop.Strings{
+				Valid:      prop.Lower + prop.Upper + prop.Digits + prop.Unicode + prop.ShellMeta + " ",
+				Max:        24,
+				Boundaries: []int{0, 1, 1024},
+			}
+			checkProperty([]prop.Gen{keys.Gen(), values.Gen()}, func(input []string) error {
+				key, value := input[0], input[1]
+				if i := strings.Index(key, "="); i >= 0 {
+					// the CLI splits KEY=value at the first "="
+					key, value = key[:i], key[i+1:]+"="+value
+				}
+				output, err := execute("deis config:set %s -a %s", shellQuote(key+"="+value), appName)
+				refusal := validation.ConfigKey(key)
+				if refusal == nil {
+					refusal = validation.ConfigValue(value)
+				}
+				if refusal != nil {
+					if err == nil {
+						execute("deis config:unset %s -a %s", shellQuote(key), appName)
+						return fmt.Errorf("expected to be refused, since %v, but was set", refusal)
+					}
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("expected to be set, got %v: %s", err, output)
+				}
+				defer execute("deis config:unset %s -a %s", shellQuote(key), appName)
+
+				output, err = execute("deis config:list -a %s", appName)
+				if err != nil {
+					return fmt.Errorf("listing config: %v: %s", err, output)
+				}
+				listed, reliable := validation.Listed(value)
+				if got, ok := parse.ConfigList(output)[key]; !ok {
+					return fmt.Errorf("expected %s to be listed, got %s", key, output)
+				} else if reliable && got != listed {
+					return fmt.Errorf("expected %s to be listed as %q, got %q", key, listed, got)
+				}
+				return nil
+			})
+		})
+	})
+
+	Context("of app names", func() {
+		It("creates the apps the model accepts, and refuses the others", func() {
+			// prefix starts every generated name, so that they are the suite's and can't be taken
+			prefix := fmt.Sprintf("%sprop%d-", resourcePrefix, randSuffix)
+			if deck != nil {
+				prefix = deck.Name(prefix)
+			}
+			names := prop.Strings{
+				Valid:      prop.Lower + prop.Digits + "-",
+				First:      prop.Lower + prop.Digits,
+				Max:        12,
+				Boundaries: []int{0, validation.MaxAppNameLength - len(prefix), validation.MaxAppNameLength - len(prefix) + 1},
+			}
+			checkProperty([]prop.Gen{names.Gen()}, func(input []string) error {
+				name := prefix + input[0]
+				output, err := execute("deis apps:create %s --no-remote", shellQuote(name))
+				if err == nil {
+					defer execute("deis apps:destroy --app=%s --confirm=%s", shellQuote(name), shellQuote(name))
+				}
+				refusal := validation.AppName(name)
+				switch {
+				case refusal != nil && err == nil:
+					return fmt.Errorf("expected %q to be refused, since %v, but it was created", name, refusal)
+				case refusal == nil && err != nil:
+					return fmt.Errorf("expected %q to be created, got %v: %s", name, err, output)
+				case refusal == nil:
+					output, err = execute("deis apps:info -a %s", name)
+					if err != nil {
+						return fmt.Errorf("expected %q to exist, got %v: %s", name, err, output)
+					}
+				}
+				return nil
+			})
+		})
+	})
+})
@@ -6,7 +6,7 @@ import (
 )
 
 var _ = Describe("deis", func() {
-	Context("with a deployed app", func() {
+	Context("with a deployed app [deploy]", func() {
 
 		XIt("can scale upward", func() {
 			// "deis ps:scale web=5 --app=%s"
@@ -1,14 +1,50 @@
 package tests
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
 	"time"
 
+	"github.com/deis/workflow/_tests/pkg/releases"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
 	. "github.com/onsi/gomega/gexec"
 )
 
+// fixtureSource replaces example-go's main.go so that each deployed version of the app responds
+// with a body we choose.
+const fixtureSource = `package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+func main() {
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, %q)
+	})
+	http.ListenAndServe(":"+os.Getenv("PORT"), nil)
+}
+`
+
+// deployFixture commits a version of the app which responds with body and pushes it to the deis
+// remote. It must be run from inside the example-go directory.
+func deployFixture(appName, body string) {
+	err := ioutil.WriteFile("main.go", []byte(fmt.Sprintf(fixtureSource, body)), 0644)
+	Expect(err).NotTo(HaveOccurred())
+	output, err := execute(`git -c user.name=%s -c user.email=%s commit -am "respond with %s"`,
+		testUser, testEmail, body)
+	Expect(err).NotTo(HaveOccurred(), output)
+	sess, err := start("GIT_SSH=%s git push deis master", gitSSH)
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(sess.Err, "2m").Should(Say(`Done, %s:v\d+ deployed to Deis`, appName))
+	Eventually(sess).Should(Exit(0))
+}
+
 var _ = Describe("Releases", func() {
 	Context("with a deployed app", func() {
 		var appName string
@@ -18,15 +54,14 @@ var _ = Describe("Releases", func() {
 			createApp(appName)
 		})
 
-		// 500's everytime
-		XIt("can deploy the app", func() {
+		It("can deploy the app [deploy]", func() {
 			sess, err := start("deis pull deis/example-go -a %s", appName)
 			Expect(err).To(BeNil())
 			Eventually(sess, (10 * time.Minute)).Should(Exit(0))
 			Eventually(sess).Should(Say("Creating build... done"))
 		})
 
-		It("can list releases", func() {
+		It("can list releases [smoke]", func() {
 			sess, err := start("deis releases:list -a %s", appName)
 			Expect(err).To(BeNil())
 			Eventually(sess, (1 * time.Minute)).Should(Exit(0))
@@ -54,5 +89,76 @@ var _ = Describe("Releases", func() {
 			Eventually(sess).Should(Say(`updated:\s+[\w\-\:]+UTC`))
 			Eventually(sess).Should(Say(`uuid:\s+[0-9a-f\-]+`))
 		})
+
+		It("records only the config change in a new release", func() {
+			before, err := releases.Snapshot(deisCLI, appName)
+			Expect(err).NotTo(HaveOccurred())
+			output, err := execute("deis config:set FOO=bar -a %s", appName)
+			Expect(err).NotTo(HaveOccurred(), output)
+			after, err := releases.Snapshot(deisCLI, appName)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(after.Version).To(Equal(before.Version + 1))
+			Expect(releases.Diff(before, after)).To(Equal([]releases.Change{
+				{Field: "config.FOO", Kind: releases.Added, New: "bar"},
+			}))
+		})
+	})
+
+	Context("with two deployed versions of an app [deploy] [slow]", func() {
+		var appName string
+		var oldRelease *releases.Release
+		var newVersion int
+
+		BeforeEach(func() {
+			os.Chdir("example-go")
+			appName = getRandAppName()
+			Eventually(createApp(appName)).Should(Exit(0))
+
+			deployFixture(appName, "fixture one")
+			output, err := execute("deis config:set FIXTURE=one -a %s", appName)
+			Expect(err).NotTo(HaveOccurred(), output)
+			oldRelease, err = releases.Snapshot(deisCLI, appName)
+			Expect(err).NotTo(HaveOccurred())
+
+			deployFixture(appName, "fixture two")
+			output, err = execute("deis config:set FIXTURE=two -a %s", appName)
+			Expect(err).NotTo(HaveOccurred(), output)
+			newVersion, err = releases.Latest(deisCLI, appName)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() (string, error) {
+				return getAppBody(appName)
+			}, "2m", "5s").Should(ContainSubstring("fixture two"))
+		})
+
+		AfterEach(func() {
+			defer os.Chdir("..")
+			destroyApp(appName)
+		})
+
+		It("restores the code and config of the release it rolls back to", func() {
+			sess, err := start("deis releases:rollback v%d -a %s", oldRelease.Version, appName)
+			Expect(err).To(BeNil())
+			Eventually(sess, (1 * time.Minute)).Should(Exit(0))
+			Eventually(sess).Should(Say(`Rolling back to v%d`, oldRelease.Version))
+			Eventually(sess).Should(Say(`done, v%d`, newVersion+1))
+
+			Eventually(func() (string, error) {
+				return getAppBody(appName)
+			}, "2m", "5s").Should(ContainSubstring("fixture one"))
+
+			rollback, err := releases.Snapshot(deisCLI, appName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rollback.Version).To(Equal(newVersion + 1))
+			Expect(rollback.Config).To(HaveKeyWithValue("FIXTURE", "one"))
+			Expect(releases.Diff(oldRelease, rollback)).To(BeEmpty())
+
+			replaced, err := releases.Fetch(deisCLI, appName, newVersion)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rollback.Info["config"]).To(Equal(oldRelease.Info["config"]))
+			Expect(rollback.Info["config"]).NotTo(Equal(replaced.Info["config"]))
+			Expect(rollback.Info["summary"]).To(MatchRegexp(`%s rolled back to v%d`, testUser, oldRelease.Version))
+		})
 	})
 })
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/k8s"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// requireKube skips the current spec unless the suite can see the cluster apps are scheduled on.
+func requireKube() {
+	if kube == nil {
+		Skip("KUBECONFIG is not set")
+	}
+}
+
+// runningPods returns the pods of app's procType processes which are running and not being deleted.
+func runningPods(app, procType string) ([]k8s.Pod, error) {
+	pods, err := kube.PodsLabelled(app, map[string]string{"app": app, "type": procType})
+	if err != nil {
+		return nil, err
+	}
+	var running []k8s.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == "Running" && pod.Metadata.DeletionTimestamp == "" {
+			running = append(running, pod)
+		}
+	}
+	return running, nil
+}
+
+// expectPods waits for app to run exactly n procType pods.
+func expectPods(app, procType string, n int) {
+	Eventually(func() (int, error) {
+		pods, err := runningPods(app, procType)
+		return len(pods), err
+	}, "5m", "2s").Should(Equal(n), "running %s pods of %s", procType, app)
+}
+
+// expectLimit waits for every running procType pod of app to limit resource to value. Memory
+// limits given to the CLI in megabytes may be scheduled in mebibytes, so "64M" matches "64Mi".
+func expectLimit(app, procType, resource, value string) {
+	limit := regexp.MustCompile("(?i)^" + regexp.QuoteMeta(value) + "i?$")
+	Eventually(func() ([]string, error) {
+		pods, err := runningPods(app, procType)
+		if err != nil || len(pods) == 0 {
+			return nil, err
+		}
+		var wrong []string
+		for _, pod := range pods {
+			for _, container := range pod.Spec.Containers {
+				if !limit.MatchString(container.Resources.Limits[resource]) {
+					wrong = append(wrong, pod.Metadata.Name+"/"+container.Name+": "+container.Resources.Limits[resource])
+				}
+			}
+		}
+		if wrong == nil {
+			wrong = []string{}
+		}
+		return wrong, nil
+	}, "5m", "2s").Should(BeEmpty(), "%s limits of %s %s pods", resource, app, procType)
+}
+
+// expectNodeSelector waits for every procType pod of app to be restricted to nodes labelled
+// key=value. Pods waiting for such a node count too.
+func expectNodeSelector(app, procType, key, value string) {
+	Eventually(func() ([]string, error) {
+		pods, err := kube.PodsLabelled(app, map[string]string{"app": app, "type": procType})
+		if err != nil || len(pods) == 0 {
+			return nil, err
+		}
+		var wrong []string
+		for _, pod := range pods {
+			if pod.Metadata.DeletionTimestamp == "" && pod.Spec.NodeSelector[key] != value {
+				wrong = append(wrong, pod.Metadata.Name)
+			}
+		}
+		if wrong == nil {
+			wrong = []string{}
+		}
+		return wrong, nil
+	}, "5m", "2s").Should(BeEmpty(), "pods of %s without the node selector %s=%s", app, key, value)
+}
+
+// expectNamespaceGone waits for the namespace of app to be deleted or on its way out.
+func expectNamespaceGone(app string) {
+	Eventually(func() bool {
+		namespace, err := kube.Namespace(app)
+		if k8s.IsNotFound(err) {
+			return true
+		}
+		return err == nil && namespace.Status.Phase == "Terminating"
+	}, "5m", "2s").Should(BeTrue(), "namespace of %s is still active", app)
+}
+
+var _ = Describe("Scheduling [kube]", func() {
+	var appName string
+	var destroyed bool
+
+	BeforeEach(func() {
+		requireKube()
+		appName = getRandAppName()
+		destroyed = false
+		sess, err := start("deis apps:create %s --no-remote", appName)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(sess).Should(Exit(0))
+		sess, err = start("deis pull deis/example-go -a %s", appName)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(sess, (10 * time.Minute)).Should(Exit(0))
+		Eventually(sess).Should(Say("Creating build... done"))
+	})
+
+	AfterEach(func() {
+		if kube != nil && !destroyed {
+			destroyApp(appName)
+		}
+	})
+
+	It("runs as many pods as the app is scaled to", func() {
+		expectPods(appName, "cmd", 1)
+		sess, err := start("deis ps:scale cmd=4 -a %s", appName)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(sess, (5 * time.Minute)).Should(Exit(0))
+		expectPods(appName, "cmd", 4)
+
+		sess, err = start("deis ps:scale cmd=1 -a %s", appName)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(sess, (5 * time.Minute)).Should(Exit(0))
+		expectPods(appName, "cmd", 1)
+	})
+
+	It("limits the memory of pods", func() {
+		sess, err := start("deis limits:set cmd=64M -a %s", appName)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(sess, (5 * time.Minute)).Should(Exit(0))
+		expectLimit(appName, "cmd", "memory", "64M")
+	})
+
+	It("runs pods only on nodes with the app's tags", func() {
+		sess, err := start("deis tags:set environ=e2e -a %s", appName)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(sess, (5 * time.Minute)).Should(Exit(0))
+		expectNodeSelector(appName, "cmd", "environ", "e2e")
+	})
+
+	It("deletes the namespace of a destroyed app", func() {
+		destroyApp(appName)
+		destroyed = true
+		expectNamespaceGone(appName)
+	})
+})
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+
+	"github.com/deis/workflow/_tests/pkg/cassette"
+	"github.com/deis/workflow/_tests/pkg/fakecontroller"
+	"github.com/deis/workflow/_tests/pkg/settings"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// settingsOf returns the path of the CLI's settings file for profile, "" being its default.
+func settingsOf(profile string) string {
+	return settings.Path(os.Getenv("HOME"), profile)
+}
+
+// expectSettings checks that profile's settings file holds a login as username to controller,
+// and returns it.
+func expectSettings(profile, controller, username string) *settings.Settings {
+	s, err := settings.Load(settingsOf(profile))
+	Expect(err).NotTo(HaveOccurred(), "reading the settings of profile %q", profile)
+	secrets.Add(s.Token)
+	Expect(s.Verify(controller, username)).To(Succeed(), "the settings of profile %q", profile)
+	return s
+}
+
+// expectNoSettings checks that profile has no settings file, as after logging out.
+func expectNoSettings(profile string) {
+	_, err := os.Stat(settingsOf(profile))
+	Expect(os.IsNotExist(err)).To(BeTrue(), "expected no settings for profile %q, got %v", profile, err)
+}
+
+// These specs check what the CLI writes to its settings file under $HOME/.deis, for the suite's
+// profile and for others chosen with DEIS_PROFILE.
+var _ = Describe("Settings", func() {
+	// profile is the suite's own, which BeforeEach logs in with
+	profile := os.Getenv("DEIS_PROFILE")
+
+	It("are written by login", func() {
+		s := expectSettings(profile, url, testUser)
+		Expect(s.SslVerify).To(BeFalse())
+	})
+
+	It("are removed by logout", func() {
+		logout()
+		expectNoSettings(profile)
+	})
+
+	It("keep all but the token after auth:regenerate", func() {
+		requireFeature("auth-regenerate")
+		before := expectSettings(profile, url, testUser)
+		output, err := execute("deis auth:regenerate")
+		Expect(err).NotTo(HaveOccurred(), output)
+		after := expectSettings(profile, url, testUser)
+		if cassetteMode != cassette.Replay {
+			// replayed tokens are all masked alike
+			Expect(after.Token).NotTo(Equal(before.Token))
+		}
+		after.Token = before.Token
+		Expect(*after).To(Equal(*before))
+	})
+
+	Context("of a new user [multi-user]", func() {
+		const newProfile = "settings-new"
+		var username string
+
+		BeforeEach(func() {
+			username = fmt.Sprintf("%s-settings", testUser)
+		})
+
+		AfterEach(func() {
+			// cancel the user if the spec failed before it could
+			if _, err := os.Stat(settingsOf(newProfile)); err == nil {
+				execute("DEIS_PROFILE=%s deis auth:cancel --username=%s --password=%s --yes", newProfile, username, testPassword)
+				os.Remove(settingsOf(newProfile))
+			}
+		})
+
+		It("are written by register and removed by auth:cancel", func() {
+			output, err := execute("DEIS_PROFILE=%s deis register %s --username=%s --password=%s --email=%s@deis.io",
+				newProfile, url, username, testPassword, username)
+			Expect(err).NotTo(HaveOccurred(), output)
+			expectSettings(newProfile, url, username)
+			expectSettings(profile, url, testUser)
+
+			output, err = execute("DEIS_PROFILE=%s deis auth:cancel --username=%s --password=%s --yes", newProfile, username, testPassword)
+			Expect(err).NotTo(HaveOccurred(), output)
+			expectNoSettings(newProfile)
+			expectSettings(profile, url, testUser)
+		})
+	})
+
+	// staging and prod are fake controllers, each logged in to through a profile of its own, as
+	// an engineer switches between clusters with DEIS_PROFILE
+	Context("of two controllers", func() {
+		profiles := []string{"staging", "prod"}
+		var servers map[string]*httptest.Server
+
+		// deis runs the CLI with the profile of one of the controllers
+		deis := func(profile, format string, args ...interface{}) (string, error) {
+			return execute("DEIS_PROFILE=%s deis %s", profile, fmt.Sprintf(format, args...))
+		}
+
+		BeforeEach(func() {
+			servers = make(map[string]*httptest.Server)
+			for _, p := range profiles {
+				servers[p] = httptest.NewServer(fakecontroller.NewServer())
+				output, err := deis(p, "register %s --username=%s-user --password=%s --email=%s@example.com",
+					servers[p].URL, p, testPassword, p)
+				Expect(err).NotTo(HaveOccurred(), output)
+				expectSettings(p, servers[p].URL, p+"-user")
+			}
+		})
+
+		AfterEach(func() {
+			for _, p := range profiles {
+				servers[p].Close()
+				os.Remove(settingsOf(p))
+			}
+		})
+
+		It("stay logged in to each, and leave the suite's profile alone", func() {
+			for _, p := range profiles {
+				output, err := deis(p, "auth:whoami")
+				Expect(err).NotTo(HaveOccurred(), output)
+				Expect(output).To(ContainSubstring("You are %s-user", p))
+			}
+			expectSettings(profile, url, testUser)
+		})
+
+		It("keep each controller's apps apart", func() {
+			for _, p := range profiles {
+				output, err := deis(p, "apps:create %s-app --no-remote", p)
+				Expect(err).NotTo(HaveOccurred(), output)
+			}
+			output, err := deis("staging", "apps:list")
+			Expect(err).NotTo(HaveOccurred(), output)
+			Expect(output).To(ContainSubstring("staging-app"))
+			Expect(output).NotTo(ContainSubstring("prod-app"))
+			output, err = deis("prod", "apps:list")
+			Expect(err).NotTo(HaveOccurred(), output)
+			Expect(output).To(ContainSubstring("prod-app"))
+			Expect(output).NotTo(ContainSubstring("staging-app"))
+		})
+
+		It("log out of one and stay logged in to the other", func() {
+			prod, err := ioutil.ReadFile(settingsOf("prod"))
+			Expect(err).NotTo(HaveOccurred())
+			output, err := deis("staging", "auth:logout")
+			Expect(err).NotTo(HaveOccurred(), output)
+			expectNoSettings("staging")
+
+			output, err = deis("staging", "auth:whoami")
+			Expect(err).To(HaveOccurred(), output)
+			Expect(output).To(ContainSubstring("Not logged in"))
+			Expect(ioutil.ReadFile(settingsOf("prod"))).To(Equal(prod))
+			output, err = deis("prod", "auth:whoami")
+			Expect(err).NotTo(HaveOccurred(), output)
+		})
+
+		It("regenerate the token of one and not the other", func() {
+			requireFeature("auth-regenerate")
+			staging := expectSettings("staging", servers["staging"].URL, "staging-user")
+			prod := expectSettings("prod", servers["prod"].URL, "prod-user")
+			output, err := deis("staging", "auth:regenerate")
+			Expect(err).NotTo(HaveOccurred(), output)
+			Expect(expectSettings("staging", servers["staging"].URL, "staging-user").Token).NotTo(Equal(staging.Token))
+			Expect(*expectSettings("prod", servers["prod"].URL, "prod-user")).To(Equal(*prod))
+		})
+
+		It("log in to one with SSL verification, and not the other", func() {
+			output, err := deis("staging", "login %s --username=staging-user --password=%s --ssl-verify=true",
+				servers["staging"].URL, testPassword)
+			Expect(err).NotTo(HaveOccurred(), output)
+			Expect(expectSettings("staging", servers["staging"].URL, "staging-user").SslVerify).To(BeTrue())
+			Expect(expectSettings("prod", servers["prod"].URL, "prod-user").SslVerify).To(BeFalse())
+		})
+	})
+})
@@ -6,7 +6,7 @@ import (
 )
 
 var _ = Describe("Tags", func() {
-	Context("with a deployed app", func() {
+	Context("with a deployed app [deploy]", func() {
 
 		// TODO: does "deis tag" have a k8s implementation?
 		XIt("can set a tag", func() {
@@ -2,26 +2,46 @@ package tests
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	neturl "net/url"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/deis/workflow/_tests/pkg/artifacts"
+	"github.com/deis/workflow/_tests/pkg/bench"
+	"github.com/deis/workflow/_tests/pkg/cassette"
+	"github.com/deis/workflow/_tests/pkg/cluster"
+	"github.com/deis/workflow/_tests/pkg/compat"
+	"github.com/deis/workflow/_tests/pkg/k8s"
+	"github.com/deis/workflow/_tests/pkg/labels"
+	"github.com/deis/workflow/_tests/pkg/parse"
+	"github.com/deis/workflow/_tests/pkg/quarantine"
+	"github.com/deis/workflow/_tests/pkg/redact"
+	"github.com/deis/workflow/_tests/pkg/report"
+	"github.com/deis/workflow/_tests/pkg/schema"
+	"github.com/deis/workflow/_tests/pkg/settings"
+	"github.com/deis/workflow/_tests/pkg/transcript"
 	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/config"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
 	. "github.com/onsi/gomega/gexec"
 )
 
 const (
-	deisRouterServiceHost = "DEIS_ROUTER_SERVICE_HOST"
-	deisRouterServicePort = "DEIS_ROUTER_SERVICE_PORT"
+	deisRouterServiceHost = cluster.RouterHostEnv
+	deisRouterServicePort = cluster.RouterPortEnv
 )
 
 var (
@@ -30,46 +50,301 @@ var (
 
 func init() {
 	rand.Seed(time.Now().UnixNano())
+	secrets.Add(testPassword)
+	// the default admin password is no secret, and masking it would mask the admin's name too
+	if testAdminPassword != testAdminUser {
+		secrets.Add(testAdminPassword)
+	}
+	for _, key := range strings.Split(os.Getenv("SENSITIVE_CONFIG_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			sensitiveConfigKeys = append(sensitiveConfigKeys, key)
+		}
+	}
 }
 
 func getRandAppName() string {
-	return fmt.Sprintf("test-%d", rand.Intn(999999999))
+	name := fmt.Sprintf("%s%d", resourcePrefix, rand.Intn(999999999))
+	if deck != nil {
+		name = deck.Name(name)
+	}
+	specAppsMu.Lock()
+	specApps = append(specApps, name)
+	specAppsMu.Unlock()
+	return name
 }
 
 func TestTests(t *testing.T) {
-	RegisterFailHandler(Fail)
-	RunSpecs(t, "Deis Workflow")
+	RegisterFailHandler(failWithArtifacts)
+	if quarantineFile == "" {
+		quarantineFile = "quarantine.yaml"
+	}
+	quarantined, err := quarantine.Load(quarantineFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compatFile == "" {
+		matrix, err = compat.LoadDefault("compat.yaml")
+	} else {
+		matrix, err = compat.Load(compatFile)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reporters []Reporter
+	if quarantinePass {
+		if len(quarantined) == 0 {
+			t.Skip("no specs are quarantined")
+		}
+		// run only the quarantined specs, reporting on them separately from the normal run
+		config.GinkgoConfig.FocusStrings = append(config.GinkgoConfig.FocusStrings, quarantined.Regexp())
+		if reportDir != "" {
+			reportDir = filepath.Join(reportDir, "quarantine")
+		}
+		results := ""
+		if reportDir != "" {
+			results = filepath.Join(reportDir, "quarantine.json")
+		}
+		reporters = append(reporters, quarantine.NewReporter(quarantined, os.Stdout, results))
+	} else if len(quarantined) > 0 {
+		config.GinkgoConfig.SkipStrings = append(config.GinkgoConfig.SkipStrings, quarantined.Regexp())
+	}
+	if benchHistory != "" {
+		reporters = append(reporters, bench.NewReporter(benchHistory, os.Getenv("BENCH_LABEL")))
+	} else {
+		config.GinkgoConfig.SkipStrings = append(config.GinkgoConfig.SkipStrings, `\[Top Level\] Benchmarks `)
+	}
+	switch upgradePhase {
+	case "":
+		config.GinkgoConfig.SkipStrings = append(config.GinkgoConfig.SkipStrings, `\[Top Level\] Upgrade `)
+	case "seed", "verify":
+		if upgradeManifest == "" {
+			t.Fatal("UPGRADE_PHASE needs UPGRADE_MANIFEST, the path of the manifest the seed phase writes")
+		}
+		// run only the phase's spec
+		config.GinkgoConfig.FocusStrings = append(config.GinkgoConfig.FocusStrings, `\[Top Level\] Upgrade `+upgradePhase+` phase `)
+	default:
+		t.Fatalf("UPGRADE_PHASE must be seed or verify, not %q", upgradePhase)
+	}
+	if loadUsers == "" {
+		config.GinkgoConfig.SkipStrings = append(config.GinkgoConfig.SkipStrings, `\[Top Level\] Load `)
+	} else {
+		// run only the load spec
+		config.GinkgoConfig.FocusStrings = append(config.GinkgoConfig.FocusStrings, `\[Top Level\] Load `)
+	}
+	if safeMode {
+		if os.Getenv("TEST_ADMIN_PASSWORD") == "" {
+			t.Fatal("safe mode needs the admin's real password in TEST_ADMIN_PASSWORD")
+		}
+		config.GinkgoConfig.SkipStrings = append(config.GinkgoConfig.SkipStrings, labels.Regexp([]string{labels.Destructive}))
+	}
+	// handler, if set, stands between the CLI and the controller
+	var handler http.Handler
+	switch cassetteMode {
+	case "":
+	case cassette.Record, cassette.Replay:
+		if cassetteDir == "" {
+			t.Fatal("CASSETTE_MODE needs CASSETTE_DIR, the directory the cassettes are kept in")
+		}
+		// git pushes go to the builder and scheduling to Kubernetes, neither of which is recorded
+		// nor replayed
+		config.GinkgoConfig.SkipStrings = append(config.GinkgoConfig.SkipStrings, labels.Regexp([]string{labels.Deploy, labels.Kube}))
+		if cassetteMode == cassette.Record {
+			target, err := neturl.Parse(url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder, err := cassette.NewRecorder(target, cassetteDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder.Redact = secrets.String
+			deck = recorder
+		} else {
+			deck = cassette.NewPlayer(cassetteDir)
+		}
+		handler = deck
+	default:
+		t.Fatalf("CASSETTE_MODE must be %s or %s, not %q", cassette.Record, cassette.Replay, cassetteMode)
+	}
+	switch schemaCheck {
+	case "":
+	case schema.Warn, schema.Fail:
+		// the CLI addresses the builder by the controller's host name, which becomes the checker's
+		config.GinkgoConfig.SkipStrings = append(config.GinkgoConfig.SkipStrings, labels.Regexp([]string{labels.Deploy}))
+		set, err := schema.Load(schemaDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if handler == nil {
+			target, err := neturl.Parse(url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler = cluster.ReverseProxy(target)
+		}
+		checker = schema.NewChecker(set, handler)
+		handler = checker
+	default:
+		t.Fatalf("SCHEMA_CHECK must be %s or %s, not %q", schema.Warn, schema.Fail, schemaCheck)
+	}
+	if handler != nil {
+		// the CLI talks to the handler instead of the controller
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		url = server.URL
+	}
+	if reportDir != "" {
+		commands = transcript.NewRecorder()
+		suiteReporter = report.NewReporter(reportDir, commands)
+		suiteReporter.Redact = secrets.String
+		reporters = append(reporters, suiteReporter)
+	}
+	if len(reporters) > 0 {
+		RunSpecsWithDefaultAndCustomReporters(t, "Deis Workflow", reporters)
+	} else {
+		RunSpecs(t, "Deis Workflow")
+	}
 }
 
 var (
-	randSuffix        = rand.Intn(1000)
-	testUser          = fmt.Sprintf("test-%d", randSuffix)
+	randSuffix = rand.Intn(1000)
+	// resourcePrefix starts the name of every user, app and key the suite creates
+	resourcePrefix    = envOr("RESOURCE_PREFIX", "test-")
+	testUser          = fmt.Sprintf("%s%d", resourcePrefix, randSuffix)
 	testPassword      = "asdf1234"
-	testEmail         = fmt.Sprintf("test-%d@deis.io", randSuffix)
-	testAdminUser     = "admin"
-	testAdminPassword = "admin"
-	testAdminEmail    = "admin@example.com"
-	keyName           = fmt.Sprintf("deiskey-%v", randSuffix)
-	url               = getController()
-	debug             = os.Getenv("DEBUG") != ""
-	homeHome          = os.Getenv("HOME")
+	testEmail         = fmt.Sprintf("%s@deis.io", testUser)
+	testAdminUser     = envOr("TEST_ADMIN_USER", "admin")
+	testAdminPassword = envOr("TEST_ADMIN_PASSWORD", "admin")
+	testAdminEmail    = envOr("TEST_ADMIN_EMAIL", "admin@example.com")
+	keyName           = fmt.Sprintf("%skey-%d", resourcePrefix, randSuffix)
+	// safeMode protects a cluster that real people use: destructive specs are skipped, the admin
+	// account is only logged in to, never registered or cancelled, and the suite refuses to run if
+	// the test user already owns apps it did not create
+	safeMode = os.Getenv("SAFE_MODE") != ""
+	// controller is the controller's URL through the router, and url the one the CLI talks to:
+	// the same, unless TestTests puts a recorder or schema checker in between
+	controller = getController()
+	url        = controller
+	debug      = os.Getenv("DEBUG") != ""
+	homeHome   = os.Getenv("HOME")
+	// reportDir is where JSON and JUnit reports are written, if set
+	reportDir = os.Getenv("REPORT_DIR")
+	// artifactsDir is where failing specs save what the controller and cluster know about their apps, if set
+	artifactsDir = os.Getenv("ARTIFACTS_DIR")
+	kubeconfig   = os.Getenv("KUBECONFIG")
+	// kube talks to the cluster the controller schedules apps on, if kubeconfig is set
+	kube *k8s.Client
+	// quarantineFile lists the specs which are skipped by normal runs, quarantine.yaml by default
+	quarantineFile = os.Getenv("QUARANTINE_FILE")
+	// quarantinePass runs only the quarantined specs
+	quarantinePass = os.Getenv("QUARANTINE") != ""
+	// compatFile declares which CLI releases work with which controller API versions, compat.yaml
+	// by default; unlike the default, a file named here must exist
+	compatFile = os.Getenv("COMPAT_FILE")
+	// benchHistory is the file benchmark results are appended to; benchmarks only run if it is set
+	benchHistory = os.Getenv("BENCH_HISTORY")
+	// upgradePhase is "seed" or "verify" to run only that phase of the upgrade test
+	upgradePhase = os.Getenv("UPGRADE_PHASE")
+	// loadUsers is how many virtual users the load spec runs; it only runs if this is set
+	loadUsers = os.Getenv("LOAD_USERS")
+	// upgradeManifest is where the seed phase records what it created for the verify phase
+	upgradeManifest = os.Getenv("UPGRADE_MANIFEST")
+	// matrix is read from compatFile
+	matrix *compat.Matrix
+	// versions are those of the CLI and controller under test, found by BeforeSuite
+	versions compat.Versions
+	// suiteReporter writes the JSON and JUnit reports, if reportDir is set
+	suiteReporter *report.Reporter
+	// cassetteMode is "record" to record the traffic between the CLI and the controller into
+	// cassettes in cassetteDir, or "replay" to answer the CLI from them without a cluster
+	cassetteMode = os.Getenv("CASSETTE_MODE")
+	cassetteDir  = os.Getenv("CASSETTE_DIR")
+	// deck records or replays cassettes, if cassetteMode is set
+	deck cassette.Deck
+	// schemaCheck is "warn" or "fail" to check the controller's responses against the schemas in
+	// schemaDir, and report or fail the specs seeing responses which differ
+	schemaCheck = os.Getenv("SCHEMA_CHECK")
+	schemaDir   = envOr("SCHEMA_DIR", "schemas")
+	// fuzzCorpus holds the inputs which once made the CLI misbehave, replayed by every run; fuzz/
+	// by default
+	fuzzCorpus = envOr("FUZZ_CORPUS", "fuzz")
+	// helpSnapshots holds what `deis help <command>` printed for each command, help/ by default
+	helpSnapshots = envOr("HELP_SNAPSHOTS", "help")
+	// checker checks the controller's responses, if schemaCheck is set
+	checker *schema.Checker
+	// commands records every command run by execute and start when reports are written
+	commands *transcript.Recorder
+	// secrets are masked in debug output, GinkgoWriter, reports and artifacts
+	secrets = redact.New()
+	// ginkgoOut is GinkgoWriter with secrets masked
+	ginkgoOut = secrets.Writer(GinkgoWriter)
+	// sensitiveConfigKeys are config keys, besides those matching sensitiveConfigRegex, whose
+	// values are secrets
+	sensitiveConfigKeys  []string
+	sensitiveConfigRegex = regexp.MustCompile(`(?i)(PASSWORD|SECRET|TOKEN|KEY)`)
+	configPairRegex      = regexp.MustCompile(`(\w+)=("[^"]*"|'[^']*'|\S+)`)
 )
 
 var testRoot, testHome, keyPath, gitSSH string
 
+var (
+	collector *artifacts.Collector
+	// specApps holds the app names handed out during the current spec
+	specApps           []string
+	specAppsMu         sync.Mutex
+	artifactsCollected bool
+)
+
 var _ = BeforeSuite(func() {
 	SetDefaultEventuallyTimeout(10 * time.Second)
+	insertCassette("BeforeSuite")
+	if deck != nil {
+		// replays must use the recorded names
+		testUser = deck.Name(testUser)
+		testEmail = fmt.Sprintf("%s@deis.io", testUser)
+		keyName = deck.Name(keyName)
+	}
+
+	if kubeconfig != "" {
+		var err error
+		kube, err = k8s.NewClientFromKubeconfig(kubeconfig)
+		Expect(err).NotTo(HaveOccurred())
+	}
+	if artifactsDir != "" {
+		collector = &artifacts.Collector{Dir: artifactsDir, Run: deisCLI, Redact: secrets.String, Kube: kube}
+	}
 
 	// use the "deis" executable in the search $PATH
 	output, err := exec.LookPath("deis")
 	Expect(err).NotTo(HaveOccurred(), output)
 
+	// find out which versions are under test, and whether they should work together
+	output, err = execute("deis --version")
+	Expect(err).NotTo(HaveOccurred(), output)
+	versions.CLI = strings.TrimSpace(output)
+	versions.API, err = compat.APIVersion(http.DefaultClient, url)
+	Expect(err).NotTo(HaveOccurred())
+	versions.Builder = os.Getenv("BUILDER_VERSION")
+	if versions.Builder != "" {
+		fmt.Fprintf(ginkgoOut, "Testing CLI %s and builder %s against API %s\n", versions.CLI, versions.Builder, versions.API)
+	} else {
+		fmt.Fprintf(ginkgoOut, "Testing CLI %s against API %s\n", versions.CLI, versions.API)
+	}
+	if suiteReporter != nil {
+		suiteReporter.Versions = versions.Map()
+	}
+	Expect(matrix.Check(versions)).To(Succeed())
+
 	testHome, err = ioutil.TempDir("", "deis-workflow-home")
 	Expect(err).NotTo(HaveOccurred())
 	os.Setenv("HOME", testHome)
 
-	// register the test-admin user
-	registerOrLogin(url, testAdminUser, testAdminPassword, testAdminEmail)
+	// register the test-admin user, unless it must already exist
+	if safeMode {
+		login(url, testAdminUser, testAdminPassword)
+	} else {
+		registerOrLogin(url, testAdminUser, testAdminPassword, testAdminEmail)
+	}
 
 	// verify this user is an admin by running a privileged command
 	sess, err := start("deis users:list")
@@ -80,6 +355,9 @@ var _ = BeforeSuite(func() {
 
 	// register the test user and add a key
 	registerOrLogin(url, testUser, testPassword, testEmail)
+	if safeMode {
+		checkOwnApps()
+	}
 
 	keyPath = createKey(keyName)
 
@@ -98,37 +376,56 @@ var _ = BeforeSuite(func() {
 	Eventually(sess).Should(Exit(0))
 	Eventually(sess).Should(Say("Uploading %s.pub to deis... done", keyName))
 
-	time.Sleep(5 * time.Second) // wait for ssh key to propagate
+	if cassetteMode != cassette.Replay {
+		time.Sleep(5 * time.Second) // wait for ssh key to propagate
+	}
+	ejectCassette()
+	checkSchemas()
 })
 
 var _ = BeforeEach(func() {
 	var err error
 	var output string
 
+	specAppsMu.Lock()
+	specApps = nil
+	specAppsMu.Unlock()
+	artifactsCollected = false
+	insertCassette(CurrentGinkgoTestDescription().FullTestText)
+
 	testRoot, err = ioutil.TempDir("", "deis-workflow-test")
 	Expect(err).NotTo(HaveOccurred())
 
 	os.Chdir(testRoot)
-	output, err = execute(`git clone https://github.com/deis/example-go.git`)
-	Expect(err).NotTo(HaveOccurred(), output)
+	// replays run offline, and only deploy specs, which they skip, need the example app
+	if cassetteMode != cassette.Replay {
+		output, err = execute(`git clone https://github.com/deis/example-go.git`)
+		Expect(err).NotTo(HaveOccurred(), output)
+	}
 
 	login(url, testUser, testPassword)
 })
 
 var _ = AfterEach(func() {
+	checkSchemas()
+	ginkgoOut.Flush()
 	err := os.RemoveAll(testRoot)
 	Expect(err).NotTo(HaveOccurred())
+	ejectCassette()
 })
 
 var _ = AfterSuite(func() {
+	insertCassette("AfterSuite")
 	cancelUserSess, cancelUserErr := cancelSess(url, testUser, testPassword)
-	cancelAdminSess, cancelAdminErr := cancelSess(url, testAdminUser, testAdminPassword)
-
 	Expect(cancelUserErr).To(BeNil())
-	Expect(cancelAdminErr).To(BeNil())
-
 	cancelUserSess.Wait(10 * time.Second)
-	cancelAdminSess.Wait(10 * time.Second)
+
+	if !safeMode {
+		cancelAdminSess, cancelAdminErr := cancelSess(url, testAdminUser, testAdminPassword)
+		Expect(cancelAdminErr).To(BeNil())
+		cancelAdminSess.Wait(10 * time.Second)
+	}
+	ejectCassette()
 
 	os.RemoveAll(fmt.Sprintf("~/.ssh/%s*", keyName))
 
@@ -138,11 +435,125 @@ var _ = AfterSuite(func() {
 	os.Setenv("HOME", homeHome)
 })
 
+// checkOwnApps aborts the suite if the logged in test user owns apps outside resourcePrefix, since
+// the user then belongs to someone else and specs could destroy their apps.
+func checkOwnApps() {
+	output, err := deisCLI("apps:list")
+	Expect(err).NotTo(HaveOccurred(), output)
+	var foreign []string
+	for _, app := range parse.AppsList(output) {
+		if !strings.HasPrefix(app, resourcePrefix) {
+			foreign = append(foreign, app)
+		}
+	}
+	if len(foreign) > 0 {
+		Fail(fmt.Sprintf("safe mode: %s already owns apps the suite did not create (%s); refusing to run",
+			testUser, strings.Join(foreign, ", ")))
+	}
+}
+
+// insertCassette starts recording or replaying the named cassette, if cassettes are in use.
+func insertCassette(name string) {
+	if deck != nil {
+		Expect(deck.Insert(name)).To(Succeed())
+	}
+}
+
+// ejectCassette saves the current cassette when recording, and checks that every request was
+// answered when replaying.
+func ejectCassette() {
+	if deck != nil {
+		Expect(deck.Eject()).To(Succeed())
+	}
+}
+
+// checkSchemas reports the controller's responses which differed from their schemas since the
+// last check, failing the spec if schemaCheck is "fail", and notes the endpoints without schemas.
+func checkSchemas() {
+	if checker == nil {
+		return
+	}
+	problems, unknown := checker.Take()
+	if len(unknown) > 0 {
+		fmt.Fprintf(ginkgoOut, "No schemas for %s\n", strings.Join(unknown, ", "))
+	}
+	if len(problems) == 0 {
+		return
+	}
+	message := fmt.Sprintf("The controller's responses differ from their schemas in %s:\n  %s", schemaDir, strings.Join(problems, "\n  "))
+	if schemaCheck == schema.Fail {
+		Fail(message)
+	}
+	fmt.Fprintln(ginkgoOut, message)
+}
+
+// supports reports whether the CLI and controller under test both have the named feature from the
+// compatibility matrix, for specs which adapt to it.
+func supports(feature string) bool {
+	ok, err := matrix.Supports(feature, versions)
+	Expect(err).NotTo(HaveOccurred())
+	return ok
+}
+
+// requireFeature skips the current spec unless the CLI and controller under test both have the
+// named feature.
+func requireFeature(feature string) {
+	if !supports(feature) {
+		Skip(fmt.Sprintf("CLI %s with API %s lacks %s", versions.CLI, versions.API, feature))
+	}
+}
+
+// envOr returns the value of the environment variable name, or def if it is unset.
+func envOr(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// failWithArtifacts is the suite's fail handler. It saves artifacts for the failing spec's apps
+// while they still exist, before AfterEach destroys them.
+func failWithArtifacts(message string, callerSkip ...int) {
+	if collector != nil && !artifactsCollected {
+		artifactsCollected = true
+		collectArtifacts()
+	}
+	skip := 1
+	if len(callerSkip) > 0 {
+		skip += callerSkip[0]
+	}
+	Fail(secrets.String(message), skip)
+}
+
+// collectArtifacts saves artifacts for those apps named during the current spec which exist.
+func collectArtifacts() {
+	output, err := deisCLI("apps:list")
+	if err != nil {
+		fmt.Fprintf(ginkgoOut, "could not list apps for artifacts: %v\n%s", err, output)
+		return
+	}
+	var apps []string
+	specAppsMu.Lock()
+	for _, name := range specApps {
+		if regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(name) + `$`).MatchString(output) {
+			apps = append(apps, name)
+		}
+	}
+	specAppsMu.Unlock()
+
+	dir, err := collector.Collect(CurrentGinkgoTestDescription().FullTestText, apps)
+	if err != nil {
+		fmt.Fprintln(ginkgoOut, err)
+	}
+	fmt.Fprintf(ginkgoOut, "Saved artifacts for %v to %s\n", apps, dir)
+}
+
 func register(url, username, password, email string) {
 	sess, err := start("deis register %s --username=%s --password=%s --email=%s", url, username, password, email)
 	Expect(err).To(BeNil())
 	Eventually(sess).Should(Say("Registered %s", username))
 	Eventually(sess).Should(Say("Logged in as %s", username))
+	rememberToken()
 }
 
 func registerOrLogin(url, username, password, email string) {
@@ -160,6 +571,7 @@ func registerOrLogin(url, username, password, email string) {
 		Eventually(sess).Should(SatisfyAll(
 			Say("Registered %s", username),
 			Say("Logged in as %s", username)))
+		rememberToken()
 	}
 }
 
@@ -170,7 +582,7 @@ func cancelSess(url, user, pass string) (*Session, error) {
 	}
 	lgSess.Wait()
 	cmd := exec.Command("deis", "auth:cancel", fmt.Sprintf("--username=%s", user), fmt.Sprintf("--password=%s", pass), "--yes")
-	return Start(cmd, GinkgoWriter, GinkgoWriter)
+	return startCmd(strings.Join(cmd.Args, " "), cmd)
 }
 
 func cancel(url, username, password string) {
@@ -186,7 +598,7 @@ func cancel(url, username, password string) {
 
 func loginSess(url, user, pass string) (*Session, error) {
 	cmd := exec.Command("deis", "login", url, fmt.Sprintf("--username=%s", user), fmt.Sprintf("--password=%s", pass))
-	return Start(cmd, GinkgoWriter, GinkgoWriter)
+	return startCmd(strings.Join(cmd.Args, " "), cmd)
 }
 
 func login(url, user, password string) {
@@ -194,6 +606,40 @@ func login(url, user, password string) {
 	Expect(err).To(BeNil())
 	Eventually(sess).Should(Exit(0))
 	Eventually(sess).Should(Say("Logged in as %s", user))
+	rememberToken()
+}
+
+// rememberToken registers the token in the client settings file as a secret.
+func rememberToken() {
+	s, err := settings.Load(settings.Path(os.Getenv("HOME"), os.Getenv("DEIS_PROFILE")))
+	if err == nil {
+		secrets.Add(s.Token)
+	}
+}
+
+// rememberSensitiveConfig registers as secrets the values given to sensitive keys by a
+// "deis config:set" command line.
+func rememberSensitiveConfig(cmdLine string) {
+	if !strings.Contains(cmdLine, "config:set") {
+		return
+	}
+	for _, match := range configPairRegex.FindAllStringSubmatch(cmdLine, -1) {
+		if isSensitiveConfigKey(match[1]) {
+			secrets.Add(strings.Trim(match[2], `"'`))
+		}
+	}
+}
+
+func isSensitiveConfigKey(key string) bool {
+	if sensitiveConfigRegex.MatchString(key) {
+		return true
+	}
+	for _, k := range sensitiveConfigKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
 }
 
 func logout() {
@@ -208,30 +654,64 @@ func logout() {
 func execute(cmdLine string, args ...interface{}) (string, error) {
 	var cmd *exec.Cmd
 	shCommand := fmt.Sprintf(cmdLine, args...)
+	rememberSensitiveConfig(shCommand)
 
 	if debug {
-		fmt.Println(shCommand)
+		fmt.Println(secrets.String(shCommand))
 	}
 
+	var output, stdout, stderr transcript.Buffer
 	cmd = exec.Command("/bin/sh", "-c", shCommand)
-	outputBytes, err := cmd.CombinedOutput()
-
-	output := string(outputBytes)
+	cmd.Stdout = io.MultiWriter(&output, &stdout)
+	cmd.Stderr = io.MultiWriter(&output, &stderr)
+	entry := commands.Begin(shCommand, &stdout, &stderr)
+	err := cmd.Run()
+	entry.End(transcript.ExitCode(err))
 
 	if debug {
-		fmt.Println(output)
+		fmt.Println(secrets.String(string(output.Contents())))
+	}
+
+	return string(output.Contents()), err
+}
+
+// deisCLI runs "deis" with args through execute, quoting each one. It satisfies releases.Runner.
+func deisCLI(args ...string) (string, error) {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
 	}
+	return execute("deis %s", strings.Join(quoted, " "))
+}
 
-	return output, err
+// shellQuote quotes s as a single /bin/sh word.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
 }
 
 func start(cmdLine string, args ...interface{}) (*Session, error) {
 	cmdStr := fmt.Sprintf(cmdLine, args...)
+	rememberSensitiveConfig(cmdStr)
 	if debug {
-		fmt.Println(cmdStr)
+		fmt.Println(secrets.String(cmdStr))
 	}
 	cmd := exec.Command("/bin/sh", "-c", cmdStr)
-	return Start(cmd, GinkgoWriter, GinkgoWriter)
+	return startCmd(cmdStr, cmd)
+}
+
+// startCmd starts cmd, recording it in the current spec's transcript as cmdStr.
+func startCmd(cmdStr string, cmd *exec.Cmd) (*Session, error) {
+	sess, err := Start(cmd, ginkgoOut, ginkgoOut)
+	if err != nil {
+		commands.Begin(cmdStr, nil, nil).End(-1)
+		return nil, err
+	}
+	entry := commands.Begin(cmdStr, sess.Out, sess.Err)
+	go func() {
+		<-sess.Exited
+		entry.End(sess.ExitCode())
+	}()
+	return sess, nil
 }
 
 func createKey(name string) string {
@@ -246,35 +726,31 @@ func createKey(name string) string {
 
 	os.Chmod(keyPath, 0600)
 
+	// the private key never belongs in a log
+	if key, err := ioutil.ReadFile(keyPath); err == nil {
+		for _, line := range strings.Split(string(key), "\n") {
+			if !strings.HasPrefix(line, "-----") {
+				secrets.Add(line)
+			}
+		}
+	}
+
 	return keyPath
 }
 
 func getController() string {
-	host := os.Getenv(deisRouterServiceHost)
-	if host == "" {
+	if cassetteMode == cassette.Replay {
+		// TestTests points the CLI at the cassettes instead
+		return ""
+	}
+	controller, err := cluster.Controller(os.Getenv(deisRouterServiceHost), os.Getenv(deisRouterServicePort))
+	if err == cluster.ErrMissingRouterHost {
 		panicStr := fmt.Sprintf(`Set the router host and port for tests, such as:
 
 $ %s=192.0.2.10 %s=31182 make test-integration`, deisRouterServiceHost, deisRouterServicePort)
 		panic(panicStr)
 	}
-	// Make a xip.io URL if DEIS_ROUTER_SERVICE_HOST is an IP V4 address
-	ipv4Regex := `^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])$`
-	matched, err := regexp.MatchString(ipv4Regex, host)
-	if err != nil {
-		panic(err)
-	}
-	if matched {
-		host = fmt.Sprintf("deis.%s.xip.io", host)
-	}
-	port := os.Getenv(deisRouterServicePort)
-	switch port {
-	case "443":
-		return "https://" + host
-	case "80", "":
-		return "http://" + host
-	default:
-		return fmt.Sprintf("http://%s:%s", host, port)
-	}
+	return controller
 }
 
 // getRawRouter returns the URL to the deis router according to env vars.
@@ -296,6 +772,38 @@ func getRawRouter() (*neturl.URL, error) {
 	}
 }
 
+// getAppURL returns the URL at which the router serves the named app.
+//
+// Apps are served from the same domain as the controller, so "deis.10.0.0.1.xip.io" becomes
+// "<name>.10.0.0.1.xip.io".
+func getAppURL(name string) string {
+	u, err := neturl.Parse(controller)
+	if err != nil {
+		panic(err)
+	}
+	u.Host = name + "." + strings.TrimPrefix(u.Host, "deis.")
+	return u.String()
+}
+
+// getAppBody fetches the named app's root page through the router and returns the response body.
+//
+// Returns an error if the request failed or the app did not respond with 200 OK.
+func getAppBody(name string) (string, error) {
+	resp, err := http.Get(getAppURL(name))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return string(body), fmt.Errorf("%s returned %s", getAppURL(name), resp.Status)
+	}
+	return string(body), nil
+}
+
 func createApp(name string) *Session {
 	cmd, err := start("deis apps:create %s", name)
 	Expect(err).NotTo(HaveOccurred())
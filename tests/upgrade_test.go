@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/deis/workflow/_tests/pkg/parse"
+	"github.com/deis/workflow/_tests/pkg/upgrade"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// upgradeApps is how many apps the seed phase creates.
+const upgradeApps = 2
+
+// The upgrade phases only run when UPGRADE_PHASE is set, one at a time, and are skipped otherwise;
+// see TestTests. The seeded users and apps outlive the run, so that the verify phase can find
+// them after the platform is upgraded.
+var _ = Describe("Upgrade", func() {
+	Context("seed phase", func() {
+		It("seeds users, keys and apps and writes a manifest [deploy] [slow]", func() {
+			owner := upgrade.User{
+				Username: fmt.Sprintf("%supgrade-%d", resourcePrefix, randSuffix),
+				Password: testPassword,
+			}
+			owner.Email = owner.Username + "@deis.io"
+			collaborator := upgrade.User{
+				Username: owner.Username + "-collab",
+				Password: testPassword,
+				Email:    owner.Username + "-collab@deis.io",
+			}
+			m := &upgrade.Manifest{Seeded: time.Now(), Versions: versions.Map()}
+
+			register(url, collaborator.Username, collaborator.Password, collaborator.Email)
+			register(url, owner.Username, owner.Password, owner.Email)
+
+			keyPath := createKey(owner.Username + "-key")
+			output, err := execute("deis keys:add %s.pub", keyPath)
+			Expect(err).NotTo(HaveOccurred(), output)
+			output, err = execute("deis keys:list")
+			Expect(err).NotTo(HaveOccurred(), output)
+			owner.Keys = parse.KeysList(output)
+			m.Users = []upgrade.User{owner, collaborator}
+
+			for i := 0; i < upgradeApps; i++ {
+				app := upgrade.App{Name: getRandAppName(), Owner: owner.Username}
+				Eventually(createApp(app.Name)).Should(Exit(0))
+				sess, err := start("deis pull deis/example-go -a %s", app.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(sess, "10m").Should(Exit(0))
+				for _, args := range [][]string{
+					{"config:set", "POWERED_BY=upgrades", fmt.Sprintf("SEEDED=%d", i), "-a", app.Name},
+					{"limits:set", "cmd=64M", "-a", app.Name},
+					{"domains:add", app.Name + ".example.com", "-a", app.Name},
+					{"perms:create", collaborator.Username, "-a", app.Name},
+				} {
+					output, err = deisCLI(args...)
+					Expect(err).NotTo(HaveOccurred(), output)
+				}
+				Eventually(func() error {
+					app.Body, err = getAppBody(app.Name)
+					return err
+				}, "5m", "1s").Should(Succeed())
+				m.Apps = append(m.Apps, describeApp(app))
+			}
+
+			Expect(upgrade.Save(m, upgradeManifest)).To(Succeed())
+			fmt.Fprintf(ginkgoOut, "Seeded %d apps owned by %s; wrote %s\n", len(m.Apps), owner.Username, upgradeManifest)
+		})
+	})
+
+	Context("verify phase", func() {
+		It("finds the seeded state intact [deploy]", func() {
+			m, err := upgrade.Load(upgradeManifest)
+			Expect(err).NotTo(HaveOccurred())
+			fmt.Fprintf(ginkgoOut, "Verifying state seeded on %v against %v\n", m.Versions, versions.Map())
+
+			login(url, testAdminUser, testAdminPassword)
+			output, err := execute("deis users:list")
+			Expect(err).NotTo(HaveOccurred(), output)
+			for _, u := range m.Users {
+				Expect(output).To(ContainSubstring(u.Username))
+			}
+
+			var diffs []string
+			for _, u := range m.Users {
+				login(url, u.Username, u.Password)
+				if len(u.Keys) == 0 {
+					continue
+				}
+				output, err := execute("deis keys:list")
+				Expect(err).NotTo(HaveOccurred(), output)
+				if keys := parse.KeysList(output); !reflect.DeepEqual(keys, u.Keys) {
+					diffs = append(diffs, fmt.Sprintf("%s's keys: expected %v, got %v", u.Username, u.Keys, keys))
+				}
+			}
+			for _, want := range m.Apps {
+				owner, ok := m.User(want.Owner)
+				Expect(ok).To(BeTrue(), "the manifest has no user %s", want.Owner)
+				login(url, owner.Username, owner.Password)
+				got := describeApp(upgrade.App{Name: want.Name, Owner: want.Owner})
+				if want.Body != "" {
+					// give the upgraded router and the app's pods time to settle
+					Eventually(func() string {
+						got.Body, _ = getAppBody(want.Name)
+						return got.Body
+					}, "2m", "1s").Should(Equal(want.Body))
+				}
+				diffs = append(diffs, upgrade.Diff(want, got)...)
+			}
+			Expect(diffs).To(BeEmpty(), strings.Join(diffs, "\n"))
+
+			if os.Getenv("UPGRADE_CLEANUP") != "" {
+				for _, app := range m.Apps {
+					owner, _ := m.User(app.Owner)
+					login(url, owner.Username, owner.Password)
+					destroyApp(app.Name)
+				}
+				for _, u := range m.Users {
+					cancel(url, u.Username, u.Password)
+				}
+			}
+		})
+	})
+})
+
+// describeApp fills in app with what its owner, who must be logged in, sees through the CLI. The
+// app's body is left as it is.
+func describeApp(app upgrade.App) upgrade.App {
+	output, err := execute("deis releases:list -a %s", app.Name)
+	Expect(err).NotTo(HaveOccurred(), output)
+	releases, err := parse.ReleasesList(output)
+	Expect(err).NotTo(HaveOccurred())
+	app.Releases = nil
+	for _, release := range releases {
+		app.Releases = append(app.Releases, release.Version)
+	}
+
+	output, err = execute("deis config:list -a %s", app.Name)
+	Expect(err).NotTo(HaveOccurred(), output)
+	app.Config = parse.ConfigList(output)
+
+	output, err = execute("deis domains:list -a %s", app.Name)
+	Expect(err).NotTo(HaveOccurred(), output)
+	app.Domains = parse.DomainsList(output)
+
+	output, err = execute("deis perms:list -a %s", app.Name)
+	Expect(err).NotTo(HaveOccurred(), output)
+	app.Perms = parse.PermsList(output)
+
+	output, err = execute("deis limits:list -a %s", app.Name)
+	Expect(err).NotTo(HaveOccurred(), output)
+	app.Limits = parse.LimitsList(output)
+	return app
+}
@@ -6,7 +6,7 @@ import (
 )
 
 var _ = Describe("Users", func() {
-	Context("when logged in as an admin user", func() {
+	Context("when logged in as an admin user [admin]", func() {
 		BeforeEach(func() {
 			login(url, testAdminUser, testAdminPassword)
 		})
@@ -1,16 +1,25 @@
 package tests
 
 import (
+	"os"
+
+	"github.com/deis/workflow/_tests/pkg/compat"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("Version", func() {
 
-	It("prints its version", func() {
+	It("prints its version [smoke]", func() {
 		output, err := execute("deis --version")
 		Expect(err).NotTo(HaveOccurred())
-		// TODO: read the expected version from ../client/deis-version?
-		Expect(output).To(Equal("2.0.0-dev\n"))
+		_, err = compat.ParseVersion(output)
+		Expect(err).NotTo(HaveOccurred())
+		// the version BeforeSuite checked against the compatibility matrix
+		Expect(output).To(Equal(versions.CLI + "\n"))
+		// which can only be told to be the right one by the version the run installed
+		if expected := os.Getenv("EXPECTED_CLI_VERSION"); expected != "" {
+			Expect(output).To(Equal(expected + "\n"))
+		}
 	})
 })